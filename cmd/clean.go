@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"switchtube-downloader/internal/download"
+
+	"github.com/spf13/cobra"
+)
+
+// init initializes the clean command and adds it to the root command.
+func init() {
+	cleanCmd.Flags().Bool("resume", false, "Resume the unfinished channel downloads the files belong to instead of deleting them")
+	rootCmd.AddCommand(cleanCmd)
+}
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean [folder]",
+	Short: "Delete leftover files of interrupted downloads",
+	Long: "Scan a folder (the current one per default) for leftovers of interrupted downloads: partially\n" +
+		"downloaded videos of unfinished channel downloads and the temporary files of downloads, i.e.\n" +
+		".part files of videos and archives, aria2c control files, remux sources (.source.mp4) and\n" +
+		"unfinished replacements of --backup (.new.mp4). Other files are never touched. The files are\n" +
+		"listed and deleted after confirmation, or with --resume the unfinished channel downloads they\n" +
+		"belong to are resumed.",
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		resume, err := cmd.Flags().GetBool("resume")
+		if err != nil {
+			reportError("Error getting resume flag", err)
+
+			return
+		}
+
+		folder := ""
+		if len(args) > 0 {
+			folder = args[0]
+		}
+
+		if err := download.Clean(folder, resume); err != nil {
+			reportError("Cleaning failed", err)
+		}
+	},
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"switchtube-downloader/internal/helper/ui/input"
+	"switchtube-downloader/internal/settings"
+
+	"github.com/spf13/cobra"
+)
+
+// init initializes the config command and its subcommands, adding them to the root command.
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configEditCmd)
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and change the persisted configuration",
+	Long:  "Read and change the settings of the configuration file in the user config directory\n\n" + configKeysHelp(),
+	Run: func(cmd *cobra.Command, _ []string) {
+		if err := cmd.Help(); err != nil {
+			reportError("Error displaying help", err)
+		}
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a setting",
+	Long:  "Print the value of a setting, or an empty line if it is not set and its default applies",
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		key, err := settings.LookupKey(args[0])
+		if err != nil {
+			reportError("Reading the config failed", err)
+
+			return
+		}
+
+		cfg, err := settings.Load()
+		if err != nil {
+			reportError("Reading the config failed", err)
+
+			return
+		}
+
+		fmt.Println(key.Get(cfg))
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change the value of a setting",
+	Long: "Validate and store the value of a setting in the configuration file.\n" +
+		"An empty value removes the setting, so its default applies again.",
+	Args: cobra.ExactArgs(2), //nolint:mnd // Key and value
+	Run: func(_ *cobra.Command, args []string) {
+		key, err := settings.LookupKey(args[0])
+		if err != nil {
+			reportError("Changing the config failed", err)
+
+			return
+		}
+
+		cfg, err := settings.Load()
+		if err != nil {
+			reportError("Changing the config failed", err)
+
+			return
+		}
+
+		if err := key.Set(&cfg, args[1]); err != nil {
+			reportError("Changing the config failed", err)
+
+			return
+		}
+
+		if err := settings.Save(cfg); err != nil {
+			reportError("Changing the config failed", err)
+		}
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Print all settings with their values",
+	Long:  "Print every setting as key=value, with an empty value if it is not set and its default applies",
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		cfg, err := settings.Load()
+		if err != nil {
+			reportError("Reading the config failed", err)
+
+			return
+		}
+
+		for _, key := range settings.Keys() {
+			fmt.Printf("%s=%s\n", key.Name, key.Get(cfg))
+		}
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the configuration file in an editor",
+	Long: "Open the configuration file in the editor set by $VISUAL or $EDITOR and validate it once\n" +
+		"the editor is closed.",
+	Args: cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		if input.PromptsDisabled() {
+			reportError("The config edit command is interactive", input.ErrNoInput)
+
+			return
+		}
+
+		if err := editConfig(); err != nil {
+			reportError("Editing the config failed", err)
+		}
+	},
+}
+
+// configKeysHelp returns the list of settings with their descriptions for the help page.
+func configKeysHelp() string {
+	var help strings.Builder
+
+	help.WriteString("Settings:\n")
+
+	for _, key := range settings.Keys() {
+		fmt.Fprintf(&help, "  %-12s %s\n", key.Name, key.Description)
+	}
+
+	return strings.TrimSuffix(help.String(), "\n")
+}
+
+// editConfig opens the configuration file in the user's editor, creating it first if
+// needed, and validates the file once the editor is closed.
+func editConfig() error {
+	path, err := settings.Path()
+	if err != nil {
+		return err //nolint:wrapcheck // Already wrapped by the settings package
+	}
+
+	if !settings.Exists() {
+		if err := settings.Save(settings.Config{}); err != nil {
+			return err //nolint:wrapcheck // Already wrapped by the settings package
+		}
+	}
+
+	editor := strings.Fields(editorCommand())
+
+	//nolint:gosec // Editor chosen by the user, the file is passed as separate argument
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run editor %s: %w", editor[0], err)
+	}
+
+	cfg, err := settings.Load()
+	if err != nil {
+		return err //nolint:wrapcheck // Already wrapped by the settings package
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("%w (run config edit again to fix %s)", err, path)
+	}
+
+	return nil
+}
+
+// editorCommand returns the editor set by $VISUAL or $EDITOR, or the default editor of
+// the platform. It may contain arguments, e.g. "code --wait".
+func editorCommand() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(name)); editor != "" {
+			return editor
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+
+	return "vi"
+}
@@ -1,22 +1,89 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
 	"strings"
+	"time"
 
 	"switchtube-downloader/internal/download"
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/history"
 	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/settings"
 
 	"github.com/spf13/cobra"
 )
 
+const (
+	// kibibyte is the unit of the --write-buffer and --copy-buffer flags.
+	kibibyte = 1 << 10
+	// defaultCopyBuffer is the size of the io.Copy buffer in KiB.
+	defaultCopyBuffer = 32
+)
+
 // init initializes the download command and adds it to the root command with its flags.
 func init() {
 	rootCmd.AddCommand(downloadCmd)
 	downloadCmd.Flags().BoolP("episode", "e", false, "Prefixes the video with episode-number e.g. 01_OR_Mapping.mp4")
+	downloadCmd.Flags().Int("episode-pad", 0, "Zero-pad episode numbers in filenames to this many digits, e.g. 2 for 01_")
+	downloadCmd.Flags().Bool("renumber", false, "Number channel videos sequentially in channel order instead of using their episode")
+	downloadCmd.Flags().Bool("infer-episodes", false, "Number channel videos without episode by their position in the channel")
 	downloadCmd.Flags().BoolP("skip", "s", false, "Skip video if it already exists")
 	downloadCmd.Flags().BoolP("force", "f", false, "Force overwrite if file already exist")
+	downloadCmd.Flags().Bool("backup", false, "Keep an existing file as numbered backup, e.g. name.1.mp4, instead of overwriting it")
 	downloadCmd.Flags().BoolP("all", "a", false, "Download the whole content of a channel")
-	downloadCmd.Flags().StringP("output", "o", "", "Output directory for downloaded files")
+	downloadCmd.Flags().String("select", "", "Download the channel videos at these positions of the list or with these IDs without prompting, e.g. 1-5,8,id:AbCdEf")
+	downloadCmd.Flags().String("select-file", "", "Read the positions or IDs to download from this file, - for stdin")
+	downloadCmd.Flags().StringP("output", "o", "", "Output directory for downloaded files, - to write a single video to stdout")
+	downloadCmd.Flags().BoolP("quiet", "q", false, "Print only the final results, without progress bars and tables")
+	downloadCmd.Flags().Bool("json", false, "Print the outcome of every video as JSON to stdout")
+	downloadCmd.Flags().Bool("flat", false, "Place channel videos directly in the output directory instead of a channel folder")
+	downloadCmd.Flags().String("folder-template", models.DefaultFolderTemplate, "Name of the channel folder, with the placeholders {channel}, {id} and {year}")
+	downloadCmd.Flags().Bool("dry-run", false, "Only show the folders the download would create and their case collisions, without writing anything")
+	downloadCmd.Flags().String("file-mode", "", "Permissions of created files in octal, e.g. 0664 (default depends on the umask)")
+	downloadCmd.Flags().String("dir-mode", "", "Permissions of created folders in octal, e.g. 0775 (default depends on the umask)")
+	downloadCmd.Flags().Bool("no-manifest", false, "Don't write a manifest.json into the channel folder")
+	downloadCmd.Flags().Bool("offline", false, "List cached videos and their download state instead of downloading, without network access")
+	downloadCmd.Flags().Bool("no-mtime", false, "Keep the download time as modification time instead of the publish date")
+	downloadCmd.Flags().Bool("playlist", false, "Write a playlist.m3u8 ordered by episode into the channel folder")
+	downloadCmd.Flags().Bool("write-feed", false, "Write an RSS feed.xml of the downloaded videos into the channel folder")
+	downloadCmd.Flags().String("external-downloader", "", "Delegate the transfer to an external tool (aria2c, curl)")
+	downloadCmd.Flags().String("chapters", "", "Write chapters listed in video descriptions to an .ffmetadata file next to the video, or embed them with ffmpeg (file, embed)")
+	downloadCmd.Flags().Bool("contact-sheet", false, "Render a grid of thumbnails across every downloaded video into a .contact.jpg next to it with ffmpeg")
+	downloadCmd.Flags().Bool("embed-metadata", false, "Embed title, episode, channel, publish date, description and license into the video files with ffmpeg")
+	downloadCmd.Flags().String("remux", "", "Remux videos into this container with ffmpeg, without re-encoding (mkv, mp4)")
+	downloadCmd.Flags().Int("segments", 1, "Download each video in this many concurrent byte ranges")
+	downloadCmd.Flags().IntP("concurrency", "j", 0, "Download at most this many videos at once (0 for all at once)")
+	downloadCmd.Flags().String("quality", string(models.QualityHighest), "Variant to download (highest, lowest, per-video, ask, or a resolution like 720p)")
+	downloadCmd.Flags().String("order", string(models.OrderSelection), "Order in which videos are downloaded (selection, episode, smallest, largest)")
+	downloadCmd.Flags().String("sort", string(models.SortChannel), "Order in which channel videos are listed (channel, episode, title, date, duration)")
+	downloadCmd.Flags().String("include", "", "Only offer channel videos whose title matches this regular expression")
+	downloadCmd.Flags().String("exclude", "", "Leave out channel videos whose title matches this regular expression")
+	downloadCmd.Flags().Duration("min-duration", 0, "Only offer channel videos at least this long, e.g. 5m")
+	downloadCmd.Flags().Duration("max-duration", 0, "Only offer channel videos at most this long, e.g. 2h")
+	downloadCmd.Flags().String("schedule", "", "Wait until this time of day (HH:MM) before downloading, e.g. 02:00")
+	downloadCmd.Flags().String("notify-cmd", "", "Shell command to run after each batch, receives a JSON summary on stdin")
+	downloadCmd.Flags().String("notify-webhook", "", "URL to POST a JSON summary to after each batch")
+	downloadCmd.Flags().Bool("sync", false, "Mirror channels: download all videos missing locally, recognizing downloaded ones by video ID")
+	downloadCmd.Flags().Bool("rename-moved", false, "With --sync, rename local files of videos that were renamed on SwitchTube")
+	downloadCmd.Flags().Bool("delete-removed", false, "With --sync, delete local files of videos that were removed from the channel")
+	downloadCmd.Flags().String("archive-output", "", "Write the videos with their manifest into this .tar, .tar.gz or .zip archive instead of individual files")
+	downloadCmd.Flags().String("staging-dir", "", "Download and process videos in this folder, e.g. on a fast local disk, and move them into place once complete")
+	downloadCmd.Flags().String("rclone-remote", "", "Upload every completed video with rclone to this remote path, e.g. gdrive:Lectures")
+	downloadCmd.Flags().Bool("rclone-move", false, "With --rclone-remote, delete the local files once they are uploaded")
+	downloadCmd.Flags().Int("copy-buffer", defaultCopyBuffer, "Copy video data through a buffer of this many KiB per transfer, less saves memory on small devices")
+	downloadCmd.Flags().Int("write-buffer", 0, "Collect this many KiB before writing to a video file, e.g. 4096 on network shares (0 to write directly)")
+	downloadCmd.Flags().String("fsync", "", "Flush downloaded data to disk once a video is complete or after every write, e.g. on network shares (end, always)")
+	downloadCmd.Flags().String("trash-dir", "", "With --delete-removed, move the files into this folder instead of deleting them")
+	downloadCmd.Flags().String("on-collision", string(models.CollisionRename), "What to do when two videos share a filename (rename, rename-episode, rename-id, skip, overwrite, error)")
+	downloadCmd.Flags().String("on-duplicate", string(models.DuplicateAsk), "What to do when a video was downloaded to another folder before (ask, link, copy, skip, download)")
+	downloadCmd.Flags().String("link-duplicates", "", "Link videos downloaded to another folder before, e.g. in another channel, instead of asking (hard, symlink)")
+	downloadCmd.Flags().Bool("force-lock", false, "Write into the output directory even if another run is using it")
+	downloadCmd.Flags().StringArray("tag", nil, "Record this tag with the downloaded videos in the history, can be repeated")
+	downloadCmd.Flags().String("note", "", "Record this note with the downloaded videos in the history")
 }
 
 var downloadCmd = &cobra.Command{
@@ -24,57 +91,803 @@ var downloadCmd = &cobra.Command{
 	Short: "Download one or more videos or channels",
 	Long: "Download one or more videos or channels. Automatically detects for each input whether it is a video or channel.\n" +
 		"You can also pass the whole URL instead of the ID for convenience.",
-	Args: cobra.MinimumNArgs(1),
+	Args: func(cmd *cobra.Command, args []string) error {
+		// Without arguments, offline mode lists the cached channels
+		if offline, err := cmd.Flags().GetBool("offline"); err == nil && offline {
+			return nil
+		}
+
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		episode, err := cmd.Flags().GetBool("episode")
 		if err != nil {
-			log.Error("Error getting episode flag", "err", err)
+			reportError("Error getting episode flag", err)
 
 			return
 		}
 
+		episodePad, err := cmd.Flags().GetInt("episode-pad")
+		if err != nil {
+			reportError("Error getting episode-pad flag", err)
+
+			return
+		}
+
+		if episodePad < 0 {
+			reportError("Invalid episode-pad flag", errNegative)
+
+			return
+		}
+
+		renumber, err := cmd.Flags().GetBool("renumber")
+		if err != nil {
+			reportError("Error getting renumber flag", err)
+
+			return
+		}
+
+		inferEpisodes, err := cmd.Flags().GetBool("infer-episodes")
+		if err != nil {
+			reportError("Error getting infer-episodes flag", err)
+
+			return
+		}
+
+		// Padded, renumbered or inferred episodes are only useful in filenames
+		episode = episode || episodePad > 0 || renumber || inferEpisodes
+
 		skip, err := cmd.Flags().GetBool("skip")
 		if err != nil {
-			log.Error("Error getting skip flag", "err", err)
+			reportError("Error getting skip flag", err)
 
 			return
 		}
 
 		force, err := cmd.Flags().GetBool("force")
 		if err != nil {
-			log.Error("Error getting force flag", "err", err)
+			reportError("Error getting force flag", err)
+
+			return
+		}
+
+		backup, err := cmd.Flags().GetBool("backup")
+		if err != nil {
+			reportError("Error getting backup flag", err)
+
+			return
+		}
+
+		if backup && skip {
+			reportError("Invalid backup flag", errBackupAndSkip)
 
 			return
 		}
 
 		all, err := cmd.Flags().GetBool("all")
 		if err != nil {
-			log.Error("Error getting all flag", "err", err)
+			reportError("Error getting all flag", err)
+
+			return
+		}
+
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			reportError("Error loading config", err)
+
+			return
+		}
+
+		output, concurrency, err := outputAndConcurrency(cmd, cfg)
+		if err != nil {
+			reportError("Error getting flags", err)
+
+			return
+		}
+
+		flat, err := cmd.Flags().GetBool("flat")
+		if err != nil {
+			reportError("Error getting flat flag", err)
+
+			return
+		}
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			reportError("Error getting dry-run flag", err)
+
+			return
+		}
+
+		folderTemplateFlag, err := cmd.Flags().GetString("folder-template")
+		if err != nil {
+			reportError("Error getting folder-template flag", err)
+
+			return
+		}
+
+		folderTemplate, err := models.ParseFolderTemplate(folderTemplateFlag)
+		if err != nil {
+			reportError("Invalid folder-template flag", err)
+
+			return
+		}
+
+		fileModeFlag, err := cmd.Flags().GetString("file-mode")
+		if err != nil {
+			reportError("Error getting file-mode flag", err)
+
+			return
+		}
+
+		fileMode, err := models.ParseFileMode(fileModeFlag)
+		if err != nil {
+			reportError("Invalid file-mode flag", err)
+
+			return
+		}
+
+		dirModeFlag, err := cmd.Flags().GetString("dir-mode")
+		if err != nil {
+			reportError("Error getting dir-mode flag", err)
+
+			return
+		}
+
+		dirMode, err := models.ParseFileMode(dirModeFlag)
+		if err != nil {
+			reportError("Invalid dir-mode flag", err)
+
+			return
+		}
+
+		noManifest, err := cmd.Flags().GetBool("no-manifest")
+		if err != nil {
+			reportError("Error getting no-manifest flag", err)
+
+			return
+		}
+
+		noMtime, err := cmd.Flags().GetBool("no-mtime")
+		if err != nil {
+			reportError("Error getting no-mtime flag", err)
+
+			return
+		}
+
+		playlist, err := cmd.Flags().GetBool("playlist")
+		if err != nil {
+			reportError("Error getting playlist flag", err)
+
+			return
+		}
+
+		writeFeed, err := cmd.Flags().GetBool("write-feed")
+		if err != nil {
+			reportError("Error getting write-feed flag", err)
+
+			return
+		}
+
+		externalDownloaderFlag, err := cmd.Flags().GetString("external-downloader")
+		if err != nil {
+			reportError("Error getting external-downloader flag", err)
+
+			return
+		}
+
+		externalDownloader, err := models.ParseExternalDownloader(externalDownloaderFlag)
+		if err != nil {
+			reportError("Invalid external-downloader flag", err)
+
+			return
+		}
+
+		remuxFlag, err := cmd.Flags().GetString("remux")
+		if err != nil {
+			reportError("Error getting remux flag", err)
+
+			return
+		}
+
+		if !cmd.Flags().Changed("remux") {
+			remuxFlag = cfg.Remux
+		}
+
+		remux, err := models.ParseContainer(strings.TrimSpace(remuxFlag))
+		if err != nil {
+			reportError("Invalid remux flag", err)
+
+			return
+		}
+
+		chaptersFlag, err := cmd.Flags().GetString("chapters")
+		if err != nil {
+			reportError("Error getting chapters flag", err)
+
+			return
+		}
+
+		chapters, err := models.ParseChapterMode(chaptersFlag)
+		if err != nil {
+			reportError("Invalid chapters flag", err)
+
+			return
+		}
+
+		contactSheet, err := cmd.Flags().GetBool("contact-sheet")
+		if err != nil {
+			reportError("Error getting contact-sheet flag", err)
+
+			return
+		}
+
+		embedMetadata, err := cmd.Flags().GetBool("embed-metadata")
+		if err != nil {
+			reportError("Error getting embed-metadata flag", err)
+
+			return
+		}
+
+		segments, err := cmd.Flags().GetInt("segments")
+		if err != nil {
+			reportError("Error getting segments flag", err)
+
+			return
+		}
+
+		if segments < 1 {
+			reportError("Invalid segments flag", errBelowOne)
+
+			return
+		}
+
+		if concurrency < 0 {
+			reportError("Invalid concurrency flag", errNegative)
+
+			return
+		}
+
+		qualityFlag, err := cmd.Flags().GetString("quality")
+		if err != nil {
+			reportError("Error getting quality flag", err)
+
+			return
+		}
+
+		quality, err := models.ParseQualityPolicy(strings.TrimSpace(qualityFlag))
+		if err != nil {
+			reportError("Invalid quality flag", err)
+
+			return
+		}
+
+		orderFlag, err := cmd.Flags().GetString("order")
+		if err != nil {
+			reportError("Error getting order flag", err)
+
+			return
+		}
+
+		order, err := models.ParseDownloadOrder(orderFlag)
+		if err != nil {
+			reportError("Invalid order flag", err)
+
+			return
+		}
+
+		sortFlag, err := cmd.Flags().GetString("sort")
+		if err != nil {
+			reportError("Error getting sort flag", err)
+
+			return
+		}
+
+		sortKey, err := models.ParseVideoSort(sortFlag)
+		if err != nil {
+			reportError("Invalid sort flag", err)
+
+			return
+		}
+
+		include, err := cmd.Flags().GetString("include")
+		if err != nil {
+			reportError("Error getting include flag", err)
+
+			return
+		}
+
+		if _, err := regexp.Compile(include); err != nil {
+			reportError("Invalid include flag", err)
+
+			return
+		}
+
+		exclude, err := cmd.Flags().GetString("exclude")
+		if err != nil {
+			reportError("Error getting exclude flag", err)
+
+			return
+		}
+
+		if _, err := regexp.Compile(exclude); err != nil {
+			reportError("Invalid exclude flag", err)
+
+			return
+		}
+
+		minDuration, err := cmd.Flags().GetDuration("min-duration")
+		if err != nil {
+			reportError("Error getting min-duration flag", err)
+
+			return
+		}
+
+		maxDuration, err := cmd.Flags().GetDuration("max-duration")
+		if err != nil {
+			reportError("Error getting max-duration flag", err)
+
+			return
+		}
+
+		if minDuration < 0 || maxDuration < 0 {
+			reportError("Invalid duration flags", errNegative)
+
+			return
+		}
+
+		if maxDuration > 0 && maxDuration < minDuration {
+			reportError("Invalid max-duration flag", errMaxBelowMin)
+
+			return
+		}
+
+		schedule, err := cmd.Flags().GetString("schedule")
+		if err != nil {
+			reportError("Error getting schedule flag", err)
+
+			return
+		}
+
+		var startAt time.Time
+		if schedule != "" {
+			startAt, err = models.ParseSchedule(schedule, time.Now())
+			if err != nil {
+				reportError("Invalid schedule flag", err)
+
+				return
+			}
+		}
+
+		notifyCmd, err := cmd.Flags().GetString("notify-cmd")
+		if err != nil {
+			reportError("Error getting notify-cmd flag", err)
+
+			return
+		}
+
+		notifyWebhook, err := cmd.Flags().GetString("notify-webhook")
+		if err != nil {
+			reportError("Error getting notify-webhook flag", err)
+
+			return
+		}
+
+		jsonOutput, err := cmd.Flags().GetBool("json")
+		if err != nil {
+			reportError("Error getting json flag", err)
+
+			return
+		}
+
+		quiet, err := cmd.Flags().GetBool("quiet")
+		if err != nil {
+			reportError("Error getting quiet flag", err)
+
+			return
+		}
+
+		headless, err := cmd.Flags().GetBool("headless")
+		if err != nil {
+			reportError("Error getting headless flag", err)
+
+			return
+		}
+
+		// Headless runs report their outcome as JSON for the calling job to parse
+		if headless && output != models.StdoutOutput {
+			jsonOutput = true
+		}
+
+		onCollision, err := cmd.Flags().GetString("on-collision")
+		if err != nil {
+			reportError("Error getting on-collision flag", err)
+
+			return
+		}
+
+		collisionPolicy, err := models.ParseCollisionPolicy(onCollision)
+		if err != nil {
+			reportError("Invalid on-collision flag", err)
+
+			return
+		}
+
+		onDuplicate, err := cmd.Flags().GetString("on-duplicate")
+		if err != nil {
+			reportError("Error getting on-duplicate flag", err)
+
+			return
+		}
+
+		duplicatePolicy, err := models.ParseDuplicatePolicy(onDuplicate)
+		if err != nil {
+			reportError("Invalid on-duplicate flag", err)
+
+			return
+		}
+
+		linkDuplicates, err := cmd.Flags().GetString("link-duplicates")
+		if err != nil {
+			reportError("Error getting link-duplicates flag", err)
+
+			return
+		}
+
+		var linkMode models.LinkMode
+
+		if linkDuplicates != "" {
+			if cmd.Flags().Changed("on-duplicate") && duplicatePolicy != models.DuplicateLink {
+				reportError("Invalid link-duplicates flag", fmt.Errorf("%w %s", errLinkAndOnDuplicate, onDuplicate))
+
+				return
+			}
+
+			linkMode, err = models.ParseLinkMode(linkDuplicates)
+			if err != nil {
+				reportError("Invalid link-duplicates flag", err)
+
+				return
+			}
+
+			duplicatePolicy = models.DuplicateLink
+		}
+
+		syncMode, err := cmd.Flags().GetBool("sync")
+		if err != nil {
+			reportError("Error getting sync flag", err)
+
+			return
+		}
+
+		selectFlag, err := cmd.Flags().GetString("select")
+		if err != nil {
+			reportError("Error getting select flag", err)
+
+			return
+		}
+
+		selectFile, err := cmd.Flags().GetString("select-file")
+		if err != nil {
+			reportError("Error getting select-file flag", err)
+
+			return
+		}
+
+		if selectFlag != "" && selectFile != "" {
+			reportError("Invalid select-file flag", errSelectFileAndSelect)
+
+			return
+		}
+
+		if (selectFlag != "" || selectFile != "") && (all || syncMode) {
+			reportError("Invalid select flag", errSelectAndAll)
+
+			return
+		}
+
+		// The environment only applies if the videos are chosen at all
+		if all || syncMode {
+			selectFlag, selectFile = "", ""
+		} else if selectFlag == "" && selectFile == "" {
+			selectFlag = os.Getenv(settings.SelectionEnv)
+		}
+
+		selection, err := readSelection(selectFlag, selectFile)
+		if err != nil {
+			reportError("Invalid select flag", err)
+
+			return
+		}
+
+		renameMoved, err := cmd.Flags().GetBool("rename-moved")
+		if err != nil {
+			reportError("Error getting rename-moved flag", err)
+
+			return
+		}
+
+		if renameMoved && !syncMode {
+			reportError("Invalid rename-moved flag", errRequiresSync)
+
+			return
+		}
+
+		deleteRemoved, err := cmd.Flags().GetBool("delete-removed")
+		if err != nil {
+			reportError("Error getting delete-removed flag", err)
+
+			return
+		}
+
+		if deleteRemoved && !syncMode {
+			reportError("Invalid delete-removed flag", errRequiresSync)
+
+			return
+		}
+
+		trashDir, err := cmd.Flags().GetString("trash-dir")
+		if err != nil {
+			reportError("Error getting trash-dir flag", err)
+
+			return
+		}
+
+		if trashDir != "" && !deleteRemoved {
+			reportError("Invalid trash-dir flag", errRequiresDeleteRemoved)
+
+			return
+		}
+
+		stagingDir, err := cmd.Flags().GetString("staging-dir")
+		if err != nil {
+			reportError("Error getting staging-dir flag", err)
+
+			return
+		}
+
+		writeBuffer, err := cmd.Flags().GetInt("write-buffer")
+		if err != nil {
+			reportError("Error getting write-buffer flag", err)
+
+			return
+		}
+
+		if writeBuffer < 0 {
+			reportError("Invalid write-buffer flag", errNegative)
+
+			return
+		}
+
+		archiveOutput, err := cmd.Flags().GetString("archive-output")
+		if err != nil {
+			reportError("Error getting archive-output flag", err)
+
+			return
+		}
+
+		archiveOutput = strings.TrimSpace(archiveOutput)
+
+		rcloneRemote, err := cmd.Flags().GetString("rclone-remote")
+		if err != nil {
+			reportError("Error getting rclone-remote flag", err)
+
+			return
+		}
+
+		rcloneMove, err := cmd.Flags().GetBool("rclone-move")
+		if err != nil {
+			reportError("Error getting rclone-move flag", err)
+
+			return
+		}
+
+		copyBuffer, err := cmd.Flags().GetInt("copy-buffer")
+		if err != nil {
+			reportError("Error getting copy-buffer flag", err)
+
+			return
+		}
+
+		if copyBuffer < 1 {
+			reportError("Invalid copy-buffer flag", errBelowOne)
+
+			return
+		}
+
+		fsyncFlag, err := cmd.Flags().GetString("fsync")
+		if err != nil {
+			reportError("Error getting fsync flag", err)
+
+			return
+		}
+
+		fsync, err := models.ParseFsyncPolicy(strings.TrimSpace(fsyncFlag))
+		if err != nil {
+			reportError("Invalid fsync flag", err)
 
 			return
 		}
 
-		output, err := cmd.Flags().GetString("output")
+		tags, err := cmd.Flags().GetStringArray("tag")
 		if err != nil {
-			log.Error("Error getting output flag", "err", err)
+			reportError("Error getting tag flag", err)
 
 			return
 		}
 
+		note, err := cmd.Flags().GetString("note")
+		if err != nil {
+			reportError("Error getting note flag", err)
+
+			return
+		}
+
+		offline, err := cmd.Flags().GetBool("offline")
+		if err != nil {
+			reportError("Error getting offline flag", err)
+
+			return
+		}
+
+		if offline {
+			if err := download.ListOffline(args, models.DownloadConfig{UseEpisode: episode}); err != nil {
+				reportError("Offline listing failed", err)
+			}
+
+			return
+		}
+
+		forceLock, err := cmd.Flags().GetBool("force-lock")
+		if err != nil {
+			reportError("Error getting force-lock flag", err)
+
+			return
+		}
+
+		if archiveOutput != "" {
+			if _, err := models.ParseArchiveFormat(archiveOutput); err != nil {
+				reportError("Invalid archive-output flag", err)
+
+				return
+			}
+
+			if len(args) > 1 || cmd.Flags().Changed("output") || rcloneRemote != "" {
+				reportError("Invalid archive-output flag", errArchiveArgs)
+
+				return
+			}
+		}
+
+		if dryRun && (output == models.StdoutOutput || archiveOutput != "") {
+			reportError("Invalid dry-run flag", errDryRunOutput)
+
+			return
+		}
+
+		if output == models.StdoutOutput {
+			if len(args) > 1 || jsonOutput {
+				reportError("Invalid output flag", errStdoutArgs)
+
+				return
+			}
+
+			if cmd.Flags().Changed("remux") {
+				reportError("Invalid remux flag", errStdoutRemux)
+
+				return
+			}
+
+			if rcloneRemote != "" {
+				reportError("Invalid rclone-remote flag", errStdoutUpload)
+
+				return
+			}
+
+			// Videos are streamed as served
+			remux = models.ContainerSource
+
+			// Keep the video data the only output on stdout
+			if err := stream.Select(stream.Stderr); err != nil {
+				reportError("Error selecting output stream", err)
+
+				return
+			}
+		}
+
 		for _, arg := range args {
 			config := models.DownloadConfig{
-				Media:      arg,
-				UseEpisode: episode,
-				Skip:       skip,
-				Force:      force,
-				All:        all,
-				OutputDir:  strings.TrimSpace(output),
+				Media:              arg,
+				Selection:          selection,
+				StartAt:            startAt,
+				UseEpisode:         episode,
+				EpisodePad:         episodePad,
+				Renumber:           renumber,
+				InferEpisodes:      inferEpisodes,
+				Skip:               skip || (syncMode && !force && !backup),
+				Force:              force,
+				Backup:             backup,
+				All:                all || syncMode,
+				OutputDir:          strings.TrimSpace(output),
+				OnCollision:        collisionPolicy,
+				OnDuplicate:        duplicatePolicy,
+				LinkMode:           linkMode,
+				Quality:            quality,
+				NotifyCmd:          notifyCmd,
+				NotifyWebhook:      notifyWebhook,
+				ExternalDownloader: externalDownloader,
+				Remux:              remux,
+				Chapters:           chapters,
+				TrashDir:           strings.TrimSpace(trashDir),
+				StagingDir:         strings.TrimSpace(stagingDir),
+				ArchiveOutput:      archiveOutput,
+				RcloneRemote:       strings.TrimSpace(rcloneRemote),
+				RcloneMove:         rcloneMove,
+				Fsync:              fsync,
+				Segments:           segments,
+				Concurrency:        concurrency,
+				WriteBuffer:        writeBuffer * kibibyte,
+				CopyBuffer:         copyBuffer * kibibyte,
+				Order:              order,
+				Sort:               sortKey,
+				Include:            include,
+				Exclude:            exclude,
+				MinDuration:        minDuration,
+				MaxDuration:        maxDuration,
+				Flat:               flat,
+				DryRun:             dryRun,
+				FolderTemplate:     folderTemplate,
+				FileMode:           fileMode,
+				DirMode:            dirMode,
+				NoMtime:            noMtime,
+				NoManifest:         noManifest,
+				Playlist:           playlist,
+				EmbedMetadata:      embedMetadata,
+				ContactSheet:       contactSheet,
+				WriteFeed:          writeFeed,
+				Quiet:              quiet,
+				JSON:               jsonOutput,
+				ForceLock:          forceLock,
+				Sync:               syncMode,
+				RenameMoved:        renameMoved,
+				DeleteRemoved:      deleteRemoved,
+				Tags:               history.NormalizeTags(tags),
+				Note:               strings.TrimSpace(note),
 			}
 
 			err = download.Download(config)
 			if err != nil {
-				log.Error("Download failed", "err", err)
+				reportError("Download failed", err)
 			}
 		}
 	},
 }
+
+// readSelection parses the selection given by the --select flag or read from the file
+// given by --select-file, - for stdin. Returns nil if neither is given.
+func readSelection(flagValue string, file string) (models.Selection, error) {
+	if file == "" {
+		if flagValue == "" {
+			return nil, nil
+		}
+
+		return models.ParseSelection(flagValue) //nolint:wrapcheck // Already wrapped by the models package
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file) //nolint:gosec // Path given by the user
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read selection file: %w", err)
+	}
+
+	return models.ParseSelection(string(data)) //nolint:wrapcheck // Already wrapped by the models package
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envPrefix starts the environment variables setting flags, e.g. SWITCHTUBE_OUTPUT for
+// --output or SWITCHTUBE_NO_INPUT for --no-input.
+const envPrefix = "SWITCHTUBE_"
+
+//nolint:gochecknoglobals // Set by the initializer and returned by the root command before running
+var errEnvFlags error
+
+// envExcluded holds the flags not read from the environment, because another variable
+// already stands for them: --select is read from SWITCHTUBE_SELECTION.
+//
+//nolint:gochecknoglobals // Read-only set of flag names
+var envExcluded = map[string]bool{"help": true, "select": true}
+
+// init reads the flags of the executed command from the environment once they are
+// parsed, before the arguments are validated, so an environment variable counts like
+// the flag given on the command line.
+func init() {
+	cobra.OnInitialize(func() {
+		cmd, _, err := rootCmd.Find(os.Args[1:])
+		if err == nil {
+			errEnvFlags = applyEnvFlags(cmd)
+		}
+	})
+}
+
+// applyEnvFlags sets every flag of cmd that was not given on the command line from its
+// environment variable, if that is set and not empty, except the flags in envExcluded.
+// Flags given on the command line take precedence, and both take precedence over the
+// config file. Returns an error naming each variable with an invalid value.
+func applyEnvFlags(cmd *cobra.Command) error {
+	var errs []error
+
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed || envExcluded[flag.Name] {
+			return
+		}
+
+		name := envName(flag.Name)
+
+		value := os.Getenv(name)
+		if value == "" {
+			return
+		}
+
+		if err := cmd.Flags().Set(flag.Name, value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s environment variable: %w", name, err))
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
+// envName returns the environment variable of the flag called name.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
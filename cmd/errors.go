@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"errors"
+
+	"switchtube-downloader/internal/download"
+	"switchtube-downloader/internal/helper/dir"
+	"switchtube-downloader/internal/helper/ui/input"
+	"switchtube-downloader/internal/i18n"
+)
+
+// Exit codes of the CLI.
+const (
+	exitOK       = 0   // Everything succeeded
+	exitFailure  = 1   // Unspecified failure
+	exitAuth     = 3   // Missing or rejected access token
+	exitNotFound = 4   // Video or channel does not exist
+	exitAPI      = 5   // SwitchTube answered with an unexpected status
+	exitAborted  = 130 // Aborted by the user (Ctrl+C or quit)
+)
+
+// Reasons an invalid flag is rejected for.
+var (
+	errNegative              = errors.New("must not be negative")
+	errNotPositive           = errors.New("must be positive")
+	errBelowOne              = errors.New("must be at least 1")
+	errMaxBelowMin           = errors.New("must not be shorter than --min-duration")
+	errBackupAndSkip         = errors.New("existing files are either backed up or skipped, not both")
+	errLinkAndOnDuplicate    = errors.New("cannot be combined with --on-duplicate")
+	errSelectFileAndSelect   = errors.New("cannot be combined with --select")
+	errSelectAndAll          = errors.New("cannot be combined with --all or --sync")
+	errRequiresSync          = errors.New("requires --sync")
+	errRequiresDeleteRemoved = errors.New("requires --delete-removed")
+	errArchiveArgs           = errors.New("an archive takes a single video or channel and no --output or --rclone-remote")
+	errDryRunOutput          = errors.New("a dry run writes no files, so it takes no --output - or --archive-output")
+	errStdoutArgs            = errors.New("writing to stdout requires a single video and no --json")
+	errStdoutRemux           = errors.New("videos written to stdout cannot be remuxed")
+	errStdoutUpload          = errors.New("videos written to stdout cannot be uploaded")
+	errNoListener            = errors.New("either --listen or --socket is required")
+	errUnknownFormat         = errors.New("unknown format")
+)
+
+//nolint:gochecknoglobals // Set by the commands and read by Execute on exit
+var exitCode = exitOK
+
+// reportError logs err with a friendly explanation of its cause and records the
+// matching exit code. The first failure of a run determines the exit code.
+func reportError(msg string, err error) {
+	code, hint := exitFailure, ""
+
+	var (
+		authErr     *download.AuthError
+		notFoundErr *download.NotFoundError
+		apiErr      *download.APIError
+	)
+
+	switch {
+	case errors.Is(err, input.ErrUserAbort):
+		code = exitAborted
+	case errors.As(err, &authErr):
+		code, hint = exitAuth, i18n.T("Check your access token with 'token validate' or store a new one with 'token set'")
+	case errors.As(err, &notFoundErr):
+		code, hint = exitNotFound, i18n.T("The video or channel does not exist or is not accessible with your token")
+	case errors.As(err, &apiErr):
+		code, hint = exitAPI, i18n.T("SwitchTube could not handle the request, try again later")
+	case errors.Is(err, dir.ErrFolderLocked):
+		hint = i18n.T("Wait for the other download to finish, or pass --force-lock if it is no longer running")
+	}
+
+	log.Error(i18n.T(msg), "err", err)
+
+	if hint != "" {
+		log.Info(hint)
+	}
+
+	if exitCode == exitOK {
+		exitCode = code
+	}
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestInvalidFlagsExitWithFailure runs commands with invalid flags and expects each to
+// fail with exitFailure and name the invalid flag, so scripts can detect the mistake.
+func TestInvalidFlagsExitWithFailure(t *testing.T) {
+	server := newTestServer(t)
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string // Part of stderr naming the invalid flag
+	}{
+		{name: "negative concurrency", args: []string{"download", "-j", "-1", testVideoID}, wantErr: "Invalid concurrency flag"},
+		{name: "unknown quality", args: []string{"download", "--quality", "best", testVideoID}, wantErr: "Invalid quality flag"},
+		{name: "select with all", args: []string{"download", "--select", "1", "--all", testVideoID}, wantErr: "Invalid select flag"},
+		{name: "zero interval", args: []string{"watch", "--interval", "0s", testVideoID}, wantErr: "Invalid interval flag"},
+		{name: "unknown export format", args: []string{"history", "export", "--format", "xml"}, wantErr: "Invalid format flag"},
+		{name: "no listener", args: []string{"serve", "--listen", ""}, wantErr: "Invalid listen flag"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			env := []string{"HOME=" + home, "XDG_CONFIG_HOME=" + filepath.Join(home, "config"), "XDG_CACHE_HOME=" + filepath.Join(home, "cache"), "SWITCHTUBE_TOKEN=" + testToken}
+			args := append([]string{"--base-url", server.URL, "--headless"}, tt.args...)
+
+			_, stderr, err := runChild(t, env, args)
+
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode() != exitFailure {
+				t.Fatalf("want exit code %d, got %v, stderr:\n%s", exitFailure, err, stderr)
+			}
+
+			if !strings.Contains(stderr, tt.wantErr) {
+				t.Fatalf("want stderr mentioning %q, got:\n%s", tt.wantErr, stderr)
+			}
+		})
+	}
+}
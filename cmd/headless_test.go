@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	// childArgsEnv passes the arguments of a run of the root command to the test binary
+	// started as child process, as JSON list.
+	childArgsEnv = "SWITCHTUBE_TEST_CHILD_ARGS"
+
+	testToken   = "test-token"
+	testVideoID = "abc123"
+	testVideo   = "video data of the test server"
+)
+
+var errKeyringUnavailable = errors.New("no D-Bus session bus, keyring unavailable")
+
+// TestMain runs the root command instead of the tests if started as child process by
+// runChild, so every run starts with fresh global state and a stdin without terminal.
+func TestMain(m *testing.M) {
+	if args, ok := os.LookupEnv(childArgsEnv); ok {
+		var argv []string
+		if err := json.Unmarshal([]byte(args), &argv); err != nil {
+			os.Exit(exitFailure)
+		}
+
+		// Any keyring access fails, like in a container without secret service
+		keyring.MockInitWithError(errKeyringUnavailable)
+		rootCmd.SetArgs(argv)
+		Execute()
+	}
+
+	os.Exit(m.Run())
+}
+
+// TestWithoutTerminalAndKeyring runs downloads without terminal and with a keyring that
+// cannot be read, for every token source with and without --headless.
+func TestWithoutTerminalAndKeyring(t *testing.T) {
+	server := newTestServer(t)
+
+	tests := []struct {
+		name     string
+		headless bool
+		source   string // Where the token comes from: env, file or none
+		wantErr  string // Part of stderr if the run fails, empty if it succeeds
+	}{
+		{name: "headless with token from env", headless: true, source: "env"},
+		{name: "headless with token from file", headless: true, source: "file"},
+		{name: "headless without token", headless: true, source: "none", wantErr: "SWITCHTUBE_TOKEN"},
+		{name: "token from env", source: "env"},
+		{name: "token from file", source: "file"},
+		{name: "without token", source: "none", wantErr: errKeyringUnavailable.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			output := t.TempDir()
+
+			env := []string{"HOME=" + home, "XDG_CONFIG_HOME=" + filepath.Join(home, "config"), "XDG_CACHE_HOME=" + filepath.Join(home, "cache")}
+			args := []string{"--base-url", server.URL}
+
+			switch tt.source {
+			case "env":
+				env = append(env, "SWITCHTUBE_TOKEN="+testToken)
+			case "file":
+				file := filepath.Join(home, "token")
+				if err := os.WriteFile(file, []byte(testToken+"\n"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+
+				args = append(args, "--token-file", file)
+			}
+
+			if tt.headless {
+				args = append(args, "--headless")
+			}
+
+			args = append(args, "download", "-o", output, testVideoID)
+
+			stdout, stderr, err := runChild(t, env, args)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(stderr, tt.wantErr) {
+					t.Fatalf("want failure mentioning %q, got err %v and stderr:\n%s", tt.wantErr, err, stderr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("run failed: %v, stderr:\n%s", err, stderr)
+			}
+
+			data, err := os.ReadFile(filepath.Join(output, "Intro.mp4"))
+			if err != nil || string(data) != testVideo {
+				t.Fatalf("want downloaded video, got %q (%v)", data, err)
+			}
+
+			if tt.headless {
+				checkJSONResult(t, stdout)
+			}
+		})
+	}
+}
+
+// checkJSONResult fails t unless stdout is the single JSON line of a successful download
+// of the test video.
+func checkJSONResult(t *testing.T, stdout string) {
+	t.Helper()
+
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("want a single line of JSON on stdout, got:\n%s", stdout)
+	}
+
+	var result struct {
+		Media  string `json:"media"`
+		Videos []struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		} `json:"videos"`
+		Aborted bool `json:"aborted"`
+	}
+
+	if err := json.Unmarshal([]byte(lines[0]), &result); err != nil {
+		t.Fatalf("invalid JSON result %q: %v", lines[0], err)
+	}
+
+	if result.Media != testVideoID || result.Aborted || len(result.Videos) != 1 ||
+		result.Videos[0].ID != testVideoID || result.Videos[0].Status != "downloaded" {
+		t.Fatalf("unexpected JSON result: %s", lines[0])
+	}
+}
+
+// runChild runs the root command with args in a child process with only env as
+// environment and stdin connected to nothing. Returns its stdout, stderr and exit error.
+// A run that does not finish within a minute counts as hanging, e.g. on a prompt.
+func runChild(t *testing.T, env []string, args []string) (string, string, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Minute)
+	defer cancel()
+
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=^$") //nolint:gosec // The test binary itself
+	cmd.Env = append(env, childArgsEnv+"="+string(encoded), "PATH="+os.Getenv("PATH"))
+
+	var stdout, stderr bytes.Buffer
+
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		t.Fatalf("run hung, stderr:\n%s", stderr.String())
+	}
+
+	return stdout.String(), stderr.String(), err
+}
+
+// newTestServer starts a SwitchTube API serving a single video to requests with the
+// test token.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/profiles/me", func(w http.ResponseWriter, _ *http.Request) {
+		writeTestJSON(w, map[string]any{"name": "Test"})
+	})
+	mux.HandleFunc("GET /api/v1/browse/videos/"+testVideoID, func(w http.ResponseWriter, _ *http.Request) {
+		writeTestJSON(w, map[string]any{"id": testVideoID, "title": "Intro"})
+	})
+	mux.HandleFunc("GET /api/v1/browse/videos/"+testVideoID+"/video_variants", func(w http.ResponseWriter, _ *http.Request) {
+		writeTestJSON(w, []map[string]any{{"path": "/storage/intro.mp4", "media_type": "video/mp4"}})
+	})
+	mux.HandleFunc("/storage/intro.mp4", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "intro.mp4", time.Time{}, strings.NewReader(testVideo))
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token "+testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// writeTestJSON writes value as JSON response.
+func writeTestJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
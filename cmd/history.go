@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"switchtube-downloader/internal/history"
+
+	"github.com/spf13/cobra"
+)
+
+// historyFilePermissions are the permissions of an export written with --output.
+const historyFilePermissions = 0o644
+
+// init initializes the history command and its subcommands, adding them to the root command.
+func init() {
+	historyExportCmd.Flags().String("format", history.FormatCSV, "Format of the export (csv, json)")
+	historyExportCmd.Flags().StringP("output", "o", "", "Write the export to this file instead of stdout")
+	historyExportCmd.Flags().String("tag", "", "Only export downloads recorded with this tag")
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyExportCmd)
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Work with the history of downloaded videos",
+	Long:  "Work with the history of downloaded videos stored in the user config directory",
+	Run: func(cmd *cobra.Command, _ []string) {
+		if err := cmd.Help(); err != nil {
+			reportError("Error displaying help", err)
+		}
+	},
+}
+
+var historyExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the download history as CSV or JSON",
+	Long: "Export every recorded download with its video, channel, file, size, tags and note, e.g. for a\n" +
+		"spreadsheet or external tooling. The export is written to stdout unless --output is given.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			reportError("Error getting format flag", err)
+
+			return
+		}
+
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format != history.FormatCSV && format != history.FormatJSON {
+			reportError("Invalid format flag", fmt.Errorf("%w: %q (expected csv or json)", errUnknownFormat, format))
+
+			return
+		}
+
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			reportError("Error getting output flag", err)
+
+			return
+		}
+
+		tag, err := cmd.Flags().GetString("tag")
+		if err != nil {
+			reportError("Error getting tag flag", err)
+
+			return
+		}
+
+		entries, err := history.Load()
+		if err != nil {
+			reportError("Loading the history failed", err)
+
+			return
+		}
+
+		if err := exportHistory(history.FilterByTag(entries, strings.TrimSpace(tag)), format, output); err != nil {
+			reportError("Exporting the history failed", err)
+		}
+	},
+}
+
+// exportHistory writes entries in format to the file output, or to stdout if output is empty.
+func exportHistory(entries []history.Entry, format string, output string) error {
+	if output == "" {
+		return history.Export(os.Stdout, entries, format) //nolint:wrapcheck // Already wrapped by the history package
+	}
+
+	file, err := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, historyFilePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	if err := history.Export(file, entries, format); err != nil {
+		_ = file.Close()
+
+		return err //nolint:wrapcheck // Already wrapped by the history package
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"switchtube-downloader/internal/download"
+
+	"github.com/spf13/cobra"
+)
+
+// init initializes the info command and adds it to the root command.
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
+
+var infoCmd = &cobra.Command{
+	Use:   "info <id|url>...",
+	Short: "Show the details and available variants of videos",
+	Long: "Show the metadata of each video and its variants from the highest to the lowest quality,\n" +
+		"with their media type, and the resolution and size read from their files on the server.",
+	Args: cobra.MinimumNArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		if err := download.Info(args); err != nil {
+			reportError("Showing video info failed", err)
+		}
+	},
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"switchtube-downloader/internal/download"
+
+	"github.com/spf13/cobra"
+)
+
+// init initializes the open command and adds it to the root command.
+func init() {
+	rootCmd.AddCommand(openCmd)
+}
+
+var openCmd = &cobra.Command{
+	Use:   "open <id|url|file>",
+	Short: "Open a video or channel on SwitchTube in the browser",
+	Long: "Open the SwitchTube page of a video or channel in the default browser.\n" +
+		"Pass a video or channel ID or URL, or a downloaded file to open the page of the video it came from.",
+	Args: cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		if err := download.Open(args[0]); err != nil {
+			reportError("Opening failed", err)
+		}
+	},
+}
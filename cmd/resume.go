@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"switchtube-downloader/internal/download"
+
+	"github.com/spf13/cobra"
+)
+
+// init initializes the resume command and adds it to the root command.
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume [channel-id|url]...",
+	Short: "Resume interrupted or partially failed channel downloads",
+	Long: "Resume interrupted or partially failed channel downloads. Only the missing or failed videos are\n" +
+		"downloaded again, using the same selection, folder and flags as the original run.\n" +
+		"Without arguments, all unfinished channel downloads are resumed.",
+	Run: func(_ *cobra.Command, args []string) {
+		if err := download.Resume(args); err != nil {
+			reportError("Resume failed", err)
+		}
+	},
+}
@@ -2,13 +2,55 @@ package cmd
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+
+	"switchtube-downloader/internal/download"
+	"switchtube-downloader/internal/helper/ui/input"
+	"switchtube-downloader/internal/helper/ui/progress"
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/onboarding"
+	"switchtube-downloader/internal/redact"
+	"switchtube-downloader/internal/settings"
+	"switchtube-downloader/internal/systemd"
+	"switchtube-downloader/internal/token"
+	"switchtube-downloader/internal/tracing"
 
 	"github.com/charmbracelet/fang"
+	charm "github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
 )
 
+var (
+	errHeadlessToken  = errors.New("headless mode never reads the keyring, set " + settings.TokenEnv + " or --token-file")
+	errEmptyTokenFile = errors.New("empty token file")
+)
+
+// init registers the global flags of the root command.
+func init() {
+	rootCmd.PersistentFlags().Bool("no-input", false, "Never prompt; fail with an error where input would be required")
+	rootCmd.PersistentFlags().BoolP("yes", "y", false, "Answer yes to all confirmations, e.g. to overwrite existing files")
+	rootCmd.PersistentFlags().Duration("prompt-timeout", 0, "Take the default answer of a prompt after waiting this long (0 to wait forever)")
+	rootCmd.PersistentFlags().String("base-url", "", "SwitchTube instance to use (default "+settings.DefaultBaseURL+")")
+	rootCmd.PersistentFlags().Bool("trace-http", false, "Log every HTTP request and response with redacted headers to stderr")
+	rootCmd.PersistentFlags().Bool("strict-api", false, "Fail on fields of SwitchTube API responses this version does not know instead of ignoring them")
+	rootCmd.PersistentFlags().Bool("headless", false, "Run without terminal or keyring: no prompts or colors, token from "+settings.TokenEnv+" or --token-file")
+	rootCmd.PersistentFlags().String("token-file", "", "Read the access token from this file instead of the keyring, e.g. a Docker secret")
+	rootCmd.PersistentFlags().Bool("skip-validation", false, "Use the stored access token without validating it against SwitchTube first")
+	rootCmd.PersistentFlags().String("lang", "", "Language of messages: en or de (default from LANG)")
+	rootCmd.PersistentFlags().Duration("refresh-rate", 0, "Redraw progress bars at this interval, e.g. 1s on small devices (0 to adapt to the terminal)")
+	rootCmd.PersistentFlags().String("progress-file", "", "Keep a JSON snapshot of the download progress in this file, e.g. for status bars to poll")
+	rootCmd.PersistentFlags().String("ui-stream", stream.Stderr, "Stream for progress bars, tables and prompts (stderr, stdout)")
+	rootCmd.PersistentFlags().Duration("api-timeout", download.DefaultAPITimeout, "Time limit of a metadata request to SwitchTube (0 for none)")
+	rootCmd.PersistentFlags().StringArray("header", nil, "Send this extra HTTP header (\"Name: value\") with every request to SwitchTube, can be repeated")
+	rootCmd.PersistentFlags().Duration("stall-timeout", download.DefaultStallTimeout, "Reconnect a video download after receiving no data for this long (0 to wait forever)")
+}
+
 var rootCmd = &cobra.Command{
 	Use:   filepath.Base(os.Args[0]),
 	Short: "A CLI downloader for SwitchTube videos",
@@ -16,11 +58,305 @@ var rootCmd = &cobra.Command{
 	CompletionOptions: cobra.CompletionOptions{
 		DisableDefaultCmd: true,
 	},
+
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		if errEnvFlags != nil {
+			return errEnvFlags
+		}
+
+		// Under systemd, log lines carry their priority for the journal, warnings included
+		if systemd.LogsToJournal(os.Stderr) {
+			log.SetOutput(systemd.JournalWriter(redact.Stream(os.Stderr)))
+			log.SetFormatter(charm.LogfmtFormatter)
+			stream.LogWarnings()
+		}
+
+		uiStream, err := cmd.Flags().GetString("ui-stream")
+		if err != nil {
+			return fmt.Errorf("error getting ui-stream flag: %w", err)
+		}
+
+		if err := stream.Select(uiStream); err != nil {
+			return fmt.Errorf("invalid --ui-stream flag: %w", err)
+		}
+
+		noInput, err := cmd.Flags().GetBool("no-input")
+		if err != nil {
+			return fmt.Errorf("error getting no-input flag: %w", err)
+		}
+
+		if noInput {
+			input.DisablePrompts()
+		}
+
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return fmt.Errorf("error getting yes flag: %w", err)
+		}
+
+		if yes {
+			input.AssumeYes()
+		}
+
+		promptTimeout, err := cmd.Flags().GetDuration("prompt-timeout")
+		if err != nil {
+			return fmt.Errorf("error getting prompt-timeout flag: %w", err)
+		}
+
+		if err := input.SetPromptTimeout(promptTimeout); err != nil {
+			return fmt.Errorf("invalid --prompt-timeout flag: %w", err)
+		}
+
+		refreshRate, err := cmd.Flags().GetDuration("refresh-rate")
+		if err != nil {
+			return fmt.Errorf("error getting refresh-rate flag: %w", err)
+		}
+
+		if err := progress.SetRefreshRate(refreshRate); err != nil {
+			return fmt.Errorf("invalid --refresh-rate flag: %w", err)
+		}
+
+		progressFile, err := cmd.Flags().GetString("progress-file")
+		if err != nil {
+			return fmt.Errorf("error getting progress-file flag: %w", err)
+		}
+
+		if err := progress.SetProgressFile(progressFile); err != nil {
+			return fmt.Errorf("invalid --progress-file flag: %w", err)
+		}
+
+		traceHTTP, err := cmd.Flags().GetBool("trace-http")
+		if err != nil {
+			return fmt.Errorf("error getting trace-http flag: %w", err)
+		}
+
+		if traceHTTP {
+			tracing.Enable()
+		}
+
+		strictAPI, err := cmd.Flags().GetBool("strict-api")
+		if err != nil {
+			return fmt.Errorf("error getting strict-api flag: %w", err)
+		}
+
+		if strictAPI {
+			download.StrictAPI()
+		}
+
+		tokenFile, err := cmd.Flags().GetString("token-file")
+		if err != nil {
+			return fmt.Errorf("error getting token-file flag: %w", err)
+		}
+
+		if err := selectToken(tokenFile); err != nil {
+			return fmt.Errorf("invalid --token-file flag: %w", err)
+		}
+
+		headless, err := cmd.Flags().GetBool("headless")
+		if err != nil {
+			return fmt.Errorf("error getting headless flag: %w", err)
+		}
+
+		if headless {
+			input.DisablePrompts()
+
+			if slices.Contains(tokenCommands(), cmd) && !token.Provided() {
+				return errHeadlessToken
+			}
+		}
+
+		skipValidation, err := cmd.Flags().GetBool("skip-validation")
+		if err != nil {
+			return fmt.Errorf("error getting skip-validation flag: %w", err)
+		}
+
+		if skipValidation {
+			token.SkipValidation()
+		}
+
+		apiTimeout, err := cmd.Flags().GetDuration("api-timeout")
+		if err != nil {
+			return fmt.Errorf("error getting api-timeout flag: %w", err)
+		}
+
+		stallTimeout, err := cmd.Flags().GetDuration("stall-timeout")
+		if err != nil {
+			return fmt.Errorf("error getting stall-timeout flag: %w", err)
+		}
+
+		if err := download.SetTimeouts(apiTimeout, stallTimeout); err != nil {
+			return fmt.Errorf("invalid timeout flags: %w", err)
+		}
+
+		headers, err := cmd.Flags().GetStringArray("header")
+		if err != nil {
+			return fmt.Errorf("error getting header flag: %w", err)
+		}
+
+		settings.SetVersion(version)
+
+		if err := settings.SetHeaders(headers); err != nil {
+			return fmt.Errorf("invalid --header flag: %w", err)
+		}
+
+		lang, err := cmd.Flags().GetString("lang")
+		if err != nil {
+			return fmt.Errorf("error getting lang flag: %w", err)
+		}
+
+		if err := selectLanguage(lang); err != nil {
+			return err
+		}
+
+		baseURL, err := cmd.Flags().GetString("base-url")
+		if err != nil {
+			return fmt.Errorf("error getting base-url flag: %w", err)
+		}
+
+		// The config commands read the file themselves, so a broken one can still be fixed
+		if cmd.Parent() == configCmd {
+			return selectInstance(baseURL, settings.Config{})
+		}
+
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		if err := selectInstance(baseURL, cfg); err != nil {
+			return err
+		}
+
+		if slices.Contains(tokenCommands(), cmd) {
+			tm := token.NewTokenManager()
+
+			if onboarding.Needed(tm) {
+				if cfg, err = onboarding.Run(tm, cfg); err != nil {
+					return fmt.Errorf("first-run setup failed: %w", err)
+				}
+			}
+		}
+
+		if headless {
+			cfg.Color = stream.ColorNever
+		}
+
+		if err := stream.SetColor(cfg.Color); err != nil {
+			return fmt.Errorf("invalid color in config: %w", err)
+		}
+
+		return nil
+	},
+}
+
+// tokenCommands returns the commands that need an access token, so they start the
+// first-run setup if none is stored yet instead of failing.
+func tokenCommands() []*cobra.Command {
+	return []*cobra.Command{downloadCmd, watchCmd, resumeCmd, tuiCmd, serveCmd, playCmd, urlCmd, infoCmd}
 }
 
 // Execute runs the root command and handles any errors.
+// Exits with the code recorded by reportError if a command failed.
 func Execute() {
 	if err := fang.Execute(context.Background(), rootCmd); err != nil {
-		os.Exit(1)
+		os.Exit(exitFailure)
+	}
+
+	os.Exit(exitCode)
+}
+
+// loadConfig reads the configuration file. Headless runs go without it if there is no
+// config directory, as containers often come without home directory.
+func loadConfig(cmd *cobra.Command) (settings.Config, error) {
+	cfg, err := settings.Load()
+	if err == nil {
+		return cfg, nil
+	}
+
+	if headless, _ := cmd.Flags().GetBool("headless"); headless && errors.Is(err, settings.ErrNoConfigDir) {
+		return settings.Config{}, nil
 	}
+
+	return settings.Config{}, err //nolint:wrapcheck // Already wrapped by the settings package
+}
+
+// outputAndConcurrency returns the --output and --concurrency flags of cmd. The output
+// directory and concurrency saved in cfg apply where a flag is not given, or cmd has no
+// such flag, so the preferences saved by the first-run setup or 'config set' are used
+// by every command that downloads.
+func outputAndConcurrency(cmd *cobra.Command, cfg settings.Config) (string, int, error) {
+	output, concurrency := cfg.OutputDir, cfg.Concurrency
+
+	if cmd.Flags().Changed("output") {
+		value, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return "", 0, fmt.Errorf("error getting output flag: %w", err)
+		}
+
+		output = value
+	}
+
+	if cmd.Flags().Changed("concurrency") {
+		value, err := cmd.Flags().GetInt("concurrency")
+		if err != nil {
+			return "", 0, fmt.Errorf("error getting concurrency flag: %w", err)
+		}
+
+		concurrency = value
+	}
+
+	return output, concurrency, nil
+}
+
+// selectLanguage selects the language given by the --lang flag, or detects it from the locale.
+func selectLanguage(flagValue string) error {
+	if flagValue == "" {
+		i18n.Detect()
+
+		return nil
+	}
+
+	if err := i18n.SetLanguage(flagValue); err != nil {
+		return fmt.Errorf("invalid --lang flag: %w", err)
+	}
+
+	return nil
+}
+
+// selectToken uses the access token read from the file at path, or given by the
+// SWITCHTUBE_TOKEN environment variable, instead of the keyring. Keeps the keyring if
+// neither is set.
+func selectToken(path string) error {
+	value := os.Getenv(settings.TokenEnv)
+
+	if path != "" {
+		data, err := os.ReadFile(path) //nolint:gosec // Path given by the user
+		if err != nil {
+			return fmt.Errorf("failed to read token file: %w", err)
+		}
+
+		if value = string(data); strings.TrimSpace(value) == "" {
+			return fmt.Errorf("%w: %s", errEmptyTokenFile, path)
+		}
+	}
+
+	if value = strings.TrimSpace(value); value != "" {
+		token.Use(value)
+	}
+
+	return nil
+}
+
+// selectInstance selects the SwitchTube instance given by the --base-url flag, which is
+// also read from the SWITCHTUBE_BASE_URL environment variable, or the config file.
+func selectInstance(flagValue string, cfg settings.Config) error {
+	if flagValue != "" {
+		return settings.SetBaseURL(flagValue)
+	}
+
+	if cfg.BaseURL != "" {
+		return settings.SetBaseURL(cfg.BaseURL)
+	}
+
+	return nil
 }
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"strings"
+
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/server"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultListenAddr is the address the API listens on. Only local clients can connect.
+const defaultListenAddr = "127.0.0.1:8765"
+
+// init initializes the serve command and adds it to the root command with its flags.
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().BoolP("episode", "e", false, "Prefixes the video with episode-number e.g. 01_OR_Mapping.mp4")
+	serveCmd.Flags().BoolP("force", "f", false, "Force overwrite if file already exist, otherwise existing files are skipped")
+	serveCmd.Flags().StringP("output", "o", "", "Output directory for downloaded files")
+	serveCmd.Flags().IntP("concurrency", "j", 0, "Download at most this many videos at once (0 for all at once)")
+	serveCmd.Flags().String("listen", defaultListenAddr, "Loopback address the HTTP API listens on, empty to listen only on --socket")
+	serveCmd.Flags().String("socket", "", "Also serve the HTTP API on a unix socket at this path")
+	serveCmd.Flags().Bool("force-lock", false, "Write into the output directory even if another run is using it")
+	serveCmd.Flags().StringArray("allow-extension", nil, "Let the browser extension with this origin call the API, e.g. chrome-extension://<id>, can be repeated")
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a local HTTP API to enqueue and follow downloads",
+	Long: "Serve a small HTTP API on localhost so other programs like a browser extension or a\n" +
+		"graphical frontend can enqueue downloads, query their progress and list the download history.\n" +
+		"Downloads run one after the other through the same engine as the download command.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		episode, err := cmd.Flags().GetBool("episode")
+		if err != nil {
+			reportError("Error getting episode flag", err)
+
+			return
+		}
+
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			reportError("Error getting force flag", err)
+
+			return
+		}
+
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			reportError("Error loading config", err)
+
+			return
+		}
+
+		output, concurrency, err := outputAndConcurrency(cmd, cfg)
+		if err != nil {
+			reportError("Error getting flags", err)
+
+			return
+		}
+
+		if concurrency < 0 {
+			reportError("Invalid concurrency flag", errNegative)
+
+			return
+		}
+
+		listen, err := cmd.Flags().GetString("listen")
+		if err != nil {
+			reportError("Error getting listen flag", err)
+
+			return
+		}
+
+		socket, err := cmd.Flags().GetString("socket")
+		if err != nil {
+			reportError("Error getting socket flag", err)
+
+			return
+		}
+
+		if listen == "" && socket == "" {
+			reportError("Invalid listen flag", errNoListener)
+
+			return
+		}
+
+		forceLock, err := cmd.Flags().GetBool("force-lock")
+		if err != nil {
+			reportError("Error getting force-lock flag", err)
+
+			return
+		}
+
+		extensions, err := cmd.Flags().GetStringArray("allow-extension")
+		if err != nil {
+			reportError("Error getting allow-extension flag", err)
+
+			return
+		}
+
+		config := models.DownloadConfig{
+			OutputDir:   strings.TrimSpace(output),
+			OnCollision: models.CollisionRename,
+			Quality:     models.QualityHighest,
+			Concurrency: concurrency,
+			UseEpisode:  episode,
+			ForceLock:   forceLock,
+			Force:       force,
+		}
+
+		if err := server.Run(config, listen, socket, extensions); err != nil {
+			reportError("Serve failed", err)
+		}
+	},
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/helper/ui/table"
+	"switchtube-downloader/internal/history"
+	"switchtube-downloader/internal/i18n"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultBusiestDays is the number of busiest days shown per default.
+const defaultBusiestDays = 5
+
+// init initializes the stats command and adds it to the root command with its flags.
+func init() {
+	statsCmd.Flags().Int("days", defaultBusiestDays, "Number of busiest days to show")
+	statsCmd.Flags().String("tag", "", "Only include downloads recorded with this tag")
+	rootCmd.AddCommand(statsCmd)
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize everything downloaded so far",
+	Long: "Summarize the download history: the total downloaded size and number of videos, the downloads\n" +
+		"per channel and the busiest days. Use --tag to summarize only the downloads recorded with a tag.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		days, err := cmd.Flags().GetInt("days")
+		if err != nil {
+			reportError("Error getting days flag", err)
+
+			return
+		}
+
+		if days < 0 {
+			reportError("Invalid days flag", errNegative)
+
+			return
+		}
+
+		tag, err := cmd.Flags().GetString("tag")
+		if err != nil {
+			reportError("Error getting tag flag", err)
+
+			return
+		}
+
+		entries, err := history.Load()
+		if err != nil {
+			reportError("Loading the history failed", err)
+
+			return
+		}
+
+		if len(entries) == 0 {
+			fmt.Fprintln(stream.UI(), i18n.T("No downloads recorded yet"))
+
+			return
+		}
+
+		entries = history.FilterByTag(entries, strings.TrimSpace(tag))
+		if len(entries) == 0 {
+			fmt.Fprintln(stream.UI(), i18n.T("No downloads recorded with tag %q", tag))
+
+			return
+		}
+
+		table.DisplayHistoryStats(history.Summarize(entries, days))
+	},
+}
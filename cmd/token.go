@@ -1,17 +1,21 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"switchtube-downloader/internal/redact"
 	"switchtube-downloader/internal/token"
 
 	charm "github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
 )
 
-var log = charm.NewWithOptions(os.Stderr, charm.Options{
+var log = charm.NewWithOptions(redact.Stream(os.Stderr), charm.Options{
 	ReportTimestamp: false,
 	ReportCaller:    false,
 })
@@ -21,6 +25,7 @@ func init() {
 	rootCmd.AddCommand(tokenCmd)
 	tokenCmd.AddCommand(tokenGetCmd)
 	tokenCmd.AddCommand(tokenSetCmd)
+	tokenSetCmd.Flags().Bool("browser", false, "Create the token in the browser and submit it on a local page instead of pasting it here")
 	tokenCmd.AddCommand(tokenDeleteCmd)
 	tokenCmd.AddCommand(tokenValidateCmd)
 }
@@ -31,7 +36,7 @@ var tokenCmd = &cobra.Command{
 	Long:  "Manage the SwitchTube access token stored in the system keyring",
 	Run: func(cmd *cobra.Command, _ []string) {
 		if err := cmd.Help(); err != nil {
-			log.Error("Error displaying help", "err", err)
+			reportError("Error displaying help", err)
 		}
 	},
 }
@@ -45,7 +50,7 @@ var tokenGetCmd = &cobra.Command{
 
 		t, err := tokenMgr.GetRaw()
 		if err != nil {
-			log.Error("Error getting token", "err", err)
+			reportError("Error getting token", err)
 
 			return
 		}
@@ -57,12 +62,29 @@ var tokenGetCmd = &cobra.Command{
 var tokenSetCmd = &cobra.Command{
 	Use:   "set",
 	Short: "Set a new access token",
-	Long:  "Create and store a new SwitchTube access token in the system keyring",
-	Run: func(_ *cobra.Command, _ []string) {
+	Long: "Create and store a new SwitchTube access token in the system keyring.\n" +
+		"With --browser, a local page opens in the browser that links to SwitchTube and takes the created token.",
+	Run: func(cmd *cobra.Command, _ []string) {
+		useBrowser, err := cmd.Flags().GetBool("browser")
+		if err != nil {
+			reportError("Error getting browser flag", err)
+
+			return
+		}
+
 		tokenMgr := token.NewTokenManager()
 
-		if err := tokenMgr.Set(); err != nil && !errors.Is(err, token.ErrTokenAlreadyExists) {
-			log.Error("Error setting token", "err", err)
+		if useBrowser {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			err = tokenMgr.SetFromBrowser(ctx)
+		} else {
+			err = tokenMgr.Set()
+		}
+
+		if err != nil && !errors.Is(err, token.ErrTokenAlreadyExists) {
+			reportError("Error setting token", err)
 		}
 	},
 }
@@ -75,7 +97,7 @@ var tokenDeleteCmd = &cobra.Command{
 		tokenMgr := token.NewTokenManager()
 
 		if err := tokenMgr.Delete(); err != nil {
-			log.Error("Error deleting token", "err", err)
+			reportError("Error deleting token", err)
 		}
 	},
 }
@@ -88,7 +110,7 @@ var tokenValidateCmd = &cobra.Command{
 		tokenMgr := token.NewTokenManager()
 
 		if err := tokenMgr.Validate(); err != nil {
-			log.Error("Error validating token", "err", err)
+			reportError("Error validating token", err)
 		}
 	},
 }
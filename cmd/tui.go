@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"strings"
+
+	"switchtube-downloader/internal/helper/ui/input"
+	"switchtube-downloader/internal/helper/ui/tui"
+	"switchtube-downloader/internal/models"
+
+	"github.com/spf13/cobra"
+)
+
+// init initializes the tui command and adds it to the root command with its flags.
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+	tuiCmd.Flags().BoolP("episode", "e", false, "Prefixes the video with episode-number e.g. 01_OR_Mapping.mp4")
+	tuiCmd.Flags().BoolP("force", "f", false, "Force overwrite if file already exist, otherwise existing files are skipped")
+	tuiCmd.Flags().StringP("output", "o", "", "Output directory for downloaded files")
+	tuiCmd.Flags().IntP("concurrency", "j", 0, "Download at most this many videos at once (0 for all at once)")
+	tuiCmd.Flags().String("order", string(models.OrderSelection), "Order in which videos are downloaded (selection, episode, smallest, largest)")
+}
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Browse, select and download videos in a full-screen interface",
+	Long: "Browse, select and download videos in a full-screen interface.\n" +
+		"Enter a video or channel ID or URL, choose the videos and the quality, and follow the download progress.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		if input.PromptsDisabled() {
+			reportError("The tui command is interactive", input.ErrNoInput)
+
+			return
+		}
+
+		episode, err := cmd.Flags().GetBool("episode")
+		if err != nil {
+			reportError("Error getting episode flag", err)
+
+			return
+		}
+
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			reportError("Error getting force flag", err)
+
+			return
+		}
+
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			reportError("Error loading config", err)
+
+			return
+		}
+
+		output, concurrency, err := outputAndConcurrency(cmd, cfg)
+		if err != nil {
+			reportError("Error getting flags", err)
+
+			return
+		}
+
+		if concurrency < 0 {
+			reportError("Invalid concurrency flag", errNegative)
+
+			return
+		}
+
+		orderFlag, err := cmd.Flags().GetString("order")
+		if err != nil {
+			reportError("Error getting order flag", err)
+
+			return
+		}
+
+		order, err := models.ParseDownloadOrder(orderFlag)
+		if err != nil {
+			reportError("Invalid order flag", err)
+
+			return
+		}
+
+		config := models.DownloadConfig{
+			OutputDir:   strings.TrimSpace(output),
+			OnCollision: models.CollisionRename,
+			Quality:     models.QualityHighest,
+			Order:       order,
+			Concurrency: concurrency,
+			UseEpisode:  episode,
+			Force:       force,
+		}
+
+		if err := tui.Run(config); err != nil {
+			reportError("Interface failed", err)
+		}
+	},
+}
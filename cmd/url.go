@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"switchtube-downloader/internal/download"
+	"switchtube-downloader/internal/models"
+
+	"github.com/spf13/cobra"
+)
+
+// init initializes the url command and adds it to the root command with its flags.
+func init() {
+	urlCmd.Flags().Bool("all", false, "List every variant with its media type instead of the one that would be downloaded")
+	urlCmd.Flags().Bool("lowest", false, "Print the URL of the smallest variant")
+	urlCmd.Flags().Bool("header", false, "Print the Authorization header needed to fetch the URLs first")
+	rootCmd.AddCommand(urlCmd)
+}
+
+var urlCmd = &cobra.Command{
+	Use:   "url <id|url>...",
+	Short: "Print the direct download URLs of videos",
+	Long: "Print the direct download URL of each video to stdout, e.g. for a download manager.\n" +
+		"SwitchTube does not sign its URLs, so they can only be fetched with the Authorization header,\n" +
+		"which --header prints as first line.",
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			reportError("Error getting all flag", err)
+
+			return
+		}
+
+		lowest, err := cmd.Flags().GetBool("lowest")
+		if err != nil {
+			reportError("Error getting lowest flag", err)
+
+			return
+		}
+
+		header, err := cmd.Flags().GetBool("header")
+		if err != nil {
+			reportError("Error getting header flag", err)
+
+			return
+		}
+
+		quality := models.QualityHighest
+		if lowest {
+			quality = models.QualityLowest
+		}
+
+		if err := download.PrintURLs(args, quality, all, header); err != nil {
+			reportError("Printing URLs failed", err)
+		}
+	},
+}
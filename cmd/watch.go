@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"switchtube-downloader/internal/download"
+	"switchtube-downloader/internal/metrics"
+	"switchtube-downloader/internal/models"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultWatchInterval is the time between two checks of the watched channels.
+const defaultWatchInterval = time.Hour
+
+// init initializes the watch command and adds it to the root command with its flags.
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().BoolP("episode", "e", false, "Prefixes the video with episode-number e.g. 01_OR_Mapping.mp4")
+	watchCmd.Flags().StringP("output", "o", "", "Output directory for downloaded files")
+	watchCmd.Flags().BoolP("quiet", "q", false, "Print only the final results, without progress bars and tables")
+	watchCmd.Flags().Bool("flat", false, "Place channel videos directly in the output directory instead of a channel folder")
+	watchCmd.Flags().DurationP("interval", "i", defaultWatchInterval, "Time between two checks of the channels, e.g. 30m or 6h")
+	watchCmd.Flags().Bool("backfill", false, "Also download videos published before watching started")
+	watchCmd.Flags().String("rclone-remote", "", "Upload every completed video with rclone to this remote path, e.g. gdrive:Lectures")
+	watchCmd.Flags().Bool("rclone-move", false, "With --rclone-remote, delete the local files once they are uploaded")
+	watchCmd.Flags().Bool("force-lock", false, "Write into the output directory even if another run is using it")
+	watchCmd.Flags().String("metrics-listen", "", "Serve Prometheus metrics at /metrics on this address, e.g. 127.0.0.1:9465")
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch <channel-id|url> [channel-id|url]...",
+	Short: "Download new videos of channels as they are published",
+	Long: "Check one or more channels at a regular interval and download every video that is not in the\n" +
+		"download history yet. Only videos published after watching started are downloaded, unless\n" +
+		"--backfill is set. Runs until interrupted.",
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		episode, err := cmd.Flags().GetBool("episode")
+		if err != nil {
+			reportError("Error getting episode flag", err)
+
+			return
+		}
+
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			reportError("Error loading config", err)
+
+			return
+		}
+
+		output, concurrency, err := outputAndConcurrency(cmd, cfg)
+		if err != nil {
+			reportError("Error getting flags", err)
+
+			return
+		}
+
+		if concurrency < 0 {
+			reportError("Invalid concurrency in config", errNegative)
+
+			return
+		}
+
+		quiet, err := cmd.Flags().GetBool("quiet")
+		if err != nil {
+			reportError("Error getting quiet flag", err)
+
+			return
+		}
+
+		flat, err := cmd.Flags().GetBool("flat")
+		if err != nil {
+			reportError("Error getting flat flag", err)
+
+			return
+		}
+
+		interval, err := cmd.Flags().GetDuration("interval")
+		if err != nil {
+			reportError("Error getting interval flag", err)
+
+			return
+		}
+
+		if interval <= 0 {
+			reportError("Invalid interval flag", errNotPositive)
+
+			return
+		}
+
+		backfill, err := cmd.Flags().GetBool("backfill")
+		if err != nil {
+			reportError("Error getting backfill flag", err)
+
+			return
+		}
+
+		forceLock, err := cmd.Flags().GetBool("force-lock")
+		if err != nil {
+			reportError("Error getting force-lock flag", err)
+
+			return
+		}
+
+		rcloneRemote, err := cmd.Flags().GetString("rclone-remote")
+		if err != nil {
+			reportError("Error getting rclone-remote flag", err)
+
+			return
+		}
+
+		rcloneMove, err := cmd.Flags().GetBool("rclone-move")
+		if err != nil {
+			reportError("Error getting rclone-move flag", err)
+
+			return
+		}
+
+		metricsListen, err := cmd.Flags().GetString("metrics-listen")
+		if err != nil {
+			reportError("Error getting metrics-listen flag", err)
+
+			return
+		}
+
+		if metricsListen = strings.TrimSpace(metricsListen); metricsListen != "" {
+			stopMetrics, err := metrics.Serve(cmd.Context(), metricsListen)
+			if err != nil {
+				reportError("Watch failed", err)
+
+				return
+			}
+			defer stopMetrics()
+		}
+
+		config := models.DownloadConfig{
+			OutputDir:    strings.TrimSpace(output),
+			OnCollision:  models.CollisionRename,
+			Quality:      models.QualityHighest,
+			Concurrency:  concurrency,
+			UseEpisode:   episode,
+			ForceLock:    forceLock,
+			Flat:         flat,
+			Quiet:        quiet,
+			RcloneRemote: strings.TrimSpace(rcloneRemote),
+			RcloneMove:   rcloneMove,
+		}
+
+		if err := download.Watch(config, args, interval, backfill); err != nil {
+			reportError("Watch failed", err)
+		}
+	},
+}
@@ -0,0 +1,98 @@
+// Package auth abstracts how requests to SwitchTube are authenticated, so schemes other
+// than API tokens can be added without touching the sites sending requests.
+package auth
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// headerAuthorization carries the API token.
+const headerAuthorization = "Authorization"
+
+// Provider supplies the credentials of requests to SwitchTube.
+type Provider interface {
+	// Credentials returns the headers authenticating a request, fetching the credentials
+	// on first use and reusing them afterwards.
+	Credentials(ctx context.Context) (http.Header, error)
+	// Reject drops the credentials sent as header after SwitchTube rejected them, so the
+	// next call of Credentials fetches them again. Other requests may have dropped them already.
+	Reject(header http.Header)
+}
+
+// TokenSource supplies SwitchTube API tokens, e.g. the token manager.
+type TokenSource interface {
+	// Get returns the stored token, validated against SwitchTube.
+	Get(ctx context.Context) (string, error)
+	// Forget drops the token from the validation cache after SwitchTube rejected it.
+	Forget(token string)
+}
+
+// tokenProvider authenticates with an API token in the Authorization header.
+type tokenProvider struct {
+	source TokenSource // Supplies the token
+	token  string      // Token fetched once for the run, empty until the first request
+	mutex  sync.Mutex  // Guards token across parallel requests
+}
+
+// NewTokenProvider returns a Provider authenticating with the API token of source.
+func NewTokenProvider(source TokenSource) Provider {
+	return &tokenProvider{source: source}
+}
+
+// Apply sets the headers of header on req, replacing values of the same name.
+func Apply(req *http.Request, header http.Header) {
+	for name, values := range header {
+		req.Header[name] = values
+	}
+}
+
+// Lines formats header as "Name: value" lines sorted by name, e.g. for external tools.
+func Lines(header http.Header) []string {
+	lines := make([]string, 0, len(header))
+
+	for name, values := range header {
+		for _, value := range values {
+			lines = append(lines, name+": "+value)
+		}
+	}
+
+	slices.Sort(lines)
+
+	return lines
+}
+
+// TokenHeader returns the headers authenticating with token.
+func TokenHeader(token string) http.Header {
+	return http.Header{headerAuthorization: {"Token " + token}}
+}
+
+// Credentials returns the Authorization header with the token, fetched once for the run.
+func (p *tokenProvider) Credentials(ctx context.Context) (http.Header, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.token == "" {
+		token, err := p.source.Get(ctx)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // Wrapped by the caller
+		}
+
+		p.token = token
+	}
+
+	return TokenHeader(p.token), nil
+}
+
+// Reject forgets the token if it is the one sent in header.
+func (p *tokenProvider) Reject(header http.Header) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.token != "" && header.Get(headerAuthorization) == "Token "+p.token {
+		p.source.Forget(p.token)
+		p.token = ""
+	}
+}
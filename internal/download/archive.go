@@ -0,0 +1,233 @@
+package download
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"switchtube-downloader/internal/helper/dir"
+	"switchtube-downloader/internal/models"
+)
+
+// archivePrefix starts the name of the folder the videos are downloaded into before
+// they are moved into the archive.
+const archivePrefix = ".switchtube-archive-"
+
+var (
+	errArchiveExists          = errors.New("archive already exists, use --force to overwrite it")
+	errFailedToWriteArchive   = errors.New("failed to write archive")
+	errFailedToPrepareArchive = errors.New("failed to prepare archive")
+)
+
+// archiveWriter adds files to an archive of one of the supported formats.
+type archiveWriter interface {
+	// add writes the file at path into the archive as name, a slash separated path.
+	add(name string, path string, info fs.FileInfo) error
+	// Close completes the archive.
+	Close() error
+}
+
+// tarArchive writes a tar archive, gzip compressed if gzip is set.
+type tarArchive struct {
+	tar  *tar.Writer
+	gzip *gzip.Writer // nil for an uncompressed archive
+}
+
+// add implements archiveWriter.
+func (a *tarArchive) add(name string, path string, info fs.FileInfo) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err //nolint:wrapcheck // Wrapped by archive.add
+	}
+
+	header.Name = name
+
+	if err := a.tar.WriteHeader(header); err != nil {
+		return err //nolint:wrapcheck // Wrapped by archive.add
+	}
+
+	return copyInto(a.tar, path)
+}
+
+// Close implements archiveWriter.
+func (a *tarArchive) Close() error {
+	err := a.tar.Close()
+
+	if a.gzip != nil {
+		err = errors.Join(err, a.gzip.Close())
+	}
+
+	return err
+}
+
+// zipArchive writes a zip archive. Entries are stored without compression, as videos
+// do not compress any further.
+type zipArchive struct {
+	zip *zip.Writer
+}
+
+// add implements archiveWriter.
+func (a *zipArchive) add(name string, path string, info fs.FileInfo) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err //nolint:wrapcheck // Wrapped by archive.add
+	}
+
+	header.Name = name
+	header.Method = zip.Store
+
+	entry, err := a.zip.CreateHeader(header)
+	if err != nil {
+		return err //nolint:wrapcheck // Wrapped by archive.add
+	}
+
+	return copyInto(entry, path)
+}
+
+// Close implements archiveWriter.
+func (a *zipArchive) Close() error {
+	return a.zip.Close() //nolint:wrapcheck // Wrapped by archive.close
+}
+
+// archive receives the finished videos of a run with --archive-output. Each video is
+// moved into the archive once complete, so only the videos in progress take up room in
+// the download folder, and an interrupted run keeps the videos finished so far.
+type archive struct {
+	target  string        // Path of the complete archive
+	partial string        // Path the archive is written to until complete
+	folder  string        // Folder the videos are downloaded into, next to the archive
+	file    *os.File      // Open partial archive
+	writer  archiveWriter // Writes the entries into file
+	added   int           // Number of files in the archive
+	mu      sync.Mutex    // Serializes writes of concurrent downloads
+}
+
+// openArchive creates the partial archive with the file mode of config, and the folder
+// next to it the videos are downloaded into. Returns an error if the archive exists and
+// the config does not force overwriting it.
+func openArchive(config models.DownloadConfig) (*archive, error) {
+	if _, err := os.Stat(config.ArchiveOutput); err == nil && !config.Force {
+		return nil, fmt.Errorf("%w: %s", errArchiveExists, config.ArchiveOutput)
+	}
+
+	format, err := models.ParseArchiveFormat(config.ArchiveOutput)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errFailedToPrepareArchive, err)
+	}
+
+	folder, err := os.MkdirTemp(filepath.Dir(config.ArchiveOutput), archivePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errFailedToPrepareArchive, err)
+	}
+
+	partial := config.ArchiveOutput + ".part"
+
+	file, err := dir.CreateVideoFile(partial, config)
+	if err != nil {
+		_ = os.RemoveAll(folder)
+
+		return nil, fmt.Errorf("%w: %w", errFailedToPrepareArchive, err)
+	}
+
+	return &archive{
+		target:  absPath(config.ArchiveOutput),
+		partial: partial,
+		folder:  absPath(folder),
+		file:    file,
+		writer:  newArchiveWriter(file, format),
+	}, nil
+}
+
+// add moves files below the archive folder into the archive, with their paths relative
+// to the folder. Each file is deleted once written.
+func (a *archive) add(files ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errFailedToWriteArchive, err)
+		}
+
+		if err := a.writer.add(a.name(path), path, info); err != nil {
+			return fmt.Errorf("%w: %s: %w", errFailedToWriteArchive, filepath.Base(path), err)
+		}
+
+		a.added++
+
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("%w: %w", errFailedToWriteArchive, err)
+		}
+	}
+
+	return nil
+}
+
+// name returns the slash separated path of file inside the archive.
+func (a *archive) name(file string) string {
+	rel, err := filepath.Rel(a.folder, absPath(file))
+	if err != nil {
+		rel = filepath.Base(file)
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// close completes the archive and renames it to its target. An archive without files is
+// discarded unless keep is set, e.g. after a run that failed before anything finished.
+// The folder is left for the caller to delete, as the run may still hold its lock.
+func (a *archive) close(keep bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	err := errors.Join(a.writer.Close(), a.file.Close())
+	if err == nil && (keep || a.added > 0) {
+		if err = os.Rename(a.partial, a.target); err == nil {
+			return nil
+		}
+	}
+
+	_ = os.Remove(a.partial)
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToWriteArchive, err)
+	}
+
+	return nil
+}
+
+// newArchiveWriter returns a writer for an archive of format written to w.
+func newArchiveWriter(w io.Writer, format models.ArchiveFormat) archiveWriter {
+	switch format {
+	case models.ArchiveZip:
+		return &zipArchive{zip: zip.NewWriter(w)}
+	case models.ArchiveTarGz:
+		compressed := gzip.NewWriter(w)
+
+		return &tarArchive{tar: tar.NewWriter(compressed), gzip: compressed}
+	default:
+		return &tarArchive{tar: tar.NewWriter(w)}
+	}
+}
+
+// copyInto copies the file at path into dst.
+func copyInto(dst io.Writer, path string) error {
+	file, err := os.Open(path) //nolint:gosec // File below the archive folder
+	if err != nil {
+		return err //nolint:wrapcheck // Wrapped by archive.add
+	}
+
+	defer func() { _ = file.Close() }()
+
+	_, err = io.Copy(dst, file)
+
+	return err //nolint:wrapcheck // Wrapped by archive.add
+}
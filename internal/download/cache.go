@@ -0,0 +1,129 @@
+package download
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"switchtube-downloader/internal/helper/dir"
+)
+
+const (
+	// apiCacheDirName is the cache subdirectory holding one file per cached API response.
+	apiCacheDirName = "api"
+	// apiCachePermissions are the permissions of written cache files.
+	apiCachePermissions = 0o600
+)
+
+// cachedResponse is an API response stored on disk together with its validators.
+type cachedResponse struct {
+	FetchedAt    time.Time       `json:"fetchedAt"`              // Time the response was received
+	URL          string          `json:"url"`                    // Requested URL
+	ETag         string          `json:"etag,omitempty"`         // ETag header of the response
+	LastModified string          `json:"lastModified,omitempty"` // Last-Modified header of the response
+	Body         json.RawMessage `json:"body"`                   // JSON body of the response
+}
+
+// setValidators adds the conditional headers that let the server answer 304 Not Modified.
+func (cr *cachedResponse) setValidators(req *http.Request) {
+	if cr.ETag != "" {
+		req.Header.Set("If-None-Match", cr.ETag)
+	}
+
+	if cr.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cr.LastModified)
+	}
+}
+
+// apiCachePath returns the path of the cache file for reqURL.
+func apiCachePath(reqURL string) (string, error) {
+	cacheDir, err := dir.CacheDir(apiCacheDirName)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(reqURL))
+
+	return filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// loadCachedResponse returns the cached response for reqURL, or nil if none is stored.
+func loadCachedResponse(reqURL string) *cachedResponse {
+	path, err := apiCachePath(reqURL)
+	if err != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil || cached.URL != reqURL {
+		return nil
+	}
+
+	return &cached
+}
+
+// saveCachedResponse stores the JSON body of resp for later runs.
+func saveCachedResponse(resp *http.Response, body []byte) error {
+	reqURL := resp.Request.URL.String()
+
+	path, err := apiCachePath(reqURL)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cachedResponse{
+		FetchedAt:    time.Now(),
+		URL:          reqURL,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Body:         body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode cached response: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, apiCachePermissions); err != nil {
+		return fmt.Errorf("failed to write cached response: %w", err)
+	}
+
+	return nil
+}
+
+// loadCachedResponses returns every cached API response.
+func loadCachedResponses() ([]cachedResponse, error) {
+	cacheDir, err := dir.CacheDir(apiCacheDirName)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(cacheDir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached responses: %w", err)
+	}
+
+	responses := make([]cachedResponse, 0, len(paths))
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		var cached cachedResponse
+		if err := json.Unmarshal(data, &cached); err == nil {
+			responses = append(responses, cached)
+		}
+	}
+
+	return responses, nil
+}
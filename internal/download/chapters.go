@@ -0,0 +1,77 @@
+package download
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// minChapters is the number of timestamps a description needs to be taken as chapters.
+	minChapters = 2
+	// timestampBase is the number of seconds per minute and minutes per hour in timestamps.
+	timestampBase = 60
+)
+
+// chapterLine matches a description line starting with a timestamp like "1:02:03",
+// optionally in brackets and followed by a separator, and captures the timestamp and title.
+//
+//nolint:gochecknoglobals // Compiled once
+var chapterLine = regexp.MustCompile(`^[\[(]?((?:\d{1,2}:)?\d{1,2}:\d{2})[\])]?\s*[-–—:|.]?\s*(.+)$`)
+
+// chapter is a named section of a video.
+type chapter struct {
+	Start time.Duration // Offset of the chapter from the start of the video
+	Title string        // The chapter title
+}
+
+// parseChapters extracts the chapters listed in a video description, one per line
+// starting with a timestamp. Returns nil unless there are at least two chapters with
+// ascending timestamps, so a single time reference in the text is not mistaken for
+// chapters.
+func parseChapters(description string) []chapter {
+	var chapters []chapter
+
+	for line := range strings.Lines(description) {
+		match := chapterLine.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+
+		start, ok := parseTimestamp(match[1])
+		if !ok {
+			continue
+		}
+
+		if len(chapters) > 0 && start <= chapters[len(chapters)-1].Start {
+			return nil
+		}
+
+		chapters = append(chapters, chapter{Start: start, Title: strings.TrimSpace(match[2])})
+	}
+
+	if len(chapters) < minChapters {
+		return nil
+	}
+
+	return chapters
+}
+
+// parseTimestamp converts a timestamp like "2:05" or "1:02:05" into a duration.
+// Reports false if minutes or seconds are out of range.
+func parseTimestamp(value string) (time.Duration, bool) {
+	var total int
+
+	parts := strings.Split(value, ":")
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || (i > 0 && n >= timestampBase) {
+			return 0, false
+		}
+
+		total = total*timestampBase + n
+	}
+
+	return time.Duration(total) * time.Second, true
+}
@@ -0,0 +1,250 @@
+package download
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"switchtube-downloader/internal/helper/dir"
+	"switchtube-downloader/internal/helper/ui/input"
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/helper/ui/table"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/models"
+)
+
+// mediaExtensions are the extensions of downloaded videos, whose temporary files are
+// leftovers.
+//
+//nolint:gochecknoglobals // Read-only list of extensions
+var mediaExtensions = []string{".mp4", ".m4v", ".webm", ".mov", ".mkv", ".mp3", ".m4a"}
+
+var errFailedToScanFolder = errors.New("failed to scan folder")
+
+// leftover is a file of an interrupted run found by Clean.
+type leftover struct {
+	path      string // Absolute path of the file
+	reason    string // Why the file is considered a leftover
+	channelID string // Channel of the unfinished run the file belongs to, empty if unknown
+	size      int64  // Size of the file in bytes
+}
+
+// Clean lists the leftovers of interrupted runs in folder: partial files of unfinished
+// channel downloads, the files of failed downloads in a staging folder and the temporary
+// files of downloads, see leftoverReason. After
+// confirmation they are deleted, or with resume the unfinished channel downloads they
+// belong to are resumed instead.
+func Clean(folder string, resume bool) error {
+	folder = absPath(cmp.Or(folder, "."))
+
+	unlock, err := dir.LockFolder(folder, false)
+	if err != nil {
+		return err //nolint:wrapcheck // Already wrapped by the dir package
+	}
+
+	states, err := loadResumeStates(nil)
+	if err != nil {
+		unlock()
+
+		return err
+	}
+
+	leftovers, err := findLeftovers(folder, states)
+	if err != nil {
+		unlock()
+
+		return err
+	}
+
+	if len(leftovers) == 0 {
+		unlock()
+		fmt.Fprintln(stream.UI(), i18n.T("No leftover files found in %s", folder))
+
+		return nil
+	}
+
+	rows := make([][]string, 0, len(leftovers))
+	for _, file := range leftovers {
+		rows = append(rows, []string{relativeTo(folder, file.path), table.FormatBytes(file.size), file.reason})
+	}
+
+	table.DisplayList([]string{i18n.T("File"), i18n.T("Size"), i18n.T("Reason")}, rows)
+
+	if resume {
+		// The resumed runs lock their folders themselves
+		unlock()
+
+		return resumeLeftovers(leftovers)
+	}
+
+	defer unlock()
+
+	confirmed, err := input.Confirm("%s", i18n.T("Delete %d files?", len(leftovers)))
+	if err != nil {
+		return err //nolint:wrapcheck // Already wrapped by the input package
+	}
+
+	if !confirmed {
+		fmt.Fprintln(stream.UI(), i18n.T("Operation cancelled"))
+
+		return nil
+	}
+
+	for _, file := range leftovers {
+		if err := os.Remove(file.path); err != nil {
+			stream.Warnf(stream.UI(), "failed to delete %s: %v", file.path, err)
+
+			continue
+		}
+
+		if isStagingFolder(filepath.Dir(file.path)) {
+			removeStaged(file.path)
+		}
+
+		fmt.Fprintln(stream.UI(), i18n.T("Deleted %s", relativeTo(folder, file.path)))
+	}
+
+	return nil
+}
+
+// findLeftovers walks folder and returns the leftover files, sorted by path. Files listed
+// as pending in states belong to an unfinished channel download. Hidden folders are
+// skipped, as downloads never write into them.
+func findLeftovers(folder string, states []resumeState) ([]leftover, error) {
+	pending := make(map[string]string)
+
+	for _, state := range states {
+		for _, video := range state.Pending {
+			if video.File != "" {
+				pending[video.File] = state.ChannelID
+			}
+		}
+	}
+
+	var leftovers []leftover
+
+	err := filepath.WalkDir(folder, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() && path != folder && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		file := leftover{path: path}
+
+		if channelID, ok := pending[path]; ok {
+			file.reason, file.channelID = i18n.T("unfinished download"), channelID
+		} else if isStagingFolder(filepath.Dir(path)) {
+			file.reason = i18n.T("staged download")
+		} else if file.reason, ok = leftoverReason(entry.Name()); !ok {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err //nolint:wrapcheck // Wrapped once for the whole walk
+		}
+
+		file.size = info.Size()
+		leftovers = append(leftovers, file)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errFailedToScanFolder, err)
+	}
+
+	return leftovers, nil
+}
+
+// leftoverReason returns why a file named name is a leftover, judged by the names
+// downloads give their temporary files: "<video>.part" while moving a video into place,
+// "<archive>.part" while writing an archive, "<video>.aria2" of aria2c, the source
+// "<name>.source.<ext>" of a remux and "<name>.new.<ext>" while replacing a video with
+// --backup. Returns false for any other file, e.g. .part files of other programs.
+func leftoverReason(name string) (string, bool) {
+	lower := strings.ToLower(name)
+
+	if target, ok := strings.CutSuffix(lower, ".part"); ok {
+		if isMediaFile(target) {
+			return i18n.T("partial file"), true
+		}
+
+		if _, err := models.ParseArchiveFormat(target); err == nil {
+			return i18n.T("partial archive"), true
+		}
+
+		return "", false
+	}
+
+	if target, ok := strings.CutSuffix(lower, ".aria2"); ok {
+		return i18n.T("aria2c control file"), isMediaFile(target)
+	}
+
+	if !isMediaFile(lower) {
+		return "", false
+	}
+
+	switch stem := strings.TrimSuffix(lower, filepath.Ext(lower)); {
+	case strings.HasSuffix(stem, ".source"):
+		return i18n.T("remux source"), true
+	case strings.HasSuffix(stem, ".new"):
+		return i18n.T("unfinished replacement"), true
+	default:
+		return "", false
+	}
+}
+
+// isMediaFile reports whether name has the extension of a downloaded video.
+func isMediaFile(name string) bool {
+	return slices.Contains(mediaExtensions, strings.ToLower(filepath.Ext(name)))
+}
+
+// resumeLeftovers resumes the unfinished channel downloads the leftovers belong to after
+// confirmation. Leftovers without a channel download are kept.
+func resumeLeftovers(leftovers []leftover) error {
+	var channels []string
+
+	kept := 0
+
+	for _, file := range leftovers {
+		switch {
+		case file.channelID == "":
+			kept++
+		case !slices.Contains(channels, file.channelID):
+			channels = append(channels, file.channelID)
+		}
+	}
+
+	if kept > 0 {
+		fmt.Fprintln(stream.UI(), i18n.T("%d files do not belong to an unfinished channel download and are kept", kept))
+	}
+
+	if len(channels) == 0 {
+		return nil
+	}
+
+	confirmed, err := input.Confirm("%s", i18n.T("Resume the unfinished downloads of %d channels?", len(channels)))
+	if err != nil {
+		return err //nolint:wrapcheck // Already wrapped by the input package
+	}
+
+	if !confirmed {
+		fmt.Fprintln(stream.UI(), i18n.T("Operation cancelled"))
+
+		return nil
+	}
+
+	return Resume(channels)
+}
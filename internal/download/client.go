@@ -2,107 +2,349 @@
 package download
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"sync"
+	"time"
 
+	"switchtube-downloader/internal/auth"
+	"switchtube-downloader/internal/helper/ui/progress"
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/settings"
 	"switchtube-downloader/internal/token"
+	"switchtube-downloader/internal/tracing"
 )
 
+// Retry behavior for throttled (429) or unavailable (5xx) responses.
+const (
+	maxRetries        = 5               // Retries per request before the response is returned as is
+	defaultRetryAfter = 5 * time.Second // Wait for 429 responses without Retry-After header
+	maxRetryAfter     = 5 * time.Minute // Upper bound for the wait requested by the server
+)
+
+// Connection settings of the shared transport. Channel preparation issues many small
+// API requests to the same host, so idle connections are kept for reuse.
+const (
+	dialTimeout           = 10 * time.Second
+	keepAlive             = 30 * time.Second
+	maxIdleConns          = 100
+	maxIdleConnsPerHost   = 32
+	idleConnTimeout       = 90 * time.Second
+	tlsHandshakeTimeout   = 10 * time.Second
+	responseHeaderTimeout = 30 * time.Second
+	expectContinueTimeout = 1 * time.Second
+)
+
+// DefaultAPITimeout bounds a metadata request including reading its response.
+const DefaultAPITimeout = 30 * time.Second
+
+// apiTimeout bounds every metadata request, 0 for no limit. Set by SetTimeouts.
+//
+//nolint:gochecknoglobals // Configured once at startup by the global flags
+var apiTimeout = DefaultAPITimeout
+
+// sharedTransport is reused by every client of the process, so connections
+// survive across the videos and channels of a run.
+//
+//nolint:gochecknoglobals // Connection pool shared across clients
+var sharedTransport = sync.OnceValue(newTransport)
+
 var (
 	errFailedToCreateRequest  = errors.New("failed to create request")
 	errFailedToDecodeResponse = errors.New("failed to decode response")
 	errFailedToGetToken       = errors.New("failed to get token")
 	errFailedToParseBaseURL   = errors.New("failed to parse base URL")
+	errNegativeTimeout        = errors.New("timeout must not be negative")
+	errNotCached              = errors.New("not available offline")
 	errUnexpectedHost         = errors.New("request URL host does not match expected base URL")
 )
 
 // client handles all API interactions.
 type client struct {
-	tokenManager *token.Manager // Manages authentication tokens for API requests
-	client       *http.Client   // HTTP client used for making requests
-	baseHost     string         // Expected host for SSRF validation
+	out      io.Writer     // Destination of retry and response handling messages, never stdout
+	auth     auth.Provider // Authenticates API requests
+	client   *http.Client  // HTTP client for video transfers, which may take hours
+	api      *http.Client  // HTTP client for metadata requests, bounded by apiTimeout
+	baseHost string        // Expected host for SSRF validation
+	offline  bool          // Serve API requests from the cache only
 }
 
-// newClient creates a new instance of Client.
+// newClient creates a new instance of Client authenticating with the API token of tm.
 func newClient(tm *token.Manager) (*client, error) {
-	parsedBase, err := url.Parse(baseURL)
+	parsedBase, err := url.Parse(settings.BaseURL())
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", errFailedToParseBaseURL, err)
 	}
 
 	return &client{
-		tokenManager: tm,
-		baseHost:     parsedBase.Host,
+		out:      progress.ErrWriter(),
+		auth:     auth.NewTokenProvider(tm),
+		baseHost: parsedBase.Host,
 		client: &http.Client{
 			Timeout:       0,
-			Transport:     http.DefaultTransport,
+			Transport:     tracing.Transport(sharedTransport()),
+			CheckRedirect: nil,
+			Jar:           nil,
+		},
+		api: &http.Client{
+			Timeout:       apiTimeout,
+			Transport:     tracing.Transport(sharedTransport()),
 			CheckRedirect: nil,
 			Jar:           nil,
 		},
 	}, nil
 }
 
+// SetTimeouts sets the limit of metadata requests and the time a video transfer may
+// receive no data before it is reconnected. 0 disables the respective limit.
+func SetTimeouts(api time.Duration, stall time.Duration) error {
+	if api < 0 || stall < 0 {
+		return errNegativeTimeout
+	}
+
+	apiTimeout, stallTimeout = api, stall
+
+	return nil
+}
+
+// credentials returns the headers authenticating API requests, as AuthError if no
+// credentials are available.
+func (c *client) credentials(ctx context.Context) (http.Header, error) {
+	header, err := c.auth.Credentials(ctx)
+	if err != nil {
+		return nil, &AuthError{Err: fmt.Errorf("%w: %w", errFailedToGetToken, err)}
+	}
+
+	return header, nil
+}
+
 // makeJSONRequest makes an authenticated HTTP request and decodes JSON response into target.
+// Responses are cached on disk: a cached response is revalidated with its ETag or
+// Last-Modified date, and used as is if the client is offline, SwitchTube cannot be
+// reached or fails with a server error. Authentication failures are never covered up.
 // Returns error if request fails or JSON decoding fails.
 func (c *client) makeJSONRequest(ctx context.Context, reqURL string, target any) error {
-	resp, err := c.makeRequest(ctx, reqURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, http.NoBody)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %w", errFailedToCreateRequest, err)
+	}
+
+	cached := loadCachedResponse(reqURL)
+	if c.offline {
+		if cached == nil {
+			return fmt.Errorf("%w: %s", errNotCached, req.URL.Path)
+		}
+
+		return c.decodeAPIResponse(cached.Body, target)
+	}
+
+	if cached != nil {
+		cached.setValidators(req)
+	}
+
+	resp, err := c.send(c.api, req)
+	if err != nil {
+		if cached == nil || ctx.Err() != nil || !isNetworkError(err) {
+			return err
+		}
+
+		return c.useCached(cached, target, err)
 	}
 
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			fmt.Printf("Warning: failed to close response body: %v\n", err)
+			stream.Warnf(c.out, "failed to close response body: %v", err)
 		}
 	}()
 
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return c.decodeAPIResponse(cached.Body, target)
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError && cached != nil {
+		return c.useCached(cached, target, statusError(resp.Request.URL, resp.StatusCode))
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%w: status %d: %s",
-			errHTTPNotOK,
-			resp.StatusCode,
-			http.StatusText(resp.StatusCode))
+		return statusError(resp.Request.URL, resp.StatusCode)
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
 		return fmt.Errorf("%w: %w", errFailedToDecodeResponse, err)
 	}
 
+	if err := c.decodeAPIResponse(body, target); err != nil {
+		return err
+	}
+
+	if err := saveCachedResponse(resp, body); err != nil {
+		stream.Warnf(c.out, "failed to cache response: %v", err)
+	}
+
 	return nil
 }
 
-// makeRequest makes an authenticated HTTP GET to the given URL.
-func (c *client) makeRequest(ctx context.Context, reqURL string) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, http.NoBody)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %w", errFailedToCreateRequest, err)
+// useCached decodes the cached response into target in place of a request that failed
+// with err, warning that the data may be outdated.
+func (c *client) useCached(cached *cachedResponse, target any, err error) error {
+	stream.Warnf(c.out, "%s", i18n.T("using cached data from %s: %v", cached.FetchedAt.Format(time.DateTime), err))
+
+	return c.decodeAPIResponse(cached.Body, target)
+}
+
+// isNetworkError reports whether err means SwitchTube could not be reached, e.g. while
+// offline. Missing or rejected credentials are not, even if fetching them failed on
+// the network, as only the user can fix them.
+func isNetworkError(err error) bool {
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return false
 	}
 
-	return c.makeRequestWithReq(req)
+	var netErr net.Error
+
+	return errors.As(err, &netErr)
 }
 
-// makeRequestWithReq executes req after attaching the auth token header.
+// makeRequestWithReq executes req after attaching the auth token header, without limit
+// on the time to read the response, e.g. for video transfers.
 // Allows callers to supply a request with a custom context (e.g. for cancellation).
 func (c *client) makeRequestWithReq(req *http.Request) (*http.Response, error) {
+	return c.send(c.client, req)
+}
+
+// send executes req with httpClient after attaching the configured headers and the
+// credentials.
+// Throttled or unavailable responses are retried after the wait requested by the server.
+func (c *client) send(httpClient *http.Client, req *http.Request) (*http.Response, error) {
 	// Validate request URL host to prevent SSRF
 	if req.URL.Host != c.baseHost {
 		return nil, fmt.Errorf("%w: got %q, want %q", errUnexpectedHost, req.URL.Host, c.baseHost)
 	}
 
-	apiToken, err := c.tokenManager.Get(req.Context())
+	credentials, err := c.credentials(req.Context())
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", errFailedToGetToken, err)
+		return nil, err
 	}
 
-	req.Header.Set(headerAuthorization, "Token "+apiToken)
+	settings.ApplyHeaders(req)
+	auth.Apply(req, credentials)
 
-	resp, err := c.client.Do(req) //nolint:gosec // URL host validated above against constant baseHost
-	if err != nil {
-		return nil, fmt.Errorf("%w: %w", errFailedToCreateRequest, err)
+	refetched := false
+
+	for attempt := 0; ; attempt++ {
+		resp, err := httpClient.Do(req) //nolint:gosec // URL host validated above against constant baseHost
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errFailedToCreateRequest, err)
+		}
+
+		// The credentials may have been revoked or replaced since they were fetched, fetch them once more
+		if resp.StatusCode == http.StatusUnauthorized && !refetched && (req.Body == nil || req.Body == http.NoBody) {
+			refetched = true
+
+			if err := resp.Body.Close(); err != nil {
+				stream.Warnf(c.out, "failed to close response body: %v", err)
+			}
+
+			c.auth.Reject(credentials)
+
+			if credentials, err = c.credentials(req.Context()); err != nil {
+				return nil, err
+			}
+
+			auth.Apply(req, credentials)
+
+			continue
+		}
+
+		wait, retry := retryAfter(resp)
+		if !retry || attempt == maxRetries {
+			return resp, nil
+		}
+
+		if err := resp.Body.Close(); err != nil {
+			stream.Warnf(c.out, "failed to close response body: %v", err)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			stream.Warnf(c.out, "%s", i18n.T("Throttled by SwitchTube (status %d), waiting %s", resp.StatusCode, wait))
+		} else {
+			stream.Warnf(c.out, "%s", i18n.T("SwitchTube is unavailable (status %d), retrying in %s", resp.StatusCode, wait))
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, fmt.Errorf("%w: %w", errFailedToCreateRequest, req.Context().Err())
+		case <-time.After(wait):
+		}
+	}
+}
+
+// decodeJSON decodes the JSON body into target. With StrictAPI, fields target has no
+// place for are an error.
+func decodeJSON(body []byte, target any) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if strictAPI {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err := decoder.Decode(target); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToDecodeResponse, err)
+	}
+
+	return nil
+}
+
+// newTransport creates an HTTP transport tuned for many small requests to one host,
+// with HTTP/2 enabled and timeouts for connecting and waiting on response headers.
+func newTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: keepAlive,
+	}
+
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		IdleConnTimeout:       idleConnTimeout,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+		ExpectContinueTimeout: expectContinueTimeout,
+	}
+}
+
+// retryAfter reports whether resp should be retried and how long to wait before.
+// 429 responses are always retried, 5xx responses only if they carry a Retry-After
+// header, which may hold either seconds or an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < http.StatusInternalServerError {
+		return 0, false
+	}
+
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return defaultRetryAfter, resp.StatusCode == http.StatusTooManyRequests
+	}
+
+	wait := defaultRetryAfter
+	if seconds, err := strconv.Atoi(header); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(header); err == nil {
+		wait = time.Until(date)
 	}
 
-	return resp, nil
+	return min(max(wait, 0), maxRetryAfter), true
 }
@@ -0,0 +1,136 @@
+package download
+
+import (
+	"slices"
+	"sync"
+
+	"switchtube-downloader/internal/metrics"
+	"switchtube-downloader/internal/models"
+)
+
+// ResultCollector gathers the outcome of the videos of a batch across parallel downloads:
+// the transfer statistics of downloaded videos, the failed videos with the reason they
+// failed, and the downloaded videos whose upload failed. Every outcome is also counted
+// in the process metrics. It is safe for concurrent use.
+type ResultCollector struct {
+	stats         map[string]models.DownloadStat // Transfer statistics by video ID
+	reasons       map[string]string              // Reason of the failure by video ID
+	failed        []models.Video                 // Failed videos in the order they failed
+	uploadErrors  map[string]string              // Reason of the failed upload by video ID
+	failedUploads []models.Video                 // Downloaded videos whose upload failed, in that order
+	mutex         sync.Mutex                     // Guards all fields across parallel downloads
+}
+
+// NewResultCollector creates an empty ResultCollector.
+func NewResultCollector() *ResultCollector {
+	return &ResultCollector{
+		stats:        make(map[string]models.DownloadStat),
+		reasons:      make(map[string]string),
+		uploadErrors: make(map[string]string),
+	}
+}
+
+// Fail records that the video failed because of err. Only the first failure of a video
+// is kept.
+func (c *ResultCollector) Fail(video models.Video, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.reasons[video.ID]; ok {
+		return
+	}
+
+	reason := ""
+	if err != nil {
+		reason = err.Error()
+	}
+
+	c.reasons[video.ID] = reason
+	c.failed = append(c.failed, video)
+
+	metrics.DownloadFailed()
+}
+
+// FailUpload records that the downloaded video could not be uploaded because of err.
+// The download itself still counts as successful.
+func (c *ResultCollector) FailUpload(video models.Video, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.uploadErrors[video.ID] = err.Error()
+	c.failedUploads = append(c.failedUploads, video)
+}
+
+// FailedUploads returns the downloaded videos whose upload failed, in the order they failed.
+func (c *ResultCollector) FailedUploads() []models.Video {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return slices.Clone(c.failedUploads)
+}
+
+// UploadError returns why the upload of the video failed, empty if it did not fail.
+func (c *ResultCollector) UploadError(videoID string) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.uploadErrors[videoID]
+}
+
+// Failed returns the failed videos in the order they failed.
+func (c *ResultCollector) Failed() []models.Video {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return slices.Clone(c.failed)
+}
+
+// HasFailed reports whether the video failed.
+func (c *ResultCollector) HasFailed(videoID string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, ok := c.reasons[videoID]
+
+	return ok
+}
+
+// Reason returns why the video failed, empty if it did not fail.
+func (c *ResultCollector) Reason(videoID string) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.reasons[videoID]
+}
+
+// Reset forgets all recorded outcomes, so the collector can be reused for the next batch.
+func (c *ResultCollector) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	clear(c.stats)
+	clear(c.reasons)
+	clear(c.uploadErrors)
+	c.failed = nil
+	c.failedUploads = nil
+}
+
+// Stat returns the transfer statistics of the downloaded video.
+func (c *ResultCollector) Stat(videoID string) (models.DownloadStat, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stat, ok := c.stats[videoID]
+
+	return stat, ok
+}
+
+// Succeed records the transfer statistics of the downloaded video.
+func (c *ResultCollector) Succeed(videoID string, stat models.DownloadStat) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.stats[videoID] = stat
+
+	metrics.DownloadSucceeded(stat.Bytes, stat.Elapsed)
+}
@@ -0,0 +1,56 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"switchtube-downloader/internal/helper/dir"
+)
+
+const (
+	// contactSheetColumns and contactSheetRows are the size of the thumbnail grid.
+	contactSheetColumns = 4
+	contactSheetRows    = 4
+	// contactSheetWidth is the width of a single thumbnail in pixels.
+	contactSheetWidth = 320
+	// contactSheetExtension is appended to the video name for the contact sheet.
+	contactSheetExtension = ".contact.jpg"
+	// contactSheetInterval is the time between thumbnails of videos with unknown length.
+	contactSheetInterval = time.Minute
+)
+
+var errFailedToCreateContactSheet = errors.New("failed to create contact sheet")
+
+// writeContactSheet renders a grid of thumbnails taken at even intervals across file,
+// the job's file or its staged copy, into an image next to the job's file, read left to
+// right and top to bottom.
+func (d *downloader) writeContactSheet(ctx context.Context, job downloadJob, file string) error {
+	if _, err := exec.LookPath(ffmpeg); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToCreateContactSheet, errFFmpegNotFound)
+	}
+
+	interval := contactSheetInterval
+	if length := job.video.Length(); length > 0 {
+		interval = length / (contactSheetColumns * contactSheetRows)
+	}
+
+	filename := strings.TrimSuffix(job.filename, filepath.Ext(job.filename)) + contactSheetExtension
+
+	// Take every thumbnail from the middle of its interval to avoid black intro frames
+	filter := fmt.Sprintf("fps=1000/%d,scale=%d:-2,tile=%dx%d",
+		max(interval.Milliseconds(), 1), contactSheetWidth, contactSheetColumns, contactSheetRows)
+
+	offset := strconv.FormatFloat((interval / 2).Seconds(), 'f', 3, 64)
+
+	if err := runFFmpeg(ctx, "-ss", offset, "-i", file, "-vf", filter, "-frames:v", "1", filename); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToCreateContactSheet, err)
+	}
+
+	return dir.ApplyFileMode(filename, d.config.FileMode) //nolint:wrapcheck // Already wrapped by the dir package
+}
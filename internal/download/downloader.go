@@ -1,36 +1,44 @@
 package download
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
-	"net/http"
+	"io"
 	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"switchtube-downloader/internal/helper/dir"
 	"switchtube-downloader/internal/helper/ui/input"
 	"switchtube-downloader/internal/helper/ui/progress"
+	"switchtube-downloader/internal/helper/ui/stream"
 	"switchtube-downloader/internal/helper/ui/styles"
+	"switchtube-downloader/internal/helper/ui/table"
+	"switchtube-downloader/internal/history"
+	"switchtube-downloader/internal/i18n"
 	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/notify"
+	"switchtube-downloader/internal/settings"
 	"switchtube-downloader/internal/token"
 
 	"github.com/charmbracelet/x/ansi"
 )
 
-// Base URL and API endpoints for SwitchTube.
+// API endpoints relative to the SwitchTube base URL.
 const (
-	baseURL             = "https://tube.switch.ch/"
-	videoAPI            = "api/v1/browse/videos/"
-	channelAPI          = "api/v1/browse/channels/"
-	videoPrefix         = "videos/"
-	channelPrefix       = "channels/"
-	headerAuthorization = "Authorization"
+	videoAPI      = "api/v1/browse/videos/"
+	channelAPI    = "api/v1/browse/channels/"
+	videoPrefix   = "videos/"
+	channelPrefix = "channels/"
 )
 
 type mediaType int
@@ -59,40 +67,138 @@ var (
 	errFailedToGetVideoInfo        = errors.New("failed to get video information")
 	errFailedToGetVideoVariants    = errors.New("failed to get video variants")
 	errFailedToSelectVideos        = errors.New("failed to select videos")
+	errFilenameCollision           = errors.New("filename already used by another video")
+	errFilenameOverwritten         = errors.New("file overwritten by a later video with the same filename")
 	errHTTPNotOK                   = errors.New("HTTP request failed with non-OK status")
 	errInvalidID                   = errors.New("invalid id")
 	errInvalidURL                  = errors.New("invalid url")
+	errIncompleteDownload          = errors.New("downloaded size does not match Content-Length")
 	errNoVariantsFound             = errors.New("no video variants found")
 )
 
-// videoVariant represents a video download variant.
-type videoVariant struct {
-	Path      string `json:"path"`       // Relative path to the video file on the server
-	MediaType string `json:"media_type"` //nolint:tagliatelle // API returns snake_case
+// downloadJob describes a prepared video download with its resolved target file.
+type downloadJob struct {
+	video    models.Video   // Video metadata
+	variant  models.Variant // Variant to download
+	filename string         // Target path on disk
 }
 
-// channelMetadata represents channel metadata.
-type channelMetadata struct {
-	Name string `json:"name"` // Display name of the channel
-}
+// ProgressFunc receives the bytes written so far and the expected total (-1 if unknown) of a video.
+type ProgressFunc func(videoID string, written int64, total int64)
 
 // downloader handles downloading of both videos and channels.
 type downloader struct {
-	client *client
-	config models.DownloadConfig
+	out            io.Writer                // Destination of status messages
+	client         *client                  // API client
+	plannedFiles   map[string]string        // Video ID to target file, e.g. of a resumed run
+	resolved       []downloadJob            // Every prepared video, including already existing files
+	onProgress     ProgressFunc             // Replaces the progress bars if set
+	queue          *jobQueue                // Pending downloads of the running batch
+	pause          *pauseGate               // Pauses all transfers of the running batch
+	collector      *ResultCollector         // Outcome of the videos of the batch
+	downloaded     map[string]history.Entry // Latest history entry by video ID, loaded on first use
+	channelName    string                   // Name of the channel being downloaded, empty for single videos
+	results        []videoResult            // Outcome of every selected video, printed with --json
+	archive        *archive                 // Archive finished videos are moved into, nil without --archive-output
+	resume         *resumeTracker           // Resume state of the running channel run, nil outside channel runs
+	heads          sync.Map                 // headResult by video URL, so every file is asked for once
+	config         models.DownloadConfig
+	appendManifest bool // Update an existing manifest instead of replacing it
 }
 
 // newDownloader creates a new Downloader instance.
 func newDownloader(config models.DownloadConfig, client *client) *downloader {
-	return &downloader{
-		config: config,
-		client: client,
+	d := &downloader{
+		out:       progress.Writer(),
+		config:    config,
+		client:    client,
+		collector: NewResultCollector(),
+		queue:     &jobQueue{},
+		pause:     newPauseGate(),
+	}
+
+	if config.Quiet {
+		d.onProgress = func(string, int64, int64) {} // No progress bars
+	}
+
+	return d
+}
+
+// download downloads the video or channel with the given ID. If the type is unknown,
+// it is tried as video first and as channel second, if SwitchTube knows no such video.
+// A video that failed to download was recorded as failure already and is not retried
+// as channel.
+func (d *downloader) download(ctx context.Context, id string, downloadType mediaType) error {
+	switch downloadType {
+	case videoType, unknownType:
+		err := d.downloadVideo(ctx, id)
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil || errors.Is(err, input.ErrUserAbort) {
+			return input.ErrUserAbort
+		}
+
+		var notFound *NotFoundError
+		if downloadType == videoType || !errors.Is(err, errFailedToGetVideoInfo) || !errors.As(err, &notFound) {
+			return fmt.Errorf("%w: %w", errFailedToDownloadVideo, err)
+		}
+
+		fallthrough // Fallthrough if type is unknown and try as channel
+	case channelType:
+		if err := d.downloadChannel(ctx, id); err != nil {
+			if ctx.Err() != nil || errors.Is(err, input.ErrUserAbort) {
+				return input.ErrUserAbort
+			}
+
+			if downloadType == unknownType {
+				return fmt.Errorf("%w: %w", errInvalidID, err)
+			}
+
+			return fmt.Errorf("%w: %w", errFailedToDownloadChannel, err)
+		}
+	}
+
+	return nil
+}
+
+// chooseOverwrites asks which existing files of conflicts are overwritten, all in one list.
+// Returns the jobs whose files are kept.
+func (d *downloader) chooseOverwrites(conflicts []downloadJob) ([]downloadJob, error) {
+	videos := make([]models.Video, len(conflicts))
+	filenames := make([]string, len(conflicts))
+
+	for i, job := range conflicts {
+		videos[i] = job.video
+		filenames[i] = filepath.Base(job.filename)
+	}
+
+	indices, err := input.SelectOverwrites(videos, filenames)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Reported per video by the caller
+	}
+
+	kept := make([]downloadJob, 0, len(conflicts))
+
+	for i, job := range conflicts {
+		if !slices.Contains(indices, i) {
+			kept = append(kept, job)
+		}
 	}
+
+	return kept, nil
 }
 
 // downloadChannel downloads selected videos from a channel.
 // Fetches channel info, displays video list, prompts for selection, and downloads chosen videos.
 func (d *downloader) downloadChannel(ctx context.Context, channelID string) error {
+	if d.config.OutputDir == models.StdoutOutput {
+		return errStdoutRequiresVideo
+	}
+
+	runAt := time.Now()
+
 	channelInfo, err := d.getChannelMetadata(ctx, channelID)
 	if err != nil {
 		return fmt.Errorf("%w: %w", errFailedToGetChannelInfo, err)
@@ -104,56 +210,238 @@ func (d *downloader) downloadChannel(ctx context.Context, channelID string) erro
 	}
 
 	if len(videos) == 0 {
-		fmt.Println("No videos found in this channel")
+		d.infof("%s\n", i18n.T("No videos found in this channel"))
 
 		return nil
 	}
 
-	fmt.Printf("Found %d videos in channel: %s\n", len(videos), channelInfo.Name)
+	d.infof("%s\n", i18n.T("Found %d videos in channel: %s", len(videos), channelInfo.Name))
+
+	if d.config.DryRun {
+		return d.useChannelFolder(channelID, channelInfo.Name, videos)
+	}
+
+	// Removed videos are detected against the whole channel, not just the filtered videos
+	channelVideos := videos
+
+	videos, err = d.filterVideos(videos)
+	if err != nil {
+		return err
+	}
+
+	if len(videos) == 0 {
+		d.infof("%s\n", i18n.T("No videos match the filters"))
+
+		return nil
+	}
+
+	sortKey := cmp.Or(d.config.Sort, models.SortChannel)
+	slices.SortStableFunc(videos, sortKey.Compare)
+
+	// Show the details the user chooses by: in the selection, or as table if it cannot be shown
+	showTable := !d.config.Quiet && !stream.IsTerminal()
+	if showTable || !d.config.All && d.config.Selection == nil && !input.PromptsDisabled() {
+		d.fillSizes(ctx, videos)
+	}
+
+	if showTable {
+		table.DisplayVideos(videos)
+	}
 
-	selectedIndices, err := input.SelectVideos(videos, d.config.All, d.config.UseEpisode)
+	selectedIndices, err := d.selectVideos(videos, sortKey)
 	if err != nil {
 		return fmt.Errorf("%w: %w", errFailedToSelectVideos, err)
 	}
 
 	if len(selectedIndices) == 0 {
-		fmt.Println("No videos selected for download")
+		d.infof("%s\n", i18n.T("No videos selected for download"))
 
 		return nil
 	}
 
-	folderName, err := dir.CreateChannelFolder(channelInfo.Name, d.config)
+	if err := d.resolveQuality(); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToSelectVideos, err)
+	}
+
+	if err := d.useChannelFolder(channelID, channelInfo.Name, channelVideos); err != nil {
+		return err
+	}
+
+	d.infof("\r\n%s\n\n", i18n.T("Downloading to folder: %s", cmp.Or(d.config.OutputDir, ".")))
+	d.trackResume(channelID, channelInfo.Name)
+	jobs, err := d.downloadSelectedVideos(ctx, videos, selectedIndices)
 	if err != nil {
-		return fmt.Errorf("%w: %w", errFailedToCreateChannelFolder, err)
+		return err
 	}
 
-	d.config.OutputDir = folderName
-	fmt.Printf("\r\nDownloading to folder: %s\n\n", folderName)
-	d.downloadSelectedVideos(ctx, videos, selectedIndices)
+	if d.config.Sync && d.config.DeleteRemoved && ctx.Err() == nil {
+		d.removeDeleted(channelID, channelVideos)
+	}
+
+	d.finishChannelRun(ctx, *channelInfo, runAt, videos, selectedIndices, jobs)
 
 	return nil
 }
 
+// selectVideos returns the indices of the listed videos to download: those of the
+// configured selection, or those chosen in the interactive selection.
+func (d *downloader) selectVideos(videos []models.Video, sortKey models.VideoSort) ([]int, error) {
+	if d.config.Selection != nil && !d.config.All {
+		return d.config.Selection.Indices(videos) //nolint:wrapcheck // Wrapped by the caller
+	}
+
+	return input.SelectVideos(videos, d.config.All, d.config.UseEpisode, sortKey, d.previewVideo) //nolint:wrapcheck // Wrapped by the caller
+}
+
 // downloadSelectedVideos downloads the videos at the given indices and prints a summary.
-func (d *downloader) downloadSelectedVideos(ctx context.Context, videos []models.Video, selectedIndices []int) {
-	var failed []string
+// Failed videos are recorded in the collector. Returns the jobs that were started, or
+// input.ErrUserAbort if the user quit while preparing the downloads.
+func (d *downloader) downloadSelectedVideos(ctx context.Context, videos []models.Video, selectedIndices []int) ([]downloadJob, error) {
+	d.collector.Reset()
 
-	videosToDownload, longestVideoName := d.prepareDownloads(ctx, videos, selectedIndices, &failed)
-	if len(videosToDownload) > 0 {
-		failed = append(failed, d.processDownloads(ctx, videos, videosToDownload, longestVideoName)...)
+	jobs, err := d.prepareDownloads(ctx, videos, selectedIndices)
+	if err != nil {
+		return nil, err
 	}
 
-	d.printResults(ctx, len(selectedIndices), failed)
+	if len(jobs) > 0 {
+		if err := d.waitForSchedule(ctx); err != nil {
+			return nil, err
+		}
+
+		d.resume.start(jobs, d.collector.Failed())
+		d.processDownloads(ctx, jobs)
+	}
+
+	d.addResults(videos, selectedIndices, jobs)
+	d.printResults(ctx, len(selectedIndices))
+	d.printStats(ctx, jobs)
+
+	return jobs, nil
+}
+
+// downloadToFile downloads the job's video to disk, remuxes it, adds its metadata and
+// renders its contact sheet if configured and applies the publish date to the file.
+// With a staging folder the video is processed there and moved into place once
+// complete; the staged file of a failed download is kept for inspection and the clean
+// command. With Backup an existing file stays in place while the new version is
+// downloaded next to it, and is renamed to a numbered backup once the new version is
+// complete. maxFilenameWidth aligns the progress bars of a multi-file download.
+func (d *downloader) downloadToFile(ctx context.Context, job downloadJob, maxFilenameWidth int) error {
+	staged := job
+
+	if d.config.StagingDir != "" {
+		staged.filename = d.stagingFile(job)
+
+		// Side files like the contact sheet are written next to the target right away
+		if err := dir.CreateParentDir(job.filename, d.config); err != nil {
+			return fmt.Errorf("%w: %w", errFailedToCreateVideoFile, err)
+		}
+	} else if _, err := os.Lstat(job.filename); err == nil && d.config.Backup {
+		staged.filename = replacementFile(job.filename)
+
+		defer func() { _ = os.Remove(staged.filename) }()
+	}
+
+	source, err := d.remuxSource(staged)
+	if err != nil {
+		return err
+	}
+
+	transfer := staged
+	if source != "" {
+		transfer.filename = source
+	}
+
+	start := time.Now()
+
+	if err := d.writeVideoFile(ctx, transfer, maxFilenameWidth); err != nil {
+		return err
+	}
+
+	elapsed := time.Since(start)
+
+	if source != "" {
+		if err := d.remux(ctx, staged, source); err != nil {
+			return err
+		}
+	}
+
+	if d.config.Chapters != models.ChaptersNone || d.config.EmbedMetadata {
+		if err := d.addMetadata(ctx, job, staged.filename); err != nil {
+			stream.Warnf(d.out, "failed to add metadata to %s: %v", job.filename, err)
+		}
+	}
+
+	if d.config.ContactSheet {
+		if err := d.writeContactSheet(ctx, job, staged.filename); err != nil {
+			stream.Warnf(d.out, "%v", err)
+		}
+	}
+
+	// A failed download leaves the previous version untouched
+	if d.config.Backup && staged.filename != job.filename {
+		backup, err := dir.BackupFile(job.filename)
+		if err != nil {
+			return err //nolint:wrapcheck // Already wrapped by the dir package
+		}
+
+		if backup != "" {
+			d.infof("%s\n", i18n.T("Kept the previous version of %s as %s", filepath.Base(job.filename), filepath.Base(backup)))
+		}
+	}
+
+	if staged.filename != job.filename {
+		if err := d.moveIntoPlace(staged.filename, job.filename); err != nil {
+			return err
+		}
+
+		if d.config.StagingDir != "" {
+			removeStaged(staged.filename)
+		}
+	}
+
+	if !d.config.NoMtime && !job.video.PublishedAt.IsZero() {
+		if err := dir.ApplyPublishDate(job.filename, job.video.PublishedAt); err != nil {
+			stream.Warnf(d.out, "failed to apply publish date to %s: %v", job.filename, err)
+		}
+	}
+
+	// Measured before uploading, which may remove the file
+	stat := newStat(job, start, elapsed)
+
+	// A failed upload keeps the download, so it is reported apart from failed downloads
+	if d.config.RcloneRemote != "" {
+		if err := d.upload(ctx, job); err != nil {
+			fmt.Fprintf(d.out, "\n%s\n", i18n.T("Failed to upload %s: %v", job.video.Title, err))
+			d.collector.FailUpload(job.video, err)
+		}
+	}
+
+	if d.archive != nil {
+		if err := d.archive.add(jobFiles(job)...); err != nil {
+			return err
+		}
+	}
+
+	d.collector.Succeed(job.video.ID, stat)
+
+	return nil
 }
 
 // downloadVideo downloads a single video by ID. Returns error if download fails.
-// rowIndex and maxFilenameWidth are used for multi-file progress display alignment.
-func (d *downloader) downloadVideo(ctx context.Context, videoID string, checkExists bool, rowIndex int, maxFilenameWidth int) error {
+func (d *downloader) downloadVideo(ctx context.Context, videoID string) error {
 	video, err := d.getVideoMetadata(ctx, videoID)
 	if err != nil {
 		return fmt.Errorf("%w: %w", errFailedToGetVideoInfo, err)
 	}
 
+	if d.config.DryRun {
+		d.reportFolderPlan(dir.PlanFolder(cmp.Or(d.config.OutputDir, ".")))
+
+		return nil
+	}
+
 	variants, err := d.getVideoVariants(ctx, videoID)
 	if err != nil {
 		return fmt.Errorf("%w: %w", errFailedToGetVideoVariants, err)
@@ -163,66 +451,119 @@ func (d *downloader) downloadVideo(ctx context.Context, videoID string, checkExi
 		return errNoVariantsFound
 	}
 
-	filename := dir.CreateFilename(video.Title, variants[0].MediaType, video.Episode, d.config)
-	if checkExists && !dir.OverwriteVideoIfExists(filename, d.config) {
-		return nil // Skip download
+	// With a single video the variant is asked for right away
+	if d.config.Quality == models.QualityAsk {
+		d.config.Quality = models.QualityPerVideo
 	}
 
-	file, err := dir.CreateVideoFile(filename)
+	variant, err := d.chooseVariant(ctx, *video, variants)
 	if err != nil {
-		return fmt.Errorf("%w: %w", errFailedToCreateVideoFile, err)
+		return err
 	}
 
-	defer func() {
-		if err := file.Close(); err != nil {
-			fmt.Printf("Warning: failed to close video file: %v\n", err)
-		}
-	}()
+	if d.config.OutputDir == models.StdoutOutput {
+		return d.streamVideo(ctx, *video, variant)
+	}
 
-	// Download the video
-	err = d.downloadVideoStream(ctx, variants[0].Path, file, rowIndex, maxFilenameWidth)
+	filename := dir.CreateFilename(video.Title, variant.MediaType, video.Episode, d.config)
+	overwrite, err := dir.OverwriteVideoIfExists(filename, &d.config)
 	if err != nil {
-		return fmt.Errorf("%w: %w", errFailedToDownloadVideo, err)
+		return err
+	}
+
+	local := filename
+
+	if _, statErr := os.Stat(filename); statErr != nil {
+		reusedFile, reused, err := d.reuseDuplicate(*video, filename)
+		if err != nil {
+			return err
+		}
+
+		if reused {
+			local = reusedFile
+			overwrite = false
+		}
+	}
+
+	if !overwrite {
+		d.results = append(d.results, d.resultFor(*video, local, statusSkipped))
+
+		return nil // Skip download
+	}
+
+	if err := d.waitForSchedule(ctx); err != nil {
+		return err
+	}
+
+	job := downloadJob{video: *video, variant: variant, filename: filename}
+	if err := d.downloadToFile(ctx, job, 0); err != nil {
+		d.collector.Fail(*video, err)
+		d.results = append(d.results, d.resultFor(job.video, filename, statusFailed))
+		d.notify(ctx, video.Title, 1)
+
+		return err
+	}
+
+	d.results = append(d.results, d.resultFor(job.video, filename, statusDownloaded))
+	d.recordHistory("", "", []downloadJob{job})
+	d.notify(ctx, video.Title, 1)
+
+	if d.config.Quiet {
+		fmt.Fprintln(d.out, i18n.T("Downloaded %s", filename))
 	}
 
 	return nil
 }
 
-// downloadVideoStream downloads video data from endpoint to file with progress tracking.
-func (d *downloader) downloadVideoStream(ctx context.Context, endpoint string, file *os.File, rowIndex int, maxFilenameWidth int) error {
-	fullURL, err := url.JoinPath(baseURL, endpoint)
-	if err != nil {
-		return fmt.Errorf("%w: %w", errFailedToConstructURL, err)
+// downloadVideoStream downloads the job's video data to file with progress tracking.
+func (d *downloader) downloadVideoStream(ctx context.Context, job downloadJob, file *os.File, maxFilenameWidth int) error {
+	if d.config.ExternalDownloader == models.ExternalCurl {
+		return d.downloadWithCurl(ctx, job, file, maxFilenameWidth)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, http.NoBody)
+	fullURL, err := videoURL(job.variant.Path)
 	if err != nil {
-		return fmt.Errorf("%w: %w", errFailedToFetchVideoStream, err)
+		return err
+	}
+
+	if d.config.Segments > 1 {
+		err := d.downloadSegmented(ctx, job, file, fullURL, maxFilenameWidth)
+		if !errors.Is(err, errNotSegmentable) {
+			if err != nil && ctx.Err() != nil {
+				return fmt.Errorf("download cancelled: %w", ctx.Err())
+			}
+
+			return err
+		}
 	}
 
-	resp, err := d.client.makeRequestWithReq(req)
+	source, total, err := d.openVideoStream(ctx, fullURL)
 	if err != nil {
 		if ctx.Err() != nil {
 			return fmt.Errorf("download cancelled: %w", ctx.Err())
 		}
 
-		return fmt.Errorf("%w: %w", errFailedToFetchVideoStream, err)
+		return err
 	}
 
 	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			fmt.Printf("Warning: failed to close response body: %v\n", err)
+		if err := source.Close(); err != nil {
+			stream.Warnf(d.out, "failed to close response body: %v", err)
 		}
 	}()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%w: status %d: %s",
-			errHTTPNotOK,
-			resp.StatusCode,
-			http.StatusText(resp.StatusCode))
+	dst, flush := d.newFileWriter(file, file)
+
+	if d.onProgress != nil {
+		_, err = io.CopyBuffer(newCallbackWriter(dst, job.video.ID, total, d.reportProgress), source, d.newCopyBuffer())
+	} else {
+		err = progress.Copy(source, dst, d.newCopyBuffer(), total, file.Name(), maxFilenameWidth)
+	}
+
+	if err == nil {
+		err = flush()
 	}
 
-	err = progress.BarWithRow(resp.Body, file, resp.ContentLength, file.Name(), rowIndex, maxFilenameWidth)
 	if err != nil {
 		if ctx.Err() != nil {
 			return fmt.Errorf("download cancelled: %w", ctx.Err())
@@ -231,89 +572,164 @@ func (d *downloader) downloadVideoStream(ctx context.Context, endpoint string, f
 		return fmt.Errorf("%w: %w", errFailedToCopyVideoData, err)
 	}
 
+	return checkLength(source.offset, total)
+}
+
+// checkLength returns errIncompleteDownload if written differs from the Content-Length
+// total, so truncated transfers are not reported as success. An unknown total (-1) passes.
+func checkLength(written int64, total int64) error {
+	if total >= 0 && written != total {
+		return fmt.Errorf("%w: got %d of %d bytes", errIncompleteDownload, written, total)
+	}
+
 	return nil
 }
 
-// downloadVideosParallel downloads multiple videos concurrently.
-// Returns slice of failed video titles.
-func (d *downloader) downloadVideosParallel(ctx context.Context, videos []models.Video, indices []int, longestVideoName int) []string {
-	var (
-		failed []string
-		wg     sync.WaitGroup
-		mutex  sync.Mutex
-	)
+// downloadVideosParallel downloads multiple videos concurrently, at most config.Concurrency
+// at once, in the configured order.
+// Failed videos, including those interrupted by cancellation, are recorded in the collector.
+func (d *downloader) downloadVideosParallel(ctx context.Context, jobs []downloadJob, longestVideoName int) {
+	var wg sync.WaitGroup
 
-	numVideos := len(indices)
+	jobs = slices.Clone(jobs)
+	d.orderJobs(ctx, jobs)
 
-	for i, idx := range indices {
-		if ctx.Err() != nil {
-			break // context already cancelled
-		}
+	numVideos := len(jobs)
+
+	d.queue.push(jobs...)
 
+	workers := d.config.Concurrency
+	if workers <= 0 || workers > numVideos {
+		workers = numVideos
+	}
+
+	for range workers {
 		wg.Add(1)
 
-		go func(videoIdx int, rowIndex int) {
+		go func() {
 			defer wg.Done()
 
-			if ctx.Err() != nil {
-				return // aborted before we started
+			for {
+				job, ok := d.queue.next()
+				if !ok {
+					return
+				}
+
+				err := ctx.Err() // aborted before we started
+				if err == nil {
+					progress.JobStarted()
+					err = d.downloadToFile(ctx, job, longestVideoName)
+					progress.JobFinished()
+				}
+
+				if err != nil {
+					d.collector.Fail(job.video, err)
+				} else {
+					d.resume.done(job.video.ID)
+				}
 			}
+		}()
+	}
 
-			video := videos[videoIdx]
+	wg.Wait()
+}
 
-			// Get variants to calculate filename width
-			variants, err := d.getVideoVariants(ctx, video.ID)
-			if err != nil || len(variants) == 0 {
-				mutex.Lock()
-				failed = append(failed, video.Title)
-				mutex.Unlock()
+// finishChannelRun stores the resume state, sends the notification and writes the manifest of a channel run.
+func (d *downloader) finishChannelRun(
+	ctx context.Context,
+	channel models.Channel,
+	runAt time.Time,
+	videos []models.Video,
+	selectedIndices []int,
+	jobs []downloadJob,
+) {
+	d.updateResumeState(channel.ID, channel.Name, jobs, d.collector.Failed())
+	d.recordHistory(channel.ID, channel.Name, jobs)
+	d.notify(ctx, channel.Name, len(selectedIndices))
+
+	if d.config.Playlist {
+		if err := writePlaylist(d.config.OutputDir, d.completedJobs(jobs), d.config.FileMode); err != nil {
+			stream.Warnf(d.out, "failed to write playlist: %v", err)
+		}
+	}
 
-				return
-			}
+	if d.config.WriteFeed {
+		if err := writeFeed(d.config.OutputDir, channel, d.completedJobs(jobs), d.config.FileMode); err != nil {
+			stream.Warnf(d.out, "failed to write feed: %v", err)
+		}
+	}
 
-			filename := dir.CreateFilename(video.Title, variants[0].MediaType, video.Episode, d.config)
-			basename := filepath.Base(filename)
+	if !d.config.NoManifest {
+		m := newManifest(channel.ID, channel.Name, runAt, d.config.OutputDir, videos, selectedIndices, jobs, d.collector)
+		if d.appendManifest {
+			m = mergeManifest(d.config.OutputDir, m)
+		}
 
-			mutex.Lock()
-			longestVideoName = max(len(basename), longestVideoName)
-			currentLongest := longestVideoName
-			mutex.Unlock()
+		if err := writeManifest(d.config.OutputDir, m, d.config.FileMode); err != nil {
+			stream.Warnf(d.out, "failed to write manifest: %v", err)
+		}
+	}
+}
 
-			if err := d.downloadVideo(ctx, video.ID, false, rowIndex, currentLongest); err != nil {
-				if ctx.Err() == nil { // only record failure if not cancelled
-					mutex.Lock()
-					failed = append(failed, video.Title)
-					mutex.Unlock()
-				}
-			}
-		}(idx, numVideos-i)
+// finishArchive moves the manifest, playlist and feed of the run into the archive and
+// completes it. The archive is kept if succeeded is set or any video is in it.
+func (d *downloader) finishArchive(succeeded bool) error {
+	var files []string
+
+	for _, name := range []string{manifestFilename, playlistFilename, feedFilename} {
+		file := filepath.Join(d.config.OutputDir, name)
+		if _, err := os.Stat(file); err == nil {
+			files = append(files, file)
+		}
 	}
 
-	wg.Wait()
+	return errors.Join(d.archive.add(files...), d.archive.close(succeeded))
+}
+
+// completedJobs returns the prepared jobs whose video is in place after the run: those
+// of downloads that succeeded according to the collector, and those that needed no
+// download like existing or reused files. Failed and cancelled downloads are left out,
+// as their files are partial or missing.
+func (d *downloader) completedJobs(downloads []downloadJob) []downloadJob {
+	started := make(map[string]bool, len(downloads))
+	for _, job := range downloads {
+		started[job.video.ID] = true
+	}
+
+	completed := make([]downloadJob, 0, len(d.resolved))
+
+	for _, job := range d.resolved {
+		if _, ok := d.collector.Stat(job.video.ID); ok || !started[job.video.ID] {
+			completed = append(completed, job)
+		}
+	}
 
-	return failed
+	return completed
 }
 
 // getChannelMetadata retrieves channel metadata from the API.
-// Returns channel metadata including name.
-func (d *downloader) getChannelMetadata(ctx context.Context, channelID string) (*channelMetadata, error) {
-	fullURL, err := url.JoinPath(baseURL, channelAPI, channelID)
+// Returns channel metadata including name, with the ID filled in if the API omits it.
+func (d *downloader) getChannelMetadata(ctx context.Context, channelID string) (*models.Channel, error) {
+	fullURL, err := url.JoinPath(settings.BaseURL(), channelAPI, channelID)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", errFailedToConstructURL, err)
 	}
 
-	var data channelMetadata
+	var data models.Channel
 	if err := d.client.makeJSONRequest(ctx, fullURL, &data); err != nil {
 		return nil, fmt.Errorf("%w: %w", errFailedToDecodeChannelMeta, err)
 	}
 
+	data.ID = cmp.Or(data.ID, channelID)
+
 	return &data, nil
 }
 
 // getChannelVideos retrieves all videos from a channel.
-// Returns slice of videos with their IDs, titles, and episode numbers.
+// Returns slice of videos with their IDs, titles, and episode numbers, which are replaced by
+// their position in the channel if Renumber is set, or filled in if InferEpisodes is set.
 func (d *downloader) getChannelVideos(ctx context.Context, channelID string) ([]models.Video, error) {
-	fullURL, err := url.JoinPath(baseURL, channelAPI, channelID, "videos")
+	fullURL, err := url.JoinPath(settings.BaseURL(), channelAPI, channelID, "videos")
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", errFailedToConstructURL, err)
 	}
@@ -323,13 +739,17 @@ func (d *downloader) getChannelVideos(ctx context.Context, channelID string) ([]
 		return nil, fmt.Errorf("%w: %w", errFailedToDecodeChannelVideos, err)
 	}
 
+	if d.config.Renumber || d.config.InferEpisodes {
+		d.renumberEpisodes(videos, !d.config.Renumber)
+	}
+
 	return videos, nil
 }
 
 // getVideoMetadata retrieves video metadata from the API.
 // Returns video info including ID, title, and episode number.
 func (d *downloader) getVideoMetadata(ctx context.Context, videoID string) (*models.Video, error) {
-	fullURL, err := url.JoinPath(baseURL, videoAPI, videoID)
+	fullURL, err := url.JoinPath(settings.BaseURL(), videoAPI, videoID)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", errFailedToConstructURL, err)
 	}
@@ -344,13 +764,13 @@ func (d *downloader) getVideoMetadata(ctx context.Context, videoID string) (*mod
 
 // getVideoVariants retrieves available video variants from the API.
 // Returns slice of variants with download paths and media types.
-func (d *downloader) getVideoVariants(ctx context.Context, videoID string) ([]videoVariant, error) {
-	fullURL, err := url.JoinPath(baseURL, videoAPI, videoID, "video_variants")
+func (d *downloader) getVideoVariants(ctx context.Context, videoID string) ([]models.Variant, error) {
+	fullURL, err := url.JoinPath(settings.BaseURL(), videoAPI, videoID, "video_variants")
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", errFailedToConstructURL, err)
 	}
 
-	var variants []videoVariant
+	var variants []models.Variant
 	if err := d.client.makeJSONRequest(ctx, fullURL, &variants); err != nil {
 		return nil, fmt.Errorf("%w: %w", errFailedToDecodeVariants, err)
 	}
@@ -358,101 +778,559 @@ func (d *downloader) getVideoVariants(ctx context.Context, videoID string) ([]vi
 	return variants, nil
 }
 
+// infof prints a status message unless quiet mode is enabled.
+func (d *downloader) infof(format string, args ...any) {
+	if !d.config.Quiet {
+		fmt.Fprintf(d.out, format, args...)
+	}
+}
+
+// notify sends the batch summary to the configured command and webhook, if any.
+func (d *downloader) notify(ctx context.Context, name string, total int) {
+	if d.config.NotifyCmd == "" && d.config.NotifyWebhook == "" {
+		return
+	}
+
+	failed := d.collector.Failed()
+
+	titles := make([]string, 0, len(failed))
+	for _, video := range failed {
+		titles = append(titles, video.Title)
+	}
+
+	summary := notify.NewSummary(d.config.Media, name, total, titles, ctx.Err() != nil)
+	if err := notify.Send(d.config.NotifyCmd, d.config.NotifyWebhook, summary); err != nil {
+		stream.Warnf(d.out, "failed to send notification: %v", err)
+	}
+}
+
+// pickVariant selects the variant to download according to the quality policy.
+// The API lists variants from highest to lowest quality.
+func (d *downloader) pickVariant(ctx context.Context, variants []models.Variant) models.Variant {
+	if height := d.config.Quality.Height(); height > 0 && len(variants) > 1 {
+		return d.variantUpTo(ctx, variants, height)
+	}
+
+	if d.config.Quality == models.QualityLowest {
+		return variants[len(variants)-1]
+	}
+
+	return variants[0]
+}
+
+// variantUpTo returns the best variant whose frame is at most height pixels high, or the
+// lowest if all are higher. The frame sizes are read from the videos, from the highest
+// quality on until a variant fits. Takes the highest if no frame size can be read.
+func (d *downloader) variantUpTo(ctx context.Context, variants []models.Variant, height int) models.Variant {
+	probed := false
+
+	for _, variant := range variants {
+		fullURL, err := videoURL(variant.Path)
+		if err != nil {
+			continue
+		}
+
+		h, err := d.probeHeight(ctx, fullURL)
+		if err != nil {
+			continue
+		}
+
+		if h <= height {
+			return variant
+		}
+
+		probed = true
+	}
+
+	if probed {
+		return variants[len(variants)-1]
+	}
+
+	stream.Warnf(d.out, "failed to read the resolution of the variants, downloading the highest quality")
+
+	return variants[0]
+}
+
+// chooseVariant returns the variant of video to download: the one picked by the quality
+// policy, or with QualityPerVideo the one chosen by the user.
+// Returns input.ErrUserAbort if the user quit the prompt.
+func (d *downloader) chooseVariant(ctx context.Context, video models.Video, variants []models.Variant) (models.Variant, error) {
+	if d.config.Quality != models.QualityPerVideo || len(variants) == 1 {
+		return d.pickVariant(ctx, variants), nil
+	}
+
+	chosen, err := input.SelectVariant(video.Title, variants)
+	if err != nil {
+		return models.Variant{}, err //nolint:wrapcheck // Returned as is so ErrUserAbort stays recognizable
+	}
+
+	return variants[chosen], nil
+}
+
+// resolveQuality asks for the quality policy once the videos are selected if the config
+// leaves it to the user, so the answer applies to all videos of the run.
+func (d *downloader) resolveQuality() error {
+	if d.config.Quality != models.QualityAsk {
+		return nil
+	}
+
+	quality, err := input.SelectQuality()
+	if err != nil {
+		return err //nolint:wrapcheck // Returned as is so ErrUserAbort stays recognizable
+	}
+
+	d.config.Quality = quality
+
+	return nil
+}
+
 // prepareDownloads checks which videos need to be downloaded and validates their availability.
-// Returns indices of videos to download and longest filename width for alignment.
-func (d *downloader) prepareDownloads(ctx context.Context, videos []models.Video, indices []int, failed *[]string) ([]int, int) {
-	var (
-		videosToDownload []int
-		longestVideoName int
-	)
+// Resolves filename collisions between videos of the same run according to the collision policy.
+// Videos downloaded to another path before are handled by the duplicate policy, asked
+// for all of them at once. Existing files are collected and offered for overwriting in a
+// single list at the end. Returns
+// the jobs to download, or input.ErrUserAbort if the user quit a prompt. Videos that cannot
+// be downloaded are recorded in the collector.
+func (d *downloader) prepareDownloads(ctx context.Context, videos []models.Video, indices []int) ([]downloadJob, error) {
+	var jobs, conflicts, duplicates []downloadJob
+
+	var existing []history.Entry // Earlier downloads of the duplicates
+
+	taken := make(map[string]bool)
+	owners := make(map[string]models.Video) // Video each taken filename belongs to
 
 	for _, idx := range indices {
 		video := videos[idx]
 
 		variants, err := d.getVideoVariants(ctx, video.ID)
 		if err != nil {
-			fmt.Printf("\nFailed to get video variants for %s: %v\n", video.Title, err)
-			*failed = append(*failed, video.Title)
+			fmt.Fprintf(d.out, "\n%s\n", i18n.T("Failed to get video variants for %s: %v", video.Title, err))
+			d.collector.Fail(video, fmt.Errorf("%w: %w", errFailedToGetVideoVariants, err))
 
 			continue
 		}
 
 		if len(variants) == 0 {
-			fmt.Printf("\nNo variants found for %s\n", video.Title)
-			*failed = append(*failed, video.Title)
+			fmt.Fprintf(d.out, "\n%s\n", i18n.T("No variants found for %s", video.Title))
+			d.collector.Fail(video, errNoVariantsFound)
+
+			continue
+		}
+
+		variant, err := d.chooseVariant(ctx, video, variants)
+		if err != nil {
+			return nil, err
+		}
+
+		filename, planned := d.plannedFiles[video.ID]
+		if !planned {
+			filename = dir.CreateFilename(video.Title, variant.MediaType, video.Episode, d.config)
+		}
+
+		if taken[filename] && !planned {
+			switch d.config.OnCollision {
+			case models.CollisionSkip:
+				fmt.Fprintf(d.out, "\n%s\n", i18n.T("Skipping %s: %s is already used by another video", video.Title, filepath.Base(filename)))
+
+				continue
+			case models.CollisionError:
+				fmt.Fprintf(d.out, "\n%s\n", i18n.T("Filename collision for %s: %s is already used by another video", video.Title, filepath.Base(filename)))
+				d.collector.Fail(video, fmt.Errorf("%w: %s", errFilenameCollision, filepath.Base(filename)))
+
+				continue
+			case models.CollisionOverwrite:
+				earlier := owners[filename]
+				fmt.Fprintf(d.out, "\n%s\n", i18n.T("Replacing %s with %s: both are named %s", earlier.Title, video.Title, filepath.Base(filename)))
+				d.collector.Fail(earlier, fmt.Errorf("%w: %s", errFilenameOverwritten, video.Title))
+
+				jobs, conflicts, duplicates, existing = d.dropVideo(earlier.ID, jobs, conflicts, duplicates, existing)
+			default:
+				filename = d.renameCollision(video, filename, taken)
+			}
+		}
+
+		taken[filename] = true
+		owners[filename] = video
+
+		if remote, ok := d.movedToRemote(video, filename); ok {
+			d.infof("%s\n", i18n.T("Skipping %s, it was moved to %s", video.Title, remote))
 
 			continue
 		}
 
-		filename := dir.CreateFilename(video.Title, variants[0].MediaType, video.Episode, d.config)
-		if dir.OverwriteVideoIfExists(filename, d.config) {
-			videosToDownload = append(videosToDownload, idx)
+		if d.config.Sync && !d.config.Force {
+			if local, ok := d.syncedFile(video, filename); ok {
+				d.resolved = append(d.resolved, downloadJob{video: video, variant: variant, filename: local})
 
-			basename := filepath.Base(filename)
-			longestVideoName = max(len(basename), longestVideoName)
+				continue
+			}
 		}
+
+		job := downloadJob{video: video, variant: variant, filename: filename}
+
+		if _, statErr := os.Stat(filename); statErr != nil && d.config.OnDuplicate == models.DuplicateAsk {
+			if entry, ok := d.duplicateOf(video, filename); ok {
+				duplicates = append(duplicates, job)
+				existing = append(existing, entry)
+
+				continue
+			}
+		} else if statErr != nil {
+			local, reused, err := d.reuseDuplicate(video, filename)
+			if err != nil {
+				return nil, err
+			}
+
+			if reused {
+				d.resolved = append(d.resolved, downloadJob{video: video, variant: variant, filename: local})
+
+				continue
+			}
+		}
+
+		d.resolved = append(d.resolved, job)
+
+		if _, err := os.Stat(filename); err == nil && !d.config.Force && !d.config.Backup {
+			if d.config.Skip {
+				continue
+			}
+
+			conflicts = append(conflicts, job)
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	if len(duplicates) > 0 {
+		downloads, err := d.chooseDuplicates(duplicates, existing)
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, downloads...)
 	}
 
-	return videosToDownload, longestVideoName
+	if len(conflicts) == 0 {
+		return jobs, nil
+	}
+
+	kept, err := d.chooseOverwrites(conflicts)
+	if errors.Is(err, input.ErrUserAbort) {
+		return nil, err
+	}
+
+	if err != nil {
+		for _, job := range conflicts {
+			fmt.Fprintf(d.out, "\n%s\n", i18n.T("Failed to prepare %s: %v", job.video.Title, err))
+			d.collector.Fail(job.video, err)
+		}
+
+		kept = conflicts
+	}
+
+	return slices.DeleteFunc(jobs, func(job downloadJob) bool { return slices.Contains(kept, job) }), nil
+}
+
+// renameCollision returns a free filename for video, whose filename is already taken by
+// another video, according to the rename collision policy.
+func (d *downloader) renameCollision(video models.Video, filename string, taken map[string]bool) string {
+	var candidate string
+
+	switch d.config.OnCollision {
+	case models.CollisionRenameEpisode:
+		if video.Episode != "" {
+			candidate = dir.SuffixedFilename(filename, models.PadEpisode(video.Episode, d.config.EpisodePad))
+		}
+	case models.CollisionRenameID:
+		candidate = dir.SuffixedFilename(filename, video.ID)
+	}
+
+	if candidate != "" && !taken[candidate] {
+		return candidate
+	}
+
+	return dir.NextFreeFilename(filename, taken)
+}
+
+// dropVideo removes the video with id from the jobs prepared so far, which happens when
+// a later video overwrites it. Returns the remaining jobs, conflicts and duplicates with
+// the history entries of the duplicates.
+func (d *downloader) dropVideo(id string, jobs, conflicts, duplicates []downloadJob, existing []history.Entry) ([]downloadJob, []downloadJob, []downloadJob, []history.Entry) {
+	isVideo := func(job downloadJob) bool { return job.video.ID == id }
+
+	if i := slices.IndexFunc(duplicates, isVideo); i >= 0 {
+		duplicates = slices.Delete(duplicates, i, i+1)
+		existing = slices.Delete(existing, i, i+1)
+	}
+
+	d.resolved = slices.DeleteFunc(d.resolved, isVideo)
+
+	return slices.DeleteFunc(jobs, isVideo), slices.DeleteFunc(conflicts, isVideo), duplicates, existing
 }
 
 // printResults displays the download results summary.
-func (d *downloader) printResults(ctx context.Context, selectedCount int, failed []string) {
+func (d *downloader) printResults(ctx context.Context, selectedCount int) {
 	if ctx.Err() != nil {
-		fmt.Printf("\n%s Download aborted by user\n", styles.Error.Render("[ERROR]"))
+		fmt.Fprintf(d.out, "\n%s %s\n", styles.Error.Render("[ERROR]"), i18n.T("Download aborted by user"))
 
 		return
 	}
 
+	failed := d.collector.Failed()
+
 	successCount := selectedCount - len(failed)
-	fmt.Printf("\nDownload complete! %d/%d videos successful\n", successCount, selectedCount)
+	fmt.Fprintf(d.out, "\n%s\n", i18n.T("Download complete! %d/%d videos successful", successCount, selectedCount))
 
 	if len(failed) > 0 {
-		fmt.Printf("%s Failed downloads:\n", styles.Error.Render("[ERROR]"))
+		fmt.Fprintf(d.out, "%s %s\n", styles.Error.Render("[ERROR]"), i18n.T("Failed downloads:"))
 
-		for _, title := range failed {
-			fmt.Printf("  - %s\n", title)
+		for _, video := range failed {
+			fmt.Fprintf(d.out, "  - %s\n", video.Title)
+		}
+	}
+
+	if uploads := d.collector.FailedUploads(); len(uploads) > 0 {
+		fmt.Fprintf(d.out, "%s %s\n", styles.Error.Render("[ERROR]"), i18n.T("Failed uploads, kept locally:"))
+
+		for _, video := range uploads {
+			fmt.Fprintf(d.out, "  - %s\n", video.Title)
 		}
 	}
 }
 
+// printStats displays the transfer statistics of the downloaded jobs.
+func (d *downloader) printStats(ctx context.Context, jobs []downloadJob) {
+	if ctx.Err() != nil || d.config.Quiet {
+		return
+	}
+
+	stats := make([]models.DownloadStat, 0, len(jobs))
+
+	for _, job := range jobs {
+		if stat, ok := d.collector.Stat(job.video.ID); ok {
+			stats = append(stats, stat)
+		}
+	}
+
+	if len(stats) == 0 {
+		return
+	}
+
+	fmt.Fprintf(d.out, "\n%s\n", table.RenderDownloadStats(stats))
+}
+
 // processDownloads performs the actual video downloads in parallel.
-// Returns slice of failed video titles.
-func (d *downloader) processDownloads(ctx context.Context, videos []models.Video, indices []int, longestVideoName int) []string {
-	var failed []string
+// Failed videos are recorded in the collector.
+func (d *downloader) processDownloads(ctx context.Context, jobs []downloadJob) {
+	longestVideoName := 0
+	for _, job := range jobs {
+		longestVideoName = max(ansi.StringWidth(filepath.Base(job.filename)), longestVideoName)
+	}
 
-	numVideos := len(indices)
+	if d.config.Quiet {
+		d.downloadVideosParallel(ctx, jobs, longestVideoName)
 
-	fmt.Print(ansi.HideCursor)
+		return
+	}
 
-	for range numVideos {
-		fmt.Println() // Reserve a line for each video
+	// A single download shows its bar without the stats line of a batch
+	if len(jobs) > 1 {
+		progress.StartBatch(len(jobs))
+		defer progress.EndBatch()
 	}
 
-	failed = d.downloadVideosParallel(ctx, videos, indices, longestVideoName)
+	stopKeys := input.ListenKeys(func(key byte) {
+		if key == 'p' {
+			progress.SetPaused(d.pause.toggle())
+		}
+	})
 
-	fmt.Print(ansi.ShowCursor)
+	d.downloadVideosParallel(ctx, jobs, longestVideoName)
 
-	return failed
+	stopKeys()
 }
 
-// Download initiates the download process based on the provided configuration.
-// Extracts ID and type from media field, then downloads video or channel accordingly.
-func Download(config models.DownloadConfig) error {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// reportProgress passes the progress of a video to the progress callback and to the
+// progress file.
+func (d *downloader) reportProgress(videoID string, written int64, total int64) {
+	d.onProgress(videoID, written, total)
+	progress.Report(videoID, written, total)
+}
 
-	// Cancel context on SIGINT (Ctrl+C) for clean abort
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+// recordHistory adds the successfully downloaded jobs to the download history.
+// channelID and channelName are empty for single videos.
+func (d *downloader) recordHistory(channelID string, channelName string, jobs []downloadJob) {
+	entries := make([]history.Entry, 0, len(jobs))
 
-	defer signal.Stop(sigCh)
+	for _, job := range jobs {
+		if d.collector.HasFailed(job.video.ID) {
+			continue
+		}
 
-	go func() {
-		<-sigCh
-		cancel()
+		entry := history.Entry{
+			DownloadedAt: time.Now(),
+			PublishedAt:  job.video.PublishedAt,
+			VideoID:      job.video.ID,
+			Title:        job.video.Title,
+			Episode:      job.video.Episode,
+			ChannelID:    channelID,
+			ChannelName:  channelName,
+			File:         absPath(job.filename),
+			Tags:         d.config.Tags,
+			Note:         d.config.Note,
+		}
+
+		if d.archive != nil {
+			entry.File, entry.Archive = d.archive.name(job.filename), d.archive.target
+		}
+
+		if d.config.RcloneRemote != "" && d.collector.UploadError(job.video.ID) == "" {
+			entry.Remote, entry.Moved = d.remotePath(job.filename), d.config.RcloneMove
+		}
+
+		// Uploaded and archived files are gone already, their size was recorded before
+		if stat, ok := d.collector.Stat(job.video.ID); ok {
+			entry.Size = stat.Bytes
+		} else if info, err := os.Stat(job.filename); err == nil {
+			entry.Size = info.Size()
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := history.Append(entries...); err != nil {
+		stream.Warnf(d.out, "failed to update history: %v", err)
+	}
+}
+
+// newStat returns the transfer statistics of a finished job.
+func newStat(job downloadJob, start time.Time, elapsed time.Duration) models.DownloadStat {
+	stat := models.DownloadStat{Title: job.video.Title, File: job.filename, Started: start, Elapsed: elapsed}
+	if info, err := os.Stat(job.filename); err == nil {
+		stat.Bytes = info.Size()
+	}
+
+	return stat
+}
+
+// lockOutput locks the output directory against other runs. Returns a function that
+// releases the lock.
+func (d *downloader) lockOutput() (func(), error) {
+	return dir.LockFolder(absPath(cmp.Or(d.config.OutputDir, ".")), d.config.ForceLock) //nolint:wrapcheck // Already wrapped by the dir package
+}
+
+// useChannelFolder creates the folder of the channel and downloads into it, unless
+// videos are placed directly in the output directory. The {year} of the folder template
+// is the year the first video of the channel was published, or the current year. The
+// channel name is remembered for the embedded metadata. With DryRun, the folders that
+// would be created are reported instead.
+func (d *downloader) useChannelFolder(channelID string, channelName string, videos []models.Video) error {
+	d.channelName = channelName
+
+	folder := cmp.Or(d.config.OutputDir, ".")
+
+	if !d.config.Flat {
+		var first time.Time
+
+		for _, video := range videos {
+			if !video.PublishedAt.IsZero() && (first.IsZero() || video.PublishedAt.Before(first)) {
+				first = video.PublishedAt
+			}
+		}
+
+		if first.IsZero() {
+			first = time.Now()
+		}
+
+		folder = dir.ChannelFolderPath(channelName, channelID, strconv.Itoa(first.Year()), d.config)
+	}
+
+	plan := dir.PlanFolder(folder)
+
+	if d.config.DryRun {
+		d.reportFolderPlan(plan)
+
+		return nil
+	}
+
+	for _, collision := range plan.Collisions {
+		stream.Warnf(d.out, "folder %s differs from the existing %s in case only, they are the same folder on macOS and Windows",
+			collision.Planned, collision.Existing)
+	}
+
+	if d.config.Flat {
+		return nil
+	}
+
+	if err := dir.CreateFolder(plan, d.config.DirMode); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToCreateChannelFolder, err)
+	}
+
+	d.config.OutputDir = plan.Path
+
+	return nil
+}
+
+// reportFolderPlan prints the folder a download would write into, the folders it would
+// create and the existing folders that differ from them in case only.
+func (d *downloader) reportFolderPlan(plan dir.FolderPlan) {
+	fmt.Fprintln(d.out, i18n.T("Dry run, videos would be written to: %s", plan.Path))
+
+	for _, folder := range plan.Create {
+		fmt.Fprintln(d.out, "  "+i18n.T("would create %s", folder))
+	}
+
+	if len(plan.Create) == 0 {
+		fmt.Fprintln(d.out, "  "+i18n.T("all folders exist"))
+	}
+
+	for _, collision := range plan.Collisions {
+		fmt.Fprintln(d.out, "  "+i18n.T("collision: %s and %s differ in case only, they are the same folder on macOS and Windows",
+			collision.Planned, collision.Existing))
+	}
+}
+
+// writeVideoFile creates the job's target file and streams the video into it.
+// maxFilenameWidth aligns the progress bars of a multi-file download.
+func (d *downloader) writeVideoFile(ctx context.Context, job downloadJob, maxFilenameWidth int) error {
+	// Without bars the progress file is fed by the progress callback
+	if d.onProgress != nil {
+		defer progress.StartReport(job.video.ID, job.filename)()
+	}
+
+	if d.config.ExternalDownloader == models.ExternalAria2c {
+		if err := d.downloadWithAria2c(ctx, job, maxFilenameWidth); err != nil {
+			return fmt.Errorf("%w: %w", errFailedToDownloadVideo, err)
+		}
+
+		return dir.ApplyFileMode(job.filename, d.config.FileMode) //nolint:wrapcheck // Already wrapped by the dir package
+	}
+
+	file, err := dir.CreateVideoFile(job.filename, d.config)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToCreateVideoFile, err)
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			stream.Warnf(d.out, "failed to close video file: %v", err)
+		}
 	}()
 
+	err = d.downloadVideoStream(ctx, job, file, maxFilenameWidth)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToDownloadVideo, err)
+	}
+
+	return d.syncFile(file)
+}
+
+// Download initiates the download process based on the provided configuration.
+// Extracts ID and type from media field, then downloads video or channel accordingly.
+func Download(config models.DownloadConfig) error {
+	ctx, stop := newInterruptContext()
+	defer stop()
+
 	id, downloadType, err := extractIDAndType(config.Media)
 	if err != nil {
 		return fmt.Errorf("%w: %w", errFailedToExtractType, err)
@@ -465,38 +1343,81 @@ func Download(config models.DownloadConfig) error {
 		return err
 	}
 
-	downloader := newDownloader(config, client)
+	if err := checkRclone(config); err != nil {
+		return err
+	}
 
-	switch downloadType {
-	case videoType, unknownType:
-		if err = downloader.downloadVideo(ctx, id, true, 0, 0); err == nil {
-			return nil
-		}
+	// Archived videos are downloaded into a folder of their own first
+	var arc *archive
 
-		if ctx.Err() != nil {
-			return input.ErrUserAbort
+	if config.ArchiveOutput != "" {
+		arc, err = openArchive(config)
+		if err != nil {
+			return err
 		}
 
-		if downloadType == videoType || errors.Is(err, dir.ErrFailedToCreateFile) {
-			return fmt.Errorf("%w: %w", errFailedToDownloadVideo, err)
-		}
+		defer func() { _ = os.RemoveAll(arc.folder) }()
 
-		fallthrough // Fallthrough if type is unknown and try as channel
-	case channelType:
-		if err = downloader.downloadChannel(ctx, id); err != nil {
-			if ctx.Err() != nil {
-				return input.ErrUserAbort
-			}
+		config.OutputDir = arc.folder
+	}
 
-			if downloadType == unknownType {
-				return fmt.Errorf("%w", errInvalidID)
-			}
+	downloader := newDownloader(config, client)
+	downloader.archive = arc
 
-			return fmt.Errorf("%w: %w", errFailedToDownloadChannel, err)
+	// Nothing is written into a folder when streaming to stdout or on a dry run
+	if config.OutputDir != models.StdoutOutput && !config.DryRun {
+		unlock, err := downloader.lockOutput()
+		if err != nil {
+			return err
 		}
+		defer unlock()
 	}
 
-	return nil
+	if config.StagingDir != "" && !config.DryRun {
+		cleanStaging(config.StagingDir, downloader.out)
+	}
+
+	err = downloader.download(ctx, id, downloadType)
+
+	// Videos finished before a failure or interruption are kept in the archive
+	if arc != nil {
+		err = errors.Join(err, downloader.finishArchive(err == nil))
+	}
+
+	if config.JSON {
+		downloader.writeResult(ctx, err)
+	}
+
+	return err
+}
+
+// videoURL returns the absolute download URL of a variant path.
+func videoURL(variantPath string) (string, error) {
+	fullURL, err := url.JoinPath(settings.BaseURL(), variantPath)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errFailedToConstructURL, err)
+	}
+
+	return fullURL, nil
+}
+
+// newInterruptContext returns a context that is cancelled on SIGINT (Ctrl+C) or SIGTERM
+// for clean abort. The returned function releases the signal handler.
+func newInterruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigCh)
+		cancel()
+	}
 }
 
 // extractIDAndType extracts the ID and determines if it's a video or channel.
@@ -504,9 +1425,9 @@ func Download(config models.DownloadConfig) error {
 func extractIDAndType(media string) (string, mediaType, error) {
 	media = strings.TrimSpace(media)
 
-	// If input doesn't start with baseURL, return as unknown type. This is the
+	// If input doesn't start with the base URL, return as unknown type. This is the
 	// case when the Id was passed as an argument
-	prefixAndID, hasPrefix := strings.CutPrefix(media, baseURL)
+	prefixAndID, hasPrefix := strings.CutPrefix(media, settings.BaseURL())
 	if !hasPrefix {
 		return media, unknownType, nil
 	}
@@ -0,0 +1,150 @@
+package download
+
+import (
+	"cmp"
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"switchtube-downloader/internal/tracing"
+)
+
+// strictAPI makes API responses with fields unknown to the models fail to decode.
+//
+//nolint:gochecknoglobals // Enabled once at startup by --strict-api
+var strictAPI bool
+
+// reportedFields holds the unknown fields already warned about, so each is reported
+// once per run instead of once per video.
+//
+//nolint:gochecknoglobals // Shared by all clients of the process
+var reportedFields sync.Map
+
+// StrictAPI makes decoding an API response fail if it has fields the downloader does
+// not know, instead of only warning about them, so API changes surface while debugging.
+func StrictAPI() {
+	strictAPI = true
+}
+
+// decodeAPIResponse decodes the JSON body of an API response into target and logs the
+// fields target has no place for with --trace-http, as SwitchTube may have added or
+// renamed them. Such fields only fail the decoding with StrictAPI.
+func (c *client) decodeAPIResponse(body []byte, target any) error {
+	if err := decodeJSON(body, target); err != nil {
+		return err
+	}
+
+	var fresh []string
+
+	for _, field := range unknownFields(body, target) {
+		if _, reported := reportedFields.LoadOrStore(field, true); !reported {
+			fresh = append(fresh, field)
+		}
+	}
+
+	if len(fresh) > 0 {
+		tracing.Debugf("SwitchTube returned fields this version ignores: %s (use --strict-api to fail on them)",
+			strings.Join(fresh, ", "))
+	}
+
+	return nil
+}
+
+// unknownFields returns the keys of the objects in the JSON body that target has no
+// field for, as "Type.key" in sorted order. Nested objects are checked against the type
+// of the field they are decoded into.
+func unknownFields(body []byte, target any) []string {
+	var data any
+	if json.Unmarshal(body, &data) != nil {
+		return nil
+	}
+
+	found := make(map[string]bool)
+	collectUnknownFields(data, reflect.TypeOf(target), found)
+
+	return slices.Sorted(maps.Keys(found))
+}
+
+// collectUnknownFields adds the keys of the objects in data that type t has no field
+// for to found.
+func collectUnknownFields(data any, t reflect.Type, found map[string]bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() { //nolint:exhaustive // Other kinds hold no objects
+	case reflect.Slice, reflect.Array:
+		items, _ := data.([]any)
+		for _, item := range items {
+			collectUnknownFields(item, t.Elem(), found)
+		}
+	case reflect.Struct:
+		object, ok := data.(map[string]any)
+		if !ok {
+			return // e.g. time.Time, decoded from a string
+		}
+
+		for key, value := range object {
+			field, ok := jsonField(t, key)
+			if !ok {
+				found[t.Name()+"."+key] = true
+
+				continue
+			}
+
+			collectUnknownFields(value, field.Type, found)
+		}
+	}
+}
+
+// jsonField returns the field of the struct type t that encoding/json decodes key into,
+// matching names case-insensitively like encoding/json does. The fields of untagged
+// embedded structs are promoted like encoding/json does.
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+
+		if embedded := embeddedStruct(field); embedded != nil && name == "" {
+			if promoted, ok := jsonField(embedded, key); ok {
+				return promoted, true
+			}
+
+			continue
+		}
+
+		if field.IsExported() && strings.EqualFold(cmp.Or(name, field.Name), key) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// embeddedStruct returns the struct type of the embedded field, nil if field is not an
+// embedded struct or pointer to one.
+func embeddedStruct(field reflect.StructField) reflect.Type {
+	if !field.Anonymous {
+		return nil
+	}
+
+	t := field.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return t
+}
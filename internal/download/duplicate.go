@@ -0,0 +1,227 @@
+package download
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"switchtube-downloader/internal/helper/dir"
+	"switchtube-downloader/internal/helper/ui/input"
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/history"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/models"
+)
+
+// lastDownload returns the latest history entry of the video, loading the history on
+// first use.
+func (d *downloader) lastDownload(videoID string) (history.Entry, bool) {
+	if d.downloaded == nil {
+		entries, err := history.Load()
+		if err != nil {
+			stream.Warnf(d.out, "failed to load history: %v", err)
+		}
+
+		d.downloaded = history.LatestByVideo(entries)
+	}
+
+	entry, ok := d.downloaded[videoID]
+
+	return entry, ok
+}
+
+// duplicateOf returns the latest history entry of the video if it was downloaded to a
+// path other than filename before and the file is still there. Files inside an archive
+// cannot be reused.
+func (d *downloader) duplicateOf(video models.Video, filename string) (history.Entry, bool) {
+	entry, ok := d.lastDownload(video.ID)
+	if !ok || entry.Archive != "" || entry.File == absPath(filename) {
+		return history.Entry{}, false
+	}
+
+	if _, err := os.Stat(entry.File); err != nil {
+		return history.Entry{}, false
+	}
+
+	return entry, true
+}
+
+// reuseDuplicate handles a video that is missing at filename but was downloaded to
+// another path before, according to the duplicate policy. Returns the local file of the
+// video and true if it needs no download, or input.ErrUserAbort if the user quit the prompt.
+func (d *downloader) reuseDuplicate(video models.Video, filename string) (string, bool, error) {
+	policy := d.config.OnDuplicate
+	if policy == "" || policy == models.DuplicateDownload {
+		return "", false, nil
+	}
+
+	entry, ok := d.duplicateOf(video, filename)
+	if !ok {
+		return "", false, nil
+	}
+
+	if policy == models.DuplicateAsk {
+		choice, err := input.ConfirmDuplicates([]string{video.Title}, []string{entry.File})
+		if errors.Is(err, input.ErrUserAbort) {
+			return "", false, err //nolint:wrapcheck // Sentinel checked by the caller
+		}
+
+		policy = choice // Download again if nobody can be asked
+	}
+
+	local, reused := d.applyDuplicate(video, entry, filename, policy)
+
+	return local, reused, nil
+}
+
+// chooseDuplicates asks once what to do with all duplicates of a channel run, the jobs
+// of videos that were downloaded to the paths of existing before. Returns the jobs that
+// still need to be downloaded, or input.ErrUserAbort if the user quit the prompt.
+func (d *downloader) chooseDuplicates(duplicates []downloadJob, existing []history.Entry) ([]downloadJob, error) {
+	titles := make([]string, len(duplicates))
+	files := make([]string, len(duplicates))
+
+	for i, job := range duplicates {
+		titles[i] = job.video.Title
+		files[i] = existing[i].File
+	}
+
+	policy, err := input.ConfirmDuplicates(titles, files)
+	if errors.Is(err, input.ErrUserAbort) {
+		return nil, err //nolint:wrapcheck // Sentinel checked by the caller
+	}
+
+	var jobs []downloadJob
+
+	for i, job := range duplicates {
+		if local, reused := d.applyDuplicate(job.video, existing[i], job.filename, policy); reused {
+			job.filename = local
+		} else {
+			jobs = append(jobs, job)
+		}
+
+		d.resolved = append(d.resolved, job)
+	}
+
+	return jobs, nil
+}
+
+// applyDuplicate reuses the file of entry for the video missing at filename as decided
+// by policy. Linked and copied files are recorded in the history under their new path.
+// Returns the local file of the video and true if it needs no download.
+func (d *downloader) applyDuplicate(video models.Video, entry history.Entry, filename string, policy models.DuplicatePolicy) (string, bool) {
+	target := absPath(filename)
+
+	switch policy {
+	case models.DuplicateSkip:
+		d.infof("%s\n", i18n.T("Skipping %s, it was already downloaded to %s", video.Title, entry.File))
+
+		return entry.File, true
+	case models.DuplicateLink:
+		if err := d.linkFile(entry.File, target, video.PublishedAt); err != nil {
+			stream.Warnf(d.out, "failed to link %s: %v", entry.File, err)
+
+			return "", false
+		}
+
+		d.infof("%s\n", i18n.T("Linked %s to %s", entry.File, target))
+	case models.DuplicateCopy:
+		if err := d.copyFile(entry.File, target, video.PublishedAt); err != nil {
+			stream.Warnf(d.out, "failed to copy %s: %v", entry.File, err)
+
+			return "", false
+		}
+
+		d.infof("%s\n", i18n.T("Copied %s to %s", entry.File, target))
+	default:
+		return "", false
+	}
+
+	entry.DownloadedAt = time.Now()
+	entry.File = target
+
+	// The reused file goes into the archive like a downloaded one
+	if d.archive != nil {
+		if err := d.archive.add(target); err != nil {
+			stream.Warnf(d.out, "%v", err)
+
+			return "", false
+		}
+
+		entry.File, entry.Archive = d.archive.name(target), d.archive.target
+	}
+	entry.Tags = d.config.Tags
+	entry.Note = d.config.Note
+	d.downloaded[video.ID] = entry
+
+	if err := history.Append(entry); err != nil {
+		stream.Warnf(d.out, "failed to update history: %v", err)
+	}
+
+	return target, true
+}
+
+// copyFile copies src to target with the file mode of the config and applies the
+// publish date to the copy.
+func (d *downloader) copyFile(src string, target string, published time.Time) error {
+	source, err := os.Open(src) //nolint:gosec // Path recorded in the history
+	if err != nil {
+		return err //nolint:wrapcheck // Reported as warning by the caller
+	}
+
+	defer func() { _ = source.Close() }()
+
+	file, err := dir.CreateVideoFile(target, d.config)
+	if err != nil {
+		return err //nolint:wrapcheck // Already wrapped by the dir package
+	}
+
+	_, err = io.Copy(file, source)
+	if err == nil {
+		err = d.syncFile(file)
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(target)
+
+		return err //nolint:wrapcheck // Reported as warning by the caller
+	}
+
+	if !d.config.NoMtime && !published.IsZero() {
+		if err := dir.ApplyPublishDate(target, published); err != nil {
+			stream.Warnf(d.out, "failed to apply publish date to %s: %v", target, err)
+		}
+	}
+
+	return nil
+}
+
+// linkFile links target to src with the link mode of the config, or copies src if it
+// cannot be linked, e.g. because hardlinks do not work across filesystems. Symbolic links
+// point to src relative to target, so they survive moving both folders together.
+func (d *downloader) linkFile(src string, target string, published time.Time) error {
+	if err := dir.CreateParentDir(target, d.config); err != nil {
+		return err //nolint:wrapcheck // Already wrapped by the dir package
+	}
+
+	if d.config.LinkMode == models.LinkSymbolic {
+		link, err := filepath.Rel(filepath.Dir(target), src)
+		if err != nil {
+			link = src
+		}
+
+		if err := os.Symlink(link, target); err == nil {
+			return nil
+		}
+	} else if err := os.Link(src, target); err == nil {
+		return nil
+	}
+
+	return d.copyFile(src, target, published)
+}
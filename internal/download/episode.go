@@ -0,0 +1,72 @@
+package download
+
+import (
+	"fmt"
+	"strconv"
+
+	"switchtube-downloader/internal/models"
+)
+
+// renumberEpisodes replaces the episode numbers of the channel videos by their position
+// in the channel, starting at 1. With missingOnly, only videos without episode number
+// are numbered. The numbers are zero-padded to the configured padding, or to the digits
+// of the video count if that is more, so they sort correctly.
+// Videos numbered by an earlier download keep the number of their file, so the files do
+// not shift when videos are added to the channel and are still found by Skip. Videos
+// new to the channel are numbered after the highest number kept. Numbers that other
+// videos already have, e.g. from the uploader with missingOnly, are skipped so no two
+// videos share an episode.
+func (d *downloader) renumberEpisodes(videos []models.Video, missingOnly bool) {
+	width := max(d.config.EpisodePad, len(strconv.Itoa(len(videos))))
+	kept := make([]bool, len(videos))
+	taken := make(map[int]bool)
+	next := 1
+
+	for i := range videos {
+		if missingOnly && videos[i].Episode != "" {
+			if number, err := strconv.Atoi(videos[i].Episode); err == nil {
+				taken[number] = true
+			}
+
+			continue
+		}
+
+		if episode, number, ok := d.downloadedEpisode(videos[i].ID); ok {
+			videos[i].Episode = episode
+			kept[i] = true
+			taken[number] = true
+			next = max(next, number+1)
+		}
+	}
+
+	for i := range videos {
+		if kept[i] || missingOnly && videos[i].Episode != "" {
+			continue
+		}
+
+		number := max(i+1, next)
+		for taken[number] {
+			number++
+		}
+
+		videos[i].Episode = fmt.Sprintf("%0*d", width, number)
+		taken[number] = true
+		next = number + 1
+	}
+}
+
+// downloadedEpisode returns the episode the video was last downloaded with, as written
+// and as number, if it was downloaded before with a numeric episode.
+func (d *downloader) downloadedEpisode(videoID string) (string, int, bool) {
+	entry, ok := d.lastDownload(videoID)
+	if !ok {
+		return "", 0, false
+	}
+
+	number, err := strconv.Atoi(entry.Episode)
+	if err != nil || number < 1 {
+		return "", 0, false
+	}
+
+	return entry.Episode, number, true
+}
@@ -0,0 +1,67 @@
+package download
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// APIError is returned when SwitchTube answers a request with an unexpected status.
+type APIError struct {
+	Endpoint string // Path of the requested URL
+	Status   int    // HTTP status code
+}
+
+// Error implements error.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%v: status %d: %s (%s)", errHTTPNotOK, e.Status, http.StatusText(e.Status), e.Endpoint)
+}
+
+// Unwrap returns errHTTPNotOK so the error still matches the plain sentinel.
+func (e *APIError) Unwrap() error {
+	return errHTTPNotOK
+}
+
+// AuthError is returned when no valid access token is available or SwitchTube rejects it.
+type AuthError struct {
+	Err error // Underlying token or API error
+}
+
+// Error implements error.
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("authentication failed: %v", e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// NotFoundError is returned when the requested video, channel or file does not exist.
+type NotFoundError struct {
+	Endpoint string // Path of the requested URL
+}
+
+// Error implements error.
+func (e *NotFoundError) Error() string {
+	return "not found: " + e.Endpoint
+}
+
+// Unwrap returns the APIError of the 404 response.
+func (e *NotFoundError) Unwrap() error {
+	return &APIError{Endpoint: e.Endpoint, Status: http.StatusNotFound}
+}
+
+// statusError converts an unexpected response status into the matching typed error.
+func statusError(reqURL *url.URL, status int) error {
+	endpoint := reqURL.Path
+
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return &AuthError{Err: &APIError{Endpoint: endpoint, Status: status}}
+	case http.StatusNotFound:
+		return &NotFoundError{Endpoint: endpoint}
+	default:
+		return &APIError{Endpoint: endpoint, Status: status}
+	}
+}
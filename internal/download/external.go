@@ -0,0 +1,356 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"switchtube-downloader/internal/auth"
+	"switchtube-downloader/internal/helper/dir"
+	"switchtube-downloader/internal/helper/ui/progress"
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/settings"
+)
+
+// Options of aria2c downloads.
+const (
+	aria2cConnections = "16" // Connections aria2c opens per download
+	aria2cMaxTimeout  = 600  // Largest --timeout in seconds aria2c accepts
+)
+
+// aria2cReadout matches the bytes completed in the progress readout aria2c prints every
+// second, e.g. "[#2089b0 12MiB/33MiB(36%) CN:16 DL:4.1MiB ETA:5s]".
+//
+//nolint:gochecknoglobals // Compiled once
+var aria2cReadout = regexp.MustCompile(`\[#[0-9a-f]+ ([0-9.]+)([KMGT]?)i?B/`)
+
+var (
+	errExternalDownloaderFailed   = errors.New("external downloader failed")
+	errExternalDownloaderNotFound = errors.New("external downloader not found in PATH")
+)
+
+// contentLength returns the size of the resource at fullURL, or -1 if unknown.
+func (d *downloader) contentLength(ctx context.Context, fullURL string) int64 {
+	total, _ := d.headVideo(ctx, fullURL)
+
+	return total
+}
+
+// downloadWithAria2c lets aria2c download the job's video with multiple connections.
+// The headers are passed through aria2c's input file on stdin, keeping the credentials out of the process list.
+// The progress is read from aria2c's readout, as the preallocated file has its full size from the start.
+func (d *downloader) downloadWithAria2c(ctx context.Context, job downloadJob, maxFilenameWidth int) error {
+	fullURL, headers, err := d.externalRequest(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	if err := dir.CreateParentDir(job.filename, d.config); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToCreateVideoFile, err)
+	}
+
+	// aria2c would write into a linked file instead of replacing it
+	if err := dir.RemoveExisting(job.filename); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToCreateVideoFile, err)
+	}
+
+	total := d.contentLength(ctx, fullURL)
+
+	var input strings.Builder
+
+	fmt.Fprintf(&input, "%s\n", fullURL)
+
+	for _, header := range headers {
+		fmt.Fprintf(&input, "  header=%s\n", header)
+	}
+
+	fmt.Fprintf(&input, "  dir=%s\n  out=%s\n", filepath.Dir(job.filename), filepath.Base(job.filename))
+
+	args := []string{
+		"--input-file=-",
+		"--console-log-level=error",
+		"--summary-interval=0",
+		"--download-result=hide",
+		"--enable-color=false",
+		"--allow-overwrite=true",
+		"--auto-file-renaming=false",
+		"--max-connection-per-server=" + aria2cConnections,
+		"--split=" + aria2cConnections,
+	}
+	if stallTimeout > 0 {
+		// aria2c reconnects stalled connections itself, continuing where they stopped
+		args = append(args,
+			"--timeout="+strconv.Itoa(aria2cTimeout(stallTimeout)),
+			"--max-tries="+strconv.Itoa(maxReconnects+1),
+		)
+	}
+
+	cmd := exec.CommandContext(ctx, string(models.ExternalAria2c), args...)
+	cmd.Stdin = strings.NewReader(input.String())
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	readout := &aria2cProgress{}
+	cmd.Stdout = readout
+
+	done := make(chan struct{})
+	watched := make(chan struct{})
+
+	go func() {
+		defer close(watched)
+		d.watchProgress(job, total, maxFilenameWidth, readout.completed.Load, done)
+	}()
+
+	err = cmd.Run()
+	if err == nil {
+		readout.completed.Store(fileSize(job.filename))
+	}
+
+	close(done)
+	<-watched
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("download cancelled: %w", ctx.Err())
+		}
+
+		return fmt.Errorf("%w: %w: %s", errExternalDownloaderFailed, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// aria2cProgress is the stdout of aria2c, keeping the bytes completed as of the latest
+// progress readout.
+type aria2cProgress struct {
+	completed atomic.Int64 // Bytes completed as of the latest readout
+	line      []byte       // Start of a readout not completed by a line break yet
+}
+
+// Write implements io.Writer, parsing every completed line of the output.
+func (p *aria2cProgress) Write(data []byte) (int, error) {
+	p.line = append(p.line, data...)
+
+	for {
+		end := bytes.IndexAny(p.line, "\r\n")
+		if end < 0 {
+			return len(data), nil
+		}
+
+		if match := aria2cReadout.FindSubmatch(p.line[:end]); match != nil {
+			p.completed.Store(parseAria2cSize(string(match[1]), string(match[2])))
+		}
+
+		p.line = p.line[end+1:]
+	}
+}
+
+// parseAria2cSize converts a size of aria2c's readout, e.g. "4.1" with unit "M" for
+// 4.1 MiB, into bytes. Returns 0 if number is invalid.
+func parseAria2cSize(number string, unit string) int64 {
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0
+	}
+
+	if unit != "" {
+		value *= math.Pow(1024, float64(strings.Index("KMGT", unit)+1)) //nolint:mnd // Binary units
+	}
+
+	return int64(value)
+}
+
+// aria2cTimeout converts a stall timeout into the whole seconds aria2c accepts as --timeout.
+func aria2cTimeout(timeout time.Duration) int {
+	return min(max(int(timeout.Round(time.Second)/time.Second), 1), aria2cMaxTimeout)
+}
+
+// downloadWithCurl lets curl transfer the job's video and streams its output into file.
+// If the output stalls, curl is restarted to continue from the last byte written.
+// The headers are read by curl from stdin, keeping the credentials out of the process list.
+func (d *downloader) downloadWithCurl(ctx context.Context, job downloadJob, file *os.File, maxFilenameWidth int) error {
+	fullURL, headers, err := d.externalRequest(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	total := d.contentLength(ctx, fullURL)
+
+	source, _, err := d.openResuming(ctx, func(ctx context.Context, offset int64) (io.ReadCloser, int64, error) {
+		return startCurl(ctx, fullURL, headers, offset, total)
+	})
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = source.Close() }()
+
+	buffered, flush := d.newFileWriter(file, file)
+
+	if d.onProgress != nil {
+		_, err = io.CopyBuffer(newCallbackWriter(buffered, job.video.ID, total, d.reportProgress), source, d.newCopyBuffer())
+	} else {
+		err = progress.Copy(source, buffered, d.newCopyBuffer(), total, file.Name(), maxFilenameWidth)
+	}
+
+	if err == nil {
+		err = flush()
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("download cancelled: %w", ctx.Err())
+		}
+
+		return fmt.Errorf("%w: %w", errFailedToCopyVideoData, err)
+	}
+
+	return checkLength(source.offset, total)
+}
+
+// startCurl starts curl writing the video at fullURL from offset on to its stdout, and
+// returns its output together with the number of bytes remaining of total (-1 if unknown).
+// curl is killed when ctx is done.
+func startCurl(ctx context.Context, fullURL string, headers []string, offset int64, total int64) (io.ReadCloser, int64, error) {
+	args := []string{"--fail", "--silent", "--show-error", "--location", "--no-buffer", "--header", "@-"}
+	if offset > 0 {
+		// curl fails instead of writing the video from the start if ranges are not supported
+		args = append(args, "--continue-at", strconv.FormatInt(offset, 10))
+	}
+
+	cmd := exec.CommandContext(ctx, string(models.ExternalCurl), append(args, fullURL)...)
+	cmd.Stdin = strings.NewReader(strings.Join(headers, "\n") + "\n")
+
+	output := &commandOutput{cmd: cmd}
+	cmd.Stderr = &output.stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", errExternalDownloaderFailed, err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", errExternalDownloaderFailed, err)
+	}
+
+	output.stdout = stdout
+
+	remaining := int64(-1)
+	if total >= 0 {
+		remaining = total - offset
+	}
+
+	return output, remaining, nil
+}
+
+// commandOutput reads the stdout of a started command. At the end of the output it waits
+// for the command and returns its failure, if any, instead of io.EOF.
+type commandOutput struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr strings.Builder
+	waited bool
+	err    error // Failure of the command once waited for
+}
+
+// Read implements io.Reader.
+func (o *commandOutput) Read(p []byte) (int, error) {
+	n, err := o.stdout.Read(p)
+	if errors.Is(err, io.EOF) {
+		if waitErr := o.wait(); waitErr != nil {
+			return n, waitErr
+		}
+	}
+
+	return n, err //nolint:wrapcheck // Read errors like io.EOF must stay unwrapped
+}
+
+// Close stops reading the output, which ends the command if it is still writing, and
+// waits for it.
+func (o *commandOutput) Close() error {
+	_ = o.stdout.Close()
+	_ = o.wait()
+
+	return nil
+}
+
+// wait waits for the command once and returns its failure with its error output.
+func (o *commandOutput) wait() error {
+	if !o.waited {
+		o.waited = true
+
+		if err := o.cmd.Wait(); err != nil {
+			o.err = fmt.Errorf("%w: %w: %s", errExternalDownloaderFailed, err, strings.TrimSpace(o.stderr.String()))
+		}
+	}
+
+	return o.err
+}
+
+// externalRequest returns the download URL of the job for an external tool and the
+// headers to send as "Name: value" lines, the configured headers and the credentials.
+func (d *downloader) externalRequest(ctx context.Context, job downloadJob) (string, []string, error) {
+	tool := string(d.config.ExternalDownloader)
+	if _, err := exec.LookPath(tool); err != nil {
+		return "", nil, fmt.Errorf("%w: %s", errExternalDownloaderNotFound, tool)
+	}
+
+	fullURL, err := videoURL(job.variant.Path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	credentials, err := d.client.credentials(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return fullURL, append(auth.Lines(settings.Headers()), auth.Lines(credentials)...), nil
+}
+
+// watchProgress reports the bytes returned by current until done is closed,
+// through the progress callback if set or as progress bar otherwise.
+func (d *downloader) watchProgress(job downloadJob, total int64, maxFilenameWidth int, current func() int64, done <-chan struct{}) {
+	if d.onProgress == nil {
+		progress.Track(job.filename, total, maxFilenameWidth, current, done)
+
+		return
+	}
+
+	ticker := time.NewTicker(callbackInterval)
+	defer ticker.Stop()
+
+	for {
+		d.reportProgress(job.video.ID, current(), total)
+
+		select {
+		case <-done:
+			d.reportProgress(job.video.ID, current(), total)
+
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fileSize returns the size of filename, or 0 if it does not exist yet.
+func fileSize(filename string) int64 {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return 0
+	}
+
+	return info.Size()
+}
@@ -0,0 +1,135 @@
+package download
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"switchtube-downloader/internal/helper/dir"
+	"switchtube-downloader/internal/models"
+)
+
+const (
+	// feedFilename is the RSS feed written into the channel folder.
+	feedFilename = "feed.xml"
+	// feedPermissions are the permissions of the written feed.
+	feedPermissions = 0o644
+)
+
+// rss is the root element of an RSS 2.0 document.
+type rss struct {
+	XMLName xml.Name    `xml:"rss"`
+	Version string      `xml:"version,attr"`
+	Channel feedChannel `xml:"channel"`
+}
+
+// feedChannel describes the downloaded channel.
+type feedChannel struct {
+	Image       *feedImage `xml:"image,omitempty"`
+	Title       string     `xml:"title"`
+	Link        string     `xml:"link"`
+	Description string     `xml:"description"`
+	Copyright   string     `xml:"copyright,omitempty"`
+	Items       []feedItem `xml:"item"`
+}
+
+// feedImage is the channel thumbnail shown by podcast apps.
+type feedImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+// feedItem describes a single downloaded video.
+type feedItem struct {
+	Enclosure   feedEnclosure `xml:"enclosure"`
+	GUID        feedGUID      `xml:"guid"`
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	Description string        `xml:"description,omitempty"`
+	PubDate     string        `xml:"pubDate,omitempty"`
+}
+
+// feedEnclosure points to the local video file.
+type feedEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int64  `xml:"length,attr"`
+}
+
+// feedGUID identifies an item by its video ID.
+type feedGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// writeFeed writes an RSS feed of the completed jobs into folder, ordered by episode.
+// Enclosures are file URLs, so podcast apps can play the downloaded videos directly.
+// The channel's description, thumbnail and license are included if the API provides them.
+// The feed gets the given file mode if set.
+func writeFeed(folder string, channel models.Channel, jobs []downloadJob, mode os.FileMode) error {
+	sorted := slices.Clone(jobs)
+	slices.SortStableFunc(sorted, func(a downloadJob, b downloadJob) int {
+		return models.CompareEpisodes(a.video.Episode, b.video.Episode)
+	})
+
+	items := make([]feedItem, 0, len(sorted))
+
+	for _, job := range sorted {
+		abs, err := filepath.Abs(job.filename)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", job.filename, err)
+		}
+
+		item := feedItem{
+			Enclosure: feedEnclosure{
+				URL:    (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(),
+				Type:   job.variant.MediaType,
+				Length: fileSize(job.filename),
+			},
+			GUID:        feedGUID{Value: job.video.ID, IsPermaLink: false},
+			Title:       job.video.Title,
+			Link:        videoPage(job.video),
+			Description: job.video.Description,
+		}
+
+		if !job.video.PublishedAt.IsZero() {
+			item.PubDate = job.video.PublishedAt.Format(time.RFC1123Z)
+		}
+
+		items = append(items, item)
+	}
+
+	doc := rss{
+		Version: "2.0",
+		Channel: feedChannel{
+			Title:       channel.Name,
+			Link:        channelPage(channel),
+			Description: cmp.Or(channel.Description, "Videos of "+channel.Name+" downloaded from SwitchTube"),
+			Copyright:   channel.License,
+			Items:       items,
+		},
+	}
+
+	if channel.ThumbnailURL != "" {
+		doc.Channel.Image = &feedImage{URL: channel.ThumbnailURL, Title: channel.Name, Link: doc.Channel.Link}
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode feed: %w", err)
+	}
+
+	data = append([]byte(xml.Header), data...)
+
+	if err := os.WriteFile(filepath.Join(folder, feedFilename), data, feedPermissions); err != nil {
+		return fmt.Errorf("failed to write feed: %w", err)
+	}
+
+	return dir.ApplyFileMode(filepath.Join(folder, feedFilename), mode) //nolint:wrapcheck // Already wrapped by the dir package
+}
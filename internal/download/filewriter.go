@@ -0,0 +1,79 @@
+package download
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"switchtube-downloader/internal/models"
+)
+
+var errFailedToSyncFile = errors.New("failed to flush file to disk")
+
+// syncWriter flushes file to disk after every write to dst, a writer into file.
+type syncWriter struct {
+	dst  io.Writer
+	file *os.File
+}
+
+// Write implements io.Writer.
+func (sw syncWriter) Write(p []byte) (int, error) {
+	n, err := sw.dst.Write(p)
+	if err != nil {
+		return n, err //nolint:wrapcheck // Wrapped by the copying caller
+	}
+
+	if err := sw.file.Sync(); err != nil {
+		return n, fmt.Errorf("%w: %w", errFailedToSyncFile, err)
+	}
+
+	return n, nil
+}
+
+// newFileWriter returns the writer downloaded data is written to dst through, a writer
+// into file, and a function writing out the data still buffered. Data is collected in
+// a buffer of d.config.WriteBuffer bytes, so network shares receive few large writes
+// instead of many small ones, and flushed to disk after every write with FsyncAlways.
+func (d *downloader) newFileWriter(file *os.File, dst io.Writer) (io.Writer, func() error) {
+	if d.config.Fsync == models.FsyncAlways {
+		dst = syncWriter{dst: dst, file: file}
+	}
+
+	if d.config.WriteBuffer <= 0 {
+		return dst, func() error { return nil }
+	}
+
+	buffer := bufio.NewWriterSize(dst, d.config.WriteBuffer)
+
+	return buffer, buffer.Flush
+}
+
+// newCopyBuffer returns the buffer video data is copied through, of d.config.CopyBuffer
+// bytes so memory use can be traded for throughput, or nil for the io.CopyBuffer default.
+// io.CopyBuffer ignores the buffer if the source implements io.WriterTo or the
+// destination io.ReaderFrom, like the *os.File of curl's output. Every download path,
+// curl included, reads through a resumingReader and writes through a countingWriter or
+// callbackWriter, which hide both, so the buffer is used. See TestCopyBufferIsUsed.
+func (d *downloader) newCopyBuffer() []byte {
+	if d.config.CopyBuffer <= 0 {
+		return nil
+	}
+
+	return make([]byte, d.config.CopyBuffer)
+}
+
+// syncFile flushes the written file to disk unless the config leaves it to the OS,
+// so a completed video is not lost if the machine or network share goes away.
+func (d *downloader) syncFile(file *os.File) error {
+	if d.config.Fsync == models.FsyncNever {
+		return nil
+	}
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToSyncFile, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"switchtube-downloader/internal/models"
+)
+
+// benchmarkVideoSize is the amount of video data copied per benchmark iteration.
+const benchmarkVideoSize = 64 << 20
+
+// TestCopyBufferIsUsed checks that the readers and writers of the download paths hide
+// io.WriterTo and io.ReaderFrom, which would make io.CopyBuffer ignore the buffer.
+func TestCopyBufferIsUsed(t *testing.T) {
+	d := newDownloader(models.DownloadConfig{}, nil)
+
+	// bytes.Reader implements io.WriterTo like the *os.File of curl's output
+	src, _, err := d.openResuming(t.Context(), openReader(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := any(src).(io.WriterTo); ok {
+		t.Error("resuming reader implements io.WriterTo")
+	}
+
+	dst := countingWriter{writer: io.Discard, counter: &atomic.Int64{}}
+	if _, ok := any(dst).(io.ReaderFrom); ok {
+		t.Error("counting writer implements io.ReaderFrom")
+	}
+
+	if _, ok := any(newCallbackWriter(dst, "", 0, nil)).(io.ReaderFrom); ok {
+		t.Error("callback writer implements io.ReaderFrom")
+	}
+}
+
+// BenchmarkCopyBuffer copies video data the way the download paths do, through a
+// resuming reader into a counting writer, with copy buffers of several sizes.
+func BenchmarkCopyBuffer(b *testing.B) {
+	data := make([]byte, benchmarkVideoSize)
+
+	for _, kib := range []int{4, 32, 256, 1024} {
+		b.Run(strconv.Itoa(kib)+"KiB", func(b *testing.B) {
+			d := newDownloader(models.DownloadConfig{CopyBuffer: kib << 10}, nil)
+
+			var written atomic.Int64
+
+			dst := countingWriter{writer: io.Discard, counter: &written}
+
+			b.SetBytes(benchmarkVideoSize)
+			b.ReportAllocs()
+
+			for b.Loop() {
+				src, _, err := d.openResuming(b.Context(), openReader(data))
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				if _, err := io.CopyBuffer(dst, src, d.newCopyBuffer()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// openReader returns an openFunc serving data from the requested offset on.
+func openReader(data []byte) openFunc {
+	return func(_ context.Context, offset int64) (io.ReadCloser, int64, error) {
+		return io.NopCloser(bytes.NewReader(data[offset:])), int64(len(data)) - offset, nil
+	}
+}
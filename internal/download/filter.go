@@ -0,0 +1,65 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/models"
+)
+
+var errInvalidFilter = errors.New("invalid filter")
+
+// filterVideos removes the channel videos that do not match the filters of the config
+// before they are offered for selection: the title patterns and the duration limits.
+// Videos of unknown duration pass the duration limits.
+func (d *downloader) filterVideos(videos []models.Video) ([]models.Video, error) {
+	include, err := compileFilter(d.config.Include)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude, err := compileFilter(d.config.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	if include == nil && exclude == nil && d.config.MinDuration <= 0 && d.config.MaxDuration <= 0 {
+		return videos, nil
+	}
+
+	filtered := slices.DeleteFunc(slices.Clone(videos), func(video models.Video) bool {
+		if include != nil && !include.MatchString(video.Title) || exclude != nil && exclude.MatchString(video.Title) {
+			return true
+		}
+
+		length := video.Length()
+		if length <= 0 {
+			return false
+		}
+
+		return length < d.config.MinDuration || d.config.MaxDuration > 0 && length > d.config.MaxDuration
+	})
+
+	if hidden := len(videos) - len(filtered); hidden > 0 {
+		d.infof("%s\n", i18n.T("Skipping %d videos that do not match the filters", hidden))
+	}
+
+	return filtered, nil
+}
+
+// compileFilter compiles a title pattern, returning nil for an empty pattern.
+func compileFilter(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil //nolint:nilnil // No pattern means no filter
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidFilter, err)
+	}
+
+	return re, nil
+}
@@ -0,0 +1,73 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"switchtube-downloader/internal/helper/ui/table"
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/token"
+)
+
+var errInfoRequiresVideo = errors.New("info can only be shown for videos, not channels")
+
+// Info prints the metadata of each given video, including its license, page and
+// thumbnail, and its description, followed by its variants from the highest to the
+// lowest quality with their resolution and size read from the server where possible.
+func Info(media []string) error {
+	ctx, stop := newInterruptContext()
+	defer stop()
+
+	client, err := newClient(token.NewTokenManager())
+	if err != nil {
+		return err
+	}
+
+	d := newDownloader(models.DownloadConfig{}, client)
+
+	for _, m := range media {
+		id, downloadType, err := extractIDAndType(m)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errFailedToExtractType, err)
+		}
+
+		if downloadType == channelType {
+			return fmt.Errorf("%w: %s", errInfoRequiresVideo, m)
+		}
+
+		video, err := d.getVideoMetadata(ctx, id)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errFailedToGetVideoInfo, err)
+		}
+
+		variants, err := d.getVideoVariants(ctx, id)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errFailedToGetVideoVariants, err)
+		}
+
+		files := make([]models.VariantFile, len(variants))
+		for i, variant := range variants {
+			files[i] = d.inspectVariant(ctx, variant)
+		}
+
+		video.URL = videoPage(*video)
+		table.DisplayVideoInfo(*video, variants, files)
+	}
+
+	return nil
+}
+
+// inspectVariant reads the frame height and size of the file of variant from the
+// server, leaving what cannot be read zero.
+func (d *downloader) inspectVariant(ctx context.Context, variant models.Variant) models.VariantFile {
+	fullURL, err := videoURL(variant.Path)
+	if err != nil {
+		return models.VariantFile{}
+	}
+
+	height, _ := d.probeHeight(ctx, fullURL)
+	size, _ := d.headVideo(ctx, fullURL)
+
+	return models.VariantFile{Height: height, Size: max(size, 0)}
+}
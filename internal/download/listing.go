@@ -0,0 +1,116 @@
+package download
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"switchtube-downloader/internal/helper/ui/input"
+	"switchtube-downloader/internal/models"
+)
+
+const (
+	// sizeWorkers is the number of videos whose size is looked up at once.
+	sizeWorkers = 8
+	// sizeTimeout bounds the size lookup of a listing, sizes not known by then stay unknown.
+	sizeTimeout = 15 * time.Second
+)
+
+// fillSizes looks up the size of the variant that would be downloaded of every video,
+// so the selection can show it. Sizes that cannot be determined in time stay 0.
+// The variants and the sizes are fetched by two pools of workers, so the size of one
+// video is asked for while the variants of the next are fetched.
+func (d *downloader) fillSizes(ctx context.Context, videos []models.Video) {
+	ctx, cancel := context.WithTimeout(ctx, sizeTimeout)
+	defer cancel()
+
+	indices := make(chan int)
+	picked := make(chan pickedVariant)
+
+	var fetchers, sizers sync.WaitGroup
+
+	for range min(sizeWorkers, len(videos)) {
+		fetchers.Go(func() {
+			for i := range indices {
+				variants, err := d.getVideoVariants(ctx, videos[i].ID)
+				if err != nil || len(variants) == 0 {
+					continue
+				}
+
+				select {
+				case picked <- pickedVariant{index: i, variant: d.pickVariant(ctx, variants)}:
+				case <-ctx.Done():
+				}
+			}
+		})
+
+		sizers.Go(func() {
+			for pick := range picked {
+				videos[pick.index].Size = d.lookupSize(ctx, pick.variant)
+			}
+		})
+	}
+
+	for i := range videos {
+		select {
+		case indices <- i:
+		case <-ctx.Done():
+		}
+	}
+
+	close(indices)
+	fetchers.Wait()
+	close(picked)
+	sizers.Wait()
+}
+
+// pickedVariant is the variant picked for the video at index of a listing.
+type pickedVariant struct {
+	index   int            // Index of the video in the listing
+	variant models.Variant // Variant that would be downloaded
+}
+
+// previewVideo fetches the full metadata and the variants of a video for the preview
+// pane of the selection.
+func (d *downloader) previewVideo(ctx context.Context, video models.Video) (input.Preview, error) {
+	preview := input.Preview{Video: video, Size: video.Size}
+
+	if full, err := d.getVideoMetadata(ctx, video.ID); err == nil {
+		preview.Video = *full
+	}
+
+	variants, err := d.getVideoVariants(ctx, video.ID)
+	if err != nil {
+		return preview, err
+	}
+
+	preview.Variants = variants
+
+	if preview.Size == 0 {
+		preview.Size = d.variantSize(ctx, variants)
+	}
+
+	return preview, nil
+}
+
+// variantSize returns the size of the variant picked by the quality policy, 0 if unknown.
+func (d *downloader) variantSize(ctx context.Context, variants []models.Variant) int64 {
+	if len(variants) == 0 {
+		return 0
+	}
+
+	return d.lookupSize(ctx, d.pickVariant(ctx, variants))
+}
+
+// lookupSize returns the size of the file of variant as reported by the server, 0 if
+// unknown.
+func (d *downloader) lookupSize(ctx context.Context, variant models.Variant) int64 {
+	fullURL, err := videoURL(variant.Path)
+	if err != nil {
+		return 0
+	}
+
+	size, _ := d.headVideo(ctx, fullURL)
+
+	return max(size, 0)
+}
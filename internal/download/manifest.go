@@ -0,0 +1,172 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"switchtube-downloader/internal/helper/dir"
+	"switchtube-downloader/internal/models"
+)
+
+const (
+	// manifestFilename is the file written into the channel folder after a run.
+	manifestFilename = "manifest.json"
+	// manifestPermissions are the permissions of the written manifest.
+	manifestPermissions = 0o644
+)
+
+// Video statuses recorded in the manifest.
+const (
+	statusDownloaded = "downloaded"
+	statusSkipped    = "skipped"
+	statusFailed     = "failed"
+)
+
+// manifest records the outcome of a channel download run.
+type manifest struct {
+	RunAt       time.Time       `json:"runAt"`       // Start time of the run
+	ChannelID   string          `json:"channelId"`   // Channel ID
+	ChannelName string          `json:"channelName"` // Display name of the channel
+	Videos      []manifestEntry `json:"videos"`      // One entry per selected video
+}
+
+// manifestEntry records the outcome for a single selected video.
+type manifestEntry struct {
+	ID      string  `json:"id"`                       // Video ID
+	Title   string  `json:"title"`                    // Video title
+	Episode string  `json:"episode,omitempty"`        // Episode number as set by the uploader
+	URL     string  `json:"url"`                      // Page of the video on SwitchTube
+	License string  `json:"license,omitempty"`        // License of the video, if provided
+	File    string  `json:"file,omitempty"`           // Path relative to the manifest
+	Status  string  `json:"status"`                   // downloaded, skipped or failed
+	Size    int64   `json:"size,omitempty"`           // File size in bytes
+	Elapsed float64 `json:"elapsedSeconds,omitempty"` // Download time in seconds
+	Speed   float64 `json:"bytesPerSecond,omitempty"` // Average download speed
+}
+
+// mergeManifest updates the entries of the manifest already stored in folder with those of m.
+// Returns m unchanged if no readable manifest exists.
+func mergeManifest(folder string, m manifest) manifest {
+	data, err := os.ReadFile(filepath.Join(folder, manifestFilename))
+	if err != nil {
+		return m
+	}
+
+	var existing manifest
+	if err := json.Unmarshal(data, &existing); err != nil {
+		return m
+	}
+
+	updated := make(map[string]manifestEntry, len(m.Videos))
+	for _, entry := range m.Videos {
+		updated[entry.ID] = entry
+	}
+
+	for i, entry := range existing.Videos {
+		if newEntry, ok := updated[entry.ID]; ok {
+			existing.Videos[i] = newEntry
+			delete(updated, entry.ID)
+		}
+	}
+
+	for _, entry := range m.Videos {
+		if _, ok := updated[entry.ID]; ok {
+			existing.Videos = append(existing.Videos, entry)
+		}
+	}
+
+	existing.RunAt = m.RunAt
+
+	return existing
+}
+
+// newManifest builds the manifest for the selected videos of a channel run.
+// Videos that were neither downloaded nor failed are recorded as skipped.
+func newManifest(
+	channelID string,
+	channelName string,
+	runAt time.Time,
+	folder string,
+	videos []models.Video,
+	selectedIndices []int,
+	jobs []downloadJob,
+	collector *ResultCollector,
+) manifest {
+	files := make(map[string]string, len(jobs))
+	for _, job := range jobs {
+		files[job.video.ID] = job.filename
+	}
+
+	entries := make([]manifestEntry, 0, len(selectedIndices))
+
+	for _, idx := range selectedIndices {
+		video := videos[idx]
+		entry := manifestEntry{
+			ID:      video.ID,
+			Title:   video.Title,
+			Episode: video.Episode,
+			URL:     videoPage(video),
+			License: video.License,
+			Status:  statusSkipped,
+		}
+
+		if filename, ok := files[video.ID]; ok {
+			entry.File = relativeTo(folder, filename)
+			entry.Status = statusDownloaded
+
+			if info, err := os.Stat(filename); err == nil {
+				entry.Size = info.Size()
+			}
+
+			// Uploaded and archived files are gone already, their size was recorded before
+			if stat, ok := collector.Stat(video.ID); ok {
+				entry.Size = stat.Bytes
+				entry.Elapsed = stat.Elapsed.Seconds()
+				entry.Speed = stat.Speed()
+			}
+		}
+
+		if collector.HasFailed(video.ID) {
+			entry.Status = statusFailed
+			entry.Size = 0
+			entry.Elapsed = 0
+			entry.Speed = 0
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return manifest{
+		RunAt:       runAt,
+		ChannelID:   channelID,
+		ChannelName: channelName,
+		Videos:      entries,
+	}
+}
+
+// relativeTo returns path relative to folder, falling back to path itself.
+func relativeTo(folder string, path string) string {
+	rel, err := filepath.Rel(folder, path)
+	if err != nil {
+		return path
+	}
+
+	return rel
+}
+
+// writeManifest writes m as indented JSON into folder, with the given file mode if set.
+func writeManifest(folder string, m manifest, mode os.FileMode) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(folder, manifestFilename), data, manifestPermissions); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return dir.ApplyFileMode(filepath.Join(folder, manifestFilename), mode) //nolint:wrapcheck // Already wrapped by the dir package
+}
@@ -0,0 +1,189 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"switchtube-downloader/internal/helper/dir"
+	"switchtube-downloader/internal/models"
+)
+
+const (
+	// chaptersExtension is appended to the video name for the written chapters file.
+	chaptersExtension = ".ffmetadata"
+	// chaptersPermissions are the permissions of the written chapters file.
+	chaptersPermissions = 0o644
+)
+
+// ffmetadataEscaper escapes the characters with a special meaning in ffmetadata files.
+//
+//nolint:gochecknoglobals // Read-only replacer
+var ffmetadataEscaper = strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", `\`+"\n")
+
+var errFailedToEmbedMetadata = errors.New("failed to embed metadata")
+
+// metadataTag is a tag embedded into the metadata of a video file, e.g. its title.
+type metadataTag struct {
+	Key   string // ffmpeg name of the tag
+	Value string // The tag value
+}
+
+// addMetadata writes the chapters listed in the description of the job's video next to
+// its file and embeds its tags and chapters into file, the job's file or its staged copy,
+// as configured. The description is the only source of chapters; it is fetched if the
+// channel listing left it out. Videos without chapters get only their tags.
+func (d *downloader) addMetadata(ctx context.Context, job downloadJob, file string) error {
+	if job.video.Description == "" {
+		if full, err := d.getVideoMetadata(ctx, job.video.ID); err == nil {
+			job.video.Description = full.Description
+		}
+	}
+
+	var chapters []chapter
+	if d.config.Chapters != models.ChaptersNone {
+		chapters = parseChapters(job.video.Description)
+	}
+
+	if chapters != nil && d.config.Chapters == models.ChaptersFile {
+		if err := d.writeChaptersFile(job, chapters); err != nil {
+			return err
+		}
+
+		chapters = nil
+	}
+
+	var tags []metadataTag
+	if d.config.EmbedMetadata {
+		tags = d.metadataTags(job.video)
+	}
+
+	if tags == nil && chapters == nil {
+		return nil
+	}
+
+	return d.embedMetadata(ctx, file, job.video.Length(), tags, chapters)
+}
+
+// embedMetadata muxes the tags and chapters into file with ffmpeg without re-encoding
+// it. length is the length of the video. The file is replaced only once they were embedded.
+func (d *downloader) embedMetadata(ctx context.Context, file string, length time.Duration, tags []metadataTag, chapters []chapter) error {
+	if _, err := exec.LookPath(ffmpeg); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToEmbedMetadata, errFFmpegNotFound)
+	}
+
+	metadata, err := os.CreateTemp(filepath.Dir(file), ".metadata-*"+chaptersExtension)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToEmbedMetadata, err)
+	}
+
+	defer func() { _ = os.Remove(metadata.Name()) }()
+
+	_, err = metadata.WriteString(ffmetadata(tags, chapters, length))
+	if closeErr := metadata.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToEmbedMetadata, err)
+	}
+
+	ext := filepath.Ext(file)
+	temp := strings.TrimSuffix(file, ext) + ".metadata" + ext
+
+	args := []string{"-i", file, "-i", metadata.Name(), "-map", "0", "-c", "copy"}
+	if tags != nil {
+		args = append(args, "-map_metadata", "1")
+	}
+
+	if chapters != nil {
+		args = append(args, "-map_chapters", "1")
+	}
+
+	err = runFFmpeg(ctx, append(args, temp)...)
+	if err == nil {
+		err = os.Rename(temp, file)
+	}
+
+	if err != nil {
+		_ = os.Remove(temp)
+
+		return fmt.Errorf("%w: %w", errFailedToEmbedMetadata, err)
+	}
+
+	return dir.ApplyFileMode(file, d.config.FileMode) //nolint:wrapcheck // Already wrapped by the dir package
+}
+
+// metadataTags returns the title, episode, channel, publish date, description, license
+// and page of the video as tags, leaving out unknown values.
+func (d *downloader) metadataTags(video models.Video) []metadataTag {
+	tags := []metadataTag{{Key: "title", Value: video.Title}}
+
+	if d.channelName != "" {
+		tags = append(tags, metadataTag{Key: "album", Value: d.channelName}, metadataTag{Key: "show", Value: d.channelName})
+	}
+
+	if video.Episode != "" {
+		tags = append(tags, metadataTag{Key: "episode_id", Value: video.Episode})
+
+		if _, err := strconv.Atoi(video.Episode); err == nil {
+			tags = append(tags, metadataTag{Key: "track", Value: video.Episode})
+		}
+	}
+
+	if !video.PublishedAt.IsZero() {
+		tags = append(tags, metadataTag{Key: "date", Value: video.PublishedAt.Format(time.DateOnly)})
+	}
+
+	if video.Description != "" {
+		tags = append(tags, metadataTag{Key: "description", Value: video.Description})
+	}
+
+	if video.License != "" {
+		tags = append(tags, metadataTag{Key: "copyright", Value: video.License})
+	}
+
+	return append(tags, metadataTag{Key: "comment", Value: videoPage(video)})
+}
+
+// writeChaptersFile writes chapters as ffmetadata file next to the job's file.
+func (d *downloader) writeChaptersFile(job downloadJob, chapters []chapter) error {
+	filename := strings.TrimSuffix(job.filename, filepath.Ext(job.filename)) + chaptersExtension
+
+	//nolint:gosec // Chapters file next to the downloaded video, which is readable as well
+	if err := os.WriteFile(filename, []byte(ffmetadata(nil, chapters, job.video.Length())), chaptersPermissions); err != nil {
+		return fmt.Errorf("failed to write chapters: %w", err)
+	}
+
+	return dir.ApplyFileMode(filename, d.config.FileMode) //nolint:wrapcheck // Already wrapped by the dir package
+}
+
+// ffmetadata renders tags and chapters as ffmetadata file, chapters in milliseconds.
+// The last chapter ends at length, or where it starts if the length is unknown.
+func ffmetadata(tags []metadataTag, chapters []chapter, length time.Duration) string {
+	var b strings.Builder
+
+	b.WriteString(";FFMETADATA1\n")
+
+	for _, tag := range tags {
+		fmt.Fprintf(&b, "%s=%s\n", tag.Key, ffmetadataEscaper.Replace(tag.Value))
+	}
+
+	for i, c := range chapters {
+		end := max(length, c.Start)
+		if i+1 < len(chapters) {
+			end = chapters[i+1].Start
+		}
+
+		fmt.Fprintf(&b, "\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+			c.Start.Milliseconds(), end.Milliseconds(), ffmetadataEscaper.Replace(c.Title))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,154 @@
+package download
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/helper/ui/table"
+	"switchtube-downloader/internal/history"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/token"
+)
+
+// notDownloaded marks videos without history entry in offline listings.
+const notDownloaded = "-"
+
+// ListOffline shows the cached videos of the given videos or channels together with
+// their download state from the history, without contacting SwitchTube.
+// Lists all cached channels when no media is given.
+func ListOffline(media []string, config models.DownloadConfig) error {
+	client, err := newClient(token.NewTokenManager())
+	if err != nil {
+		return err
+	}
+
+	client.offline = true
+
+	entries, err := history.Load()
+	if err != nil {
+		return err
+	}
+
+	downloaded := history.LatestByVideo(entries)
+
+	if len(media) == 0 {
+		return listCachedChannels(entries)
+	}
+
+	session := &Session{out: client.out, client: client, config: config}
+
+	for _, m := range media {
+		listing, err := session.Lookup(context.Background(), m)
+		if err != nil {
+			return err
+		}
+
+		listCachedVideos(listing, downloaded)
+	}
+
+	return nil
+}
+
+// listCachedChannels shows every channel with cached metadata and how many of its
+// videos were downloaded.
+func listCachedChannels(entries []history.Entry) error {
+	responses, err := loadCachedResponses()
+	if err != nil {
+		return err
+	}
+
+	channelPath, err := url.JoinPath("/", channelAPI)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToConstructURL, err)
+	}
+
+	downloadedByChannel := make(map[string]map[string]bool)
+	for _, entry := range entries {
+		if downloadedByChannel[entry.ChannelID] == nil {
+			downloadedByChannel[entry.ChannelID] = make(map[string]bool)
+		}
+
+		downloadedByChannel[entry.ChannelID][entry.VideoID] = true
+	}
+
+	var rows [][]string
+
+	for _, cached := range responses {
+		reqURL, err := url.Parse(cached.URL)
+		if err != nil {
+			continue
+		}
+
+		id, isChannel := strings.CutPrefix(reqURL.Path, channelPath)
+		if !isChannel || id == "" || strings.Contains(id, "/") {
+			continue
+		}
+
+		var channel models.Channel
+		if decodeJSON(cached.Body, &channel) != nil {
+			continue
+		}
+
+		videoCount := "?"
+
+		if videosURL, err := url.JoinPath(cached.URL, "videos"); err == nil {
+			var videos []models.Video
+			if listing := loadCachedResponse(videosURL); listing != nil && decodeJSON(listing.Body, &videos) == nil {
+				videoCount = strconv.Itoa(len(videos))
+			}
+		}
+
+		rows = append(rows, []string{
+			id,
+			channel.Name,
+			videoCount,
+			strconv.Itoa(len(downloadedByChannel[id])),
+			cached.FetchedAt.Format(time.DateOnly),
+		})
+	}
+
+	if len(rows) == 0 {
+		fmt.Fprintln(stream.UI(), i18n.T("No channels cached yet"))
+
+		return nil
+	}
+
+	slices.SortFunc(rows, func(a []string, b []string) int { return cmp.Compare(a[1], b[1]) })
+	table.DisplayList([]string{"ID", i18n.T("Channel"), i18n.T("Videos"), i18n.T("Downloaded"), i18n.T("Cached")}, rows)
+
+	return nil
+}
+
+// listCachedVideos shows the videos of listing with their download state.
+func listCachedVideos(listing *Listing, downloaded map[string]history.Entry) {
+	if listing.IsChannel {
+		fmt.Fprintln(stream.UI(), i18n.T("Channel: %s (%d videos)", listing.Name, len(listing.Videos)))
+	}
+
+	rows := make([][]string, 0, len(listing.Videos))
+
+	for _, video := range listing.Videos {
+		published, downloadedAt, file := notDownloaded, notDownloaded, notDownloaded
+
+		if !video.PublishedAt.IsZero() {
+			published = video.PublishedAt.Format(time.DateOnly)
+		}
+
+		if entry, ok := downloaded[video.ID]; ok {
+			downloadedAt = entry.DownloadedAt.Format(time.DateOnly)
+			file = entry.Location()
+		}
+
+		rows = append(rows, []string{video.Episode, video.Title, published, downloadedAt, file})
+	}
+
+	table.DisplayList([]string{i18n.T("Episode"), i18n.T("Title"), i18n.T("Published"), i18n.T("Downloaded"), i18n.T("File")}, rows)
+}
@@ -0,0 +1,116 @@
+package download
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"switchtube-downloader/internal/helper/browser"
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/history"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/settings"
+	"switchtube-downloader/internal/token"
+)
+
+var errNotInHistory = errors.New("file is not in the download history")
+
+// Open opens the SwitchTube page of a video or channel ID/URL, or of the video a
+// downloaded file came from, in the default browser. Plain IDs are looked up in the
+// history first and resolved through the API otherwise.
+func Open(media string) error {
+	pageURL, err := resolvePage(media)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stream.UI(), i18n.T("Opening %s", pageURL))
+
+	return browser.Open(pageURL) //nolint:wrapcheck // Already wrapped by the browser package
+}
+
+// pageURL returns the SwitchTube page of a video or channel.
+func pageURL(prefix string, id string) (string, error) {
+	fullURL, err := url.JoinPath(settings.BaseURL(), prefix, id)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errFailedToConstructURL, err)
+	}
+
+	return fullURL, nil
+}
+
+// videoPage returns the page of video on SwitchTube as provided by the API, or else
+// built from its ID.
+func videoPage(video models.Video) string {
+	return cmp.Or(video.URL, settings.BaseURL()+videoPrefix+video.ID)
+}
+
+// channelPage returns the page of channel on SwitchTube as provided by the API, or else
+// built from its ID.
+func channelPage(channel models.Channel) string {
+	return cmp.Or(channel.URL, settings.BaseURL()+channelPrefix+channel.ID)
+}
+
+// resolvePage returns the SwitchTube page that media refers to.
+func resolvePage(media string) (string, error) {
+	entries, err := history.Load()
+	if err != nil {
+		return "", err //nolint:wrapcheck // Already wrapped by the history package
+	}
+
+	if _, err := os.Stat(media); err == nil {
+		file := absPath(media)
+		for _, entry := range entries {
+			if entry.File == file {
+				return pageURL(videoPrefix, entry.VideoID)
+			}
+		}
+
+		return "", fmt.Errorf("%w: %s", errNotInHistory, media)
+	}
+
+	id, downloadType, err := extractIDAndType(media)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errFailedToExtractType, err)
+	}
+
+	switch downloadType {
+	case videoType:
+		return pageURL(videoPrefix, id)
+	case channelType:
+		return pageURL(channelPrefix, id)
+	case unknownType:
+	}
+
+	for _, entry := range entries {
+		switch id {
+		case entry.VideoID:
+			return pageURL(videoPrefix, id)
+		case entry.ChannelID:
+			return pageURL(channelPrefix, id)
+		}
+	}
+
+	client, err := newClient(token.NewTokenManager())
+	if err != nil {
+		return "", err
+	}
+
+	ctx, stop := newInterruptContext()
+	defer stop()
+
+	d := newDownloader(models.DownloadConfig{}, client)
+
+	if _, err := d.getVideoMetadata(ctx, id); err == nil {
+		return pageURL(videoPrefix, id)
+	}
+
+	if _, err := d.getChannelMetadata(ctx, id); err != nil {
+		return "", fmt.Errorf("%w: %w", errInvalidID, err)
+	}
+
+	return pageURL(channelPrefix, id)
+}
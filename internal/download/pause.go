@@ -0,0 +1,68 @@
+package download
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pauseGate blocks the readers of all transfers of a downloader while paused.
+// Stopping to read lets TCP flow control throttle the server, so no data is lost.
+type pauseGate struct {
+	resumed   chan struct{} // Closed while not paused
+	resumedAt time.Time     // Time the transfers were last resumed, zero if never paused
+	mutex     sync.Mutex
+}
+
+// newPauseGate creates a pauseGate that is not paused.
+func newPauseGate() *pauseGate {
+	resumed := make(chan struct{})
+	close(resumed)
+
+	return &pauseGate{resumed: resumed}
+}
+
+// toggle pauses or resumes the transfers. Returns whether they are paused now.
+func (g *pauseGate) toggle() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	select {
+	case <-g.resumed:
+		g.resumed = make(chan struct{})
+
+		return true
+	default:
+		close(g.resumed)
+		g.resumedAt = time.Now()
+
+		return false
+	}
+}
+
+// wait blocks while the gate is paused. Returns the context error if ctx is cancelled meanwhile.
+func (g *pauseGate) wait(ctx context.Context) error {
+	g.mutex.Lock()
+	resumed := g.resumed
+	g.mutex.Unlock()
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck // Cancellation is reported as is
+	}
+}
+
+// state returns whether the transfers are paused and when they were last resumed.
+func (g *pauseGate) state() (bool, time.Time) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	select {
+	case <-g.resumed:
+		return false, g.resumedAt
+	default:
+		return true, g.resumedAt
+	}
+}
@@ -0,0 +1,197 @@
+package download
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/token"
+)
+
+// playerShutdownTimeout bounds how long open player connections are waited for on exit.
+const playerShutdownTimeout = 2 * time.Second
+
+var (
+	errNoPlayerFound      = errors.New("no media player found in PATH (install mpv or vlc, or pass --player)")
+	errPlayRequiresVideo  = errors.New("playing requires a video, not a channel")
+	errFailedToStartProxy = errors.New("failed to start playback proxy")
+	errPlayerFailed       = errors.New("media player failed")
+)
+
+// players are the media players tried in order if none is given.
+//
+//nolint:gochecknoglobals // Read-only list of players
+var players = []string{"mpv", "vlc"}
+
+// proxiedHeaders are the response headers passed on to the player, so it can seek.
+//
+//nolint:gochecknoglobals // Read-only list of headers
+var proxiedHeaders = []string{"Accept-Ranges", "Content-Length", "Content-Range", "Content-Type", "Last-Modified"}
+
+// Play streams a video in a local media player without downloading it. The player
+// gets the video from a proxy on the loopback interface that adds the auth header,
+// so the token never shows up in the process list. Uses the first of mpv and vlc
+// found in PATH if player is empty.
+func Play(media string, player string, quality models.QualityPolicy) error {
+	ctx, stop := newInterruptContext()
+	defer stop()
+
+	player, err := findPlayer(player)
+	if err != nil {
+		return err
+	}
+
+	id, downloadType, err := extractIDAndType(media)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToExtractType, err)
+	}
+
+	if downloadType == channelType {
+		return errPlayRequiresVideo
+	}
+
+	client, err := newClient(token.NewTokenManager())
+	if err != nil {
+		return err
+	}
+
+	d := newDownloader(models.DownloadConfig{Quality: quality}, client)
+
+	video, err := d.getVideoMetadata(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToGetVideoInfo, err)
+	}
+
+	variants, err := d.getVideoVariants(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToGetVideoVariants, err)
+	}
+
+	if len(variants) == 0 {
+		return errNoVariantsFound
+	}
+
+	fullURL, err := videoURL(d.pickVariant(ctx, variants).Path)
+	if err != nil {
+		return err
+	}
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToStartProxy, err)
+	}
+
+	// The random path keeps other local users from fetching the video through the proxy
+	path := "/" + rand.Text()
+	server := &http.Server{
+		Handler:           d.proxyHandler(path, fullURL),
+		ReadHeaderTimeout: playerShutdownTimeout,
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			stream.Warnf(stream.UI(), "playback proxy stopped: %v", err)
+		}
+	}()
+
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), playerShutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			_ = server.Close()
+		}
+	}()
+
+	fmt.Fprintln(stream.UI(), i18n.T("Playing %s with %s", video.Title, player))
+
+	cmd := exec.CommandContext(ctx, player, "http://"+listener.Addr().String()+path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, stream.UI(), os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		return fmt.Errorf("%w: %w", errPlayerFailed, err)
+	}
+
+	return nil
+}
+
+// findPlayer returns player if it is in PATH, or the first known player in PATH if
+// player is empty.
+func findPlayer(player string) (string, error) {
+	if player != "" {
+		if _, err := exec.LookPath(player); err != nil {
+			return "", fmt.Errorf("%w: %s", errNoPlayerFound, player)
+		}
+
+		return player, nil
+	}
+
+	for _, candidate := range players {
+		if _, err := exec.LookPath(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+
+	return "", errNoPlayerFound
+}
+
+// proxyHandler serves the video at fullURL on path with the auth header added. Range
+// requests are passed through, so the player can seek.
+func (d *downloader) proxyHandler(path string, fullURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path || r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		req, err := http.NewRequestWithContext(r.Context(), r.Method, fullURL, http.NoBody)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+
+		resp, err := d.client.makeRequestWithReq(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+
+			return
+		}
+
+		defer func() {
+			if err := resp.Body.Close(); err != nil {
+				stream.Warnf(d.out, "failed to close response body: %v", err)
+			}
+		}()
+
+		for _, header := range proxiedHeaders {
+			if value := resp.Header.Get(header); value != "" {
+				w.Header().Set(header, value)
+			}
+		}
+
+		w.WriteHeader(resp.StatusCode)
+
+		// The player closes connections when seeking, so copy errors are expected
+		_, _ = io.Copy(w, resp.Body)
+	})
+}
@@ -0,0 +1,50 @@
+package download
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"switchtube-downloader/internal/helper/dir"
+	"switchtube-downloader/internal/models"
+)
+
+const (
+	// playlistFilename is the playlist written into the channel folder.
+	playlistFilename = "playlist.m3u8"
+	// playlistPermissions are the permissions of the written playlist.
+	playlistPermissions = 0o644
+)
+
+// writePlaylist writes an extended M3U playlist of the completed jobs into folder,
+// ordered by episode number with the length of every video if known, with the given file
+// mode if set. Paths are relative so the folder can be moved.
+func writePlaylist(folder string, jobs []downloadJob, mode os.FileMode) error {
+	sorted := slices.Clone(jobs)
+	slices.SortStableFunc(sorted, func(a downloadJob, b downloadJob) int {
+		return models.CompareEpisodes(a.video.Episode, b.video.Episode)
+	})
+
+	var b strings.Builder
+
+	b.WriteString("#EXTM3U\n")
+
+	for _, job := range sorted {
+		// -1 marks an unknown length
+		length := -1
+		if seconds := int(job.video.Length().Round(time.Second).Seconds()); seconds > 0 {
+			length = seconds
+		}
+
+		fmt.Fprintf(&b, "#EXTINF:%d,%s\n%s\n", length, job.video.Title, filepath.ToSlash(relativeTo(folder, job.filename)))
+	}
+
+	if err := os.WriteFile(filepath.Join(folder, playlistFilename), []byte(b.String()), playlistPermissions); err != nil {
+		return fmt.Errorf("failed to write playlist: %w", err)
+	}
+
+	return dir.ApplyFileMode(filepath.Join(folder, playlistFilename), mode) //nolint:wrapcheck // Already wrapped by the dir package
+}
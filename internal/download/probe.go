@@ -0,0 +1,178 @@
+package download
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Layout of the MP4 boxes read to find the frame size of a video.
+const (
+	boxHeaderSize    = 8       // Size and type of a box
+	largeSizeSize    = 8       // 64-bit size following the header if the 32-bit size is 1
+	maxInlineSkip    = 1 << 16 // Largest top-level box skipped by reading instead of a new request
+	maxTopLevelBoxes = 16      // Top-level boxes looked at before giving up
+	tkhdHeightV0     = 80      // Offset of the height in a version 0 track header
+	tkhdHeightV1     = 92      // Offset of the height in a version 1 track header
+)
+
+var errNoFrameSize = errors.New("no frame size found in video")
+
+// probeHeight returns the frame height of the MP4 video at fullURL, read from the track
+// header of its first video track. Only the headers of the top-level boxes and the
+// movie box are requested, not the video data.
+func (d *downloader) probeHeight(ctx context.Context, fullURL string) (int, error) {
+	var offset int64
+
+	open := d.requestRange(fullURL, 0, -1)
+
+	for range maxTopLevelBoxes {
+		body, _, err := open(ctx, offset)
+		if err != nil {
+			return 0, err
+		}
+
+		height, next, err := scanBoxes(body, offset)
+		_ = body.Close()
+
+		if err != nil || height > 0 {
+			return height, err
+		}
+
+		offset = next
+	}
+
+	return 0, errNoFrameSize
+}
+
+// box is the header of an MP4 box.
+type box struct {
+	kind   string // Four-character type, e.g. "moov"
+	header int64  // Size of the header
+	size   int64  // Size of the content, -1 if the box extends to the end of the file
+}
+
+// scanBoxes reads the top-level boxes of r, which starts at offset of the video. Returns
+// the frame height if r reaches the movie box, or else the offset of the next box too
+// large to skip by reading.
+func scanBoxes(r io.Reader, offset int64) (int, int64, error) {
+	for {
+		b, err := readBoxHeader(r)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w: %w", errNoFrameSize, err)
+		}
+
+		switch {
+		case b.kind == "moov":
+			content := r
+			if b.size >= 0 {
+				content = io.LimitReader(r, b.size)
+			}
+
+			height, err := findHeight(content)
+			if err == nil && height == 0 {
+				err = errNoFrameSize
+			}
+
+			return height, 0, err
+		case b.size < 0:
+			return 0, 0, errNoFrameSize // The last box, and not the movie box
+		case b.size > maxInlineSkip:
+			return 0, offset + b.header + b.size, nil
+		}
+
+		if _, err := io.CopyN(io.Discard, r, b.size); err != nil {
+			return 0, 0, fmt.Errorf("%w: %w", errNoFrameSize, err)
+		}
+
+		offset += b.header + b.size
+	}
+}
+
+// findHeight returns the height of the first track with a frame size among the boxes in
+// r, descending into track boxes. Returns 0 if r ends without one.
+func findHeight(r io.Reader) (int, error) {
+	for {
+		b, err := readBoxHeader(r)
+		if errors.Is(err, io.EOF) {
+			return 0, nil
+		}
+
+		if err != nil || b.size < 0 {
+			return 0, errNoFrameSize
+		}
+
+		content := io.LimitReader(r, b.size)
+
+		switch b.kind {
+		case "trak":
+			height, err := findHeight(content)
+			if err != nil || height > 0 {
+				return height, err
+			}
+		case "tkhd":
+			if height := trackHeight(content); height > 0 {
+				return height, nil
+			}
+		}
+
+		// Skips what is left of the box
+		if _, err := io.Copy(io.Discard, content); err != nil {
+			return 0, fmt.Errorf("%w: %w", errNoFrameSize, err)
+		}
+	}
+}
+
+// trackHeight returns the height stored in the track header box in r, 0 for tracks
+// without picture like audio.
+func trackHeight(r io.Reader) int {
+	data, err := io.ReadAll(r)
+	if err != nil || len(data) == 0 {
+		return 0
+	}
+
+	at := tkhdHeightV0
+	if data[0] == 1 {
+		at = tkhdHeightV1
+	}
+
+	if len(data) < at+4 {
+		return 0
+	}
+
+	// 16.16 fixed-point number
+	return int(binary.BigEndian.Uint32(data[at:]) >> 16)
+}
+
+// readBoxHeader reads the header of the next box in r.
+func readBoxHeader(r io.Reader) (box, error) {
+	var header [boxHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return box{}, err //nolint:wrapcheck // io.EOF marks the end of the boxes
+	}
+
+	b := box{kind: string(header[4:]), header: boxHeaderSize}
+
+	switch size := int64(binary.BigEndian.Uint32(header[:4])); size {
+	case 0:
+		b.size = -1
+	case 1:
+		var large [largeSizeSize]byte
+		if _, err := io.ReadFull(r, large[:]); err != nil {
+			return box{}, fmt.Errorf("%w: %w", errNoFrameSize, err)
+		}
+
+		b.header += largeSizeSize
+		b.size = int64(binary.BigEndian.Uint64(large[:])) - b.header //nolint:gosec // Box sizes fit into int64
+	default:
+		b.size = size - b.header
+	}
+
+	if b.size < -1 {
+		return box{}, errNoFrameSize
+	}
+
+	return b, nil
+}
@@ -0,0 +1,129 @@
+package download
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"switchtube-downloader/internal/models"
+)
+
+// jobQueue hands the pending downloads of a run to the workers in priority order.
+type jobQueue struct {
+	jobs  []downloadJob // Pending jobs, next one first
+	mutex sync.Mutex
+}
+
+// bump moves the pending job of videoID to the front of the queue.
+// Returns false if the video is not pending anymore.
+func (q *jobQueue) bump(videoID string) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	i := slices.IndexFunc(q.jobs, func(job downloadJob) bool { return job.video.ID == videoID })
+	if i < 0 {
+		return false
+	}
+
+	job := q.jobs[i]
+	q.jobs = slices.Insert(slices.Delete(q.jobs, i, i+1), 0, job)
+
+	return true
+}
+
+// next removes and returns the first pending job. Returns false once the queue is empty.
+func (q *jobQueue) next() (downloadJob, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.jobs) == 0 {
+		return downloadJob{}, false
+	}
+
+	job := q.jobs[0]
+	q.jobs = q.jobs[1:]
+
+	return job, true
+}
+
+// push appends jobs to the end of the queue.
+func (q *jobQueue) push(jobs ...downloadJob) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.jobs = append(q.jobs, jobs...)
+}
+
+// orderJobs sorts jobs according to the configured download order. Sizes for the
+// smallest and largest orders are taken from the listing if known, see jobSizes, videos
+// of unknown size are downloaded last.
+func (d *downloader) orderJobs(ctx context.Context, jobs []downloadJob) {
+	switch d.config.Order {
+	case models.OrderEpisode:
+		slices.SortStableFunc(jobs, func(a downloadJob, b downloadJob) int {
+			return models.CompareEpisodes(a.video.Episode, b.video.Episode)
+		})
+	case models.OrderSmallest, models.OrderLargest:
+		sizes := d.jobSizes(ctx, jobs)
+
+		slices.SortStableFunc(jobs, func(a downloadJob, b downloadJob) int {
+			sizeA, sizeB := sizes[a.video.ID], sizes[b.video.ID]
+			if sizeA < 0 || sizeB < 0 {
+				return cmp.Compare(sizeB, sizeA) // Unknown sizes last
+			}
+
+			if d.config.Order == models.OrderLargest {
+				return cmp.Compare(sizeB, sizeA)
+			}
+
+			return cmp.Compare(sizeA, sizeB)
+		})
+	case models.OrderSelection:
+	}
+}
+
+// jobSizes returns the size of the file of every job by video ID, -1 if unknown. Sizes
+// shown in the listing are reused, the others are looked up with concurrent HEAD
+// requests.
+func (d *downloader) jobSizes(ctx context.Context, jobs []downloadJob) map[string]int64 {
+	sizes := make(map[string]int64, len(jobs))
+	pending := make(chan downloadJob)
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+	)
+
+	for range min(sizeWorkers, len(jobs)) {
+		wg.Go(func() {
+			for job := range pending {
+				size := int64(-1)
+				if fullURL, err := videoURL(job.variant.Path); err == nil {
+					size, _ = d.headVideo(ctx, fullURL)
+				}
+
+				mutex.Lock()
+				sizes[job.video.ID] = size
+				mutex.Unlock()
+			}
+		})
+	}
+
+	for _, job := range jobs {
+		if job.video.Size > 0 {
+			mutex.Lock()
+			sizes[job.video.ID] = job.video.Size
+			mutex.Unlock()
+
+			continue
+		}
+
+		pending <- job
+	}
+
+	close(pending)
+	wg.Wait()
+
+	return sizes
+}
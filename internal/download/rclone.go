@@ -0,0 +1,115 @@
+package download
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"switchtube-downloader/internal/models"
+)
+
+// rclone is the tool completed videos are uploaded to a cloud remote with.
+const rclone = "rclone"
+
+var (
+	errRcloneNotFound = errors.New(rclone + " not found in PATH")
+	errFailedToUpload = errors.New("failed to upload video")
+)
+
+// checkRclone returns an error if the config uploads videos but rclone is missing,
+// so a run fails before downloading anything instead of after every video.
+func checkRclone(config models.DownloadConfig) error {
+	if config.RcloneRemote == "" {
+		return nil
+	}
+
+	if _, err := exec.LookPath(rclone); err != nil {
+		return fmt.Errorf("%w: it is required by --rclone-remote", errRcloneNotFound)
+	}
+
+	return nil
+}
+
+// upload copies the job's file and the side files written next to it to the same path
+// below the rclone remote as below the output directory. With RcloneMove the local
+// files are deleted once uploaded. Files already uploaded are kept on the remote if a
+// later one fails.
+func (d *downloader) upload(ctx context.Context, job downloadJob) error {
+	subcommand := "copyto"
+	if d.config.RcloneMove {
+		subcommand = "moveto"
+	}
+
+	for _, file := range jobFiles(job) {
+		//nolint:gosec // Fixed tool, the files are passed as separate arguments
+		cmd := exec.CommandContext(ctx, rclone, subcommand, file, d.remotePath(file))
+
+		var stderr strings.Builder
+
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%w: %s: %w: %s (the download is kept as %s)",
+				errFailedToUpload, filepath.Base(file), err, strings.TrimSpace(stderr.String()), file)
+		}
+	}
+
+	return nil
+}
+
+// jobFiles returns the job's file followed by its side files that exist, the contact
+// sheet and the chapters file.
+func jobFiles(job downloadJob) []string {
+	files := []string{job.filename}
+	base := strings.TrimSuffix(job.filename, filepath.Ext(job.filename))
+
+	for _, extension := range []string{contactSheetExtension, chaptersExtension} {
+		if _, err := os.Stat(base + extension); err == nil {
+			files = append(files, base+extension)
+		}
+	}
+
+	return files
+}
+
+// remotePath returns the path of file on the rclone remote, relative to the remote like
+// file is to the output directory. Files outside the output directory are placed at the
+// top of the remote.
+func (d *downloader) remotePath(file string) string {
+	rel, err := filepath.Rel(cmp.Or(d.config.OutputDir, "."), file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		rel = filepath.Base(file)
+	}
+
+	remote := d.config.RcloneRemote
+	if !strings.HasSuffix(remote, ":") && !strings.HasSuffix(remote, "/") {
+		remote += "/"
+	}
+
+	return remote + filepath.ToSlash(rel)
+}
+
+// movedToRemote reports whether the video, missing at filename, was moved to the same
+// path on the configured rclone remote before, and returns that path. Such videos are
+// not downloaded again with Skip or Sync, unless Force is set.
+func (d *downloader) movedToRemote(video models.Video, filename string) (string, bool) {
+	if d.config.RcloneRemote == "" || d.config.Force || !d.config.Skip && !d.config.Sync {
+		return "", false
+	}
+
+	if _, err := os.Stat(filename); err == nil {
+		return "", false
+	}
+
+	entry, ok := d.lastDownload(video.ID)
+	if !ok || !entry.Moved || entry.Remote != d.remotePath(filename) {
+		return "", false
+	}
+
+	return entry.Remote, true
+}
@@ -0,0 +1,75 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"switchtube-downloader/internal/helper/dir"
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/models"
+)
+
+// ffmpeg is the tool that rewrites the container and metadata of downloaded videos.
+const ffmpeg = "ffmpeg"
+
+var (
+	errFFmpegNotFound = errors.New(ffmpeg + " not found in PATH")
+	errFailedToRemux  = errors.New("failed to remux video")
+)
+
+// remuxSource returns the file the job's video is downloaded to before it is remuxed
+// into the job's file, or an empty string if no remuxing is configured or the served
+// container is already the configured one.
+func (d *downloader) remuxSource(job downloadJob) (string, error) {
+	extension := dir.Extension(job.variant.MediaType)
+	if d.config.Remux == models.ContainerSource || string(d.config.Remux) == extension {
+		return "", nil
+	}
+
+	if _, err := exec.LookPath(ffmpeg); err != nil {
+		return "", fmt.Errorf("%w: it is required by --remux", errFFmpegNotFound)
+	}
+
+	return strings.TrimSuffix(job.filename, filepath.Ext(job.filename)) + ".source." + extension, nil
+}
+
+// remux copies the streams of source into the job's file without re-encoding them and
+// deletes source afterwards. source is kept if remuxing fails.
+func (d *downloader) remux(ctx context.Context, job downloadJob, source string) error {
+	if err := runFFmpeg(ctx, "-i", source, "-map", "0", "-c", "copy", job.filename); err != nil {
+		_ = os.Remove(job.filename)
+
+		return fmt.Errorf("%w: %w (the download is kept as %s)", errFailedToRemux, err, source)
+	}
+
+	if err := os.Remove(source); err != nil {
+		stream.Warnf(d.out, "failed to delete %s: %v", source, err)
+	}
+
+	return dir.ApplyFileMode(job.filename, d.config.FileMode) //nolint:wrapcheck // Already wrapped by the dir package
+}
+
+// runFFmpeg runs ffmpeg quietly with args, overwriting existing output files. Returns
+// the error output of ffmpeg with its exit status if it fails.
+func runFFmpeg(ctx context.Context, args ...string) error {
+	args = append([]string{"-hide_banner", "-loglevel", "error", "-nostdin", "-y"}, args...)
+
+	//nolint:gosec // Fixed tool, the files are passed as separate arguments
+	cmd := exec.CommandContext(ctx, ffmpeg, args...)
+
+	var stderr bytes.Buffer
+
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
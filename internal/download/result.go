@@ -0,0 +1,100 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/redact"
+)
+
+// videoResult is the outcome of a single video in the JSON result.
+type videoResult struct {
+	ID      string  `json:"id"`                       // The video ID
+	Title   string  `json:"title"`                    // The video title
+	File    string  `json:"file,omitempty"`           // Target path on disk, empty if none was chosen
+	Status  string  `json:"status"`                   // Same statuses as in the manifest
+	Error   string  `json:"error,omitempty"`          // Reason the video failed
+	Upload  string  `json:"uploadError,omitempty"`    // Reason the upload of the downloaded video failed
+	Size    int64   `json:"size,omitempty"`           // File size in bytes
+	Elapsed float64 `json:"elapsedSeconds,omitempty"` // Download time in seconds
+}
+
+// runResult is the machine-readable outcome of a download, printed to stdout with --json.
+type runResult struct {
+	Media   string        `json:"media"`           // Video or channel ID/URL as passed by the user
+	Error   string        `json:"error,omitempty"` // Reason the download failed as a whole
+	Videos  []videoResult `json:"videos"`          // Outcome of every selected video
+	Aborted bool          `json:"aborted"`         // Whether the download was aborted by the user
+}
+
+// addResults records the outcome of the selected videos of a batch for the JSON result.
+func (d *downloader) addResults(videos []models.Video, indices []int, jobs []downloadJob) {
+	files := make(map[string]string, len(d.resolved))
+	for _, job := range d.resolved {
+		files[job.video.ID] = job.filename
+	}
+
+	downloaded := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		downloaded[job.video.ID] = true
+	}
+
+	for _, i := range indices {
+		video := videos[i]
+
+		status := statusSkipped
+		if d.collector.HasFailed(video.ID) {
+			status = statusFailed
+		} else if downloaded[video.ID] {
+			status = statusDownloaded
+		}
+
+		d.results = append(d.results, d.resultFor(video, files[video.ID], status))
+	}
+}
+
+// resultFor builds the JSON result of the video with the failure reason and transfer
+// statistics recorded in the collector.
+func (d *downloader) resultFor(video models.Video, file string, status string) videoResult {
+	result := videoResult{ID: video.ID, Title: video.Title, File: file, Status: status}
+
+	if reason := d.collector.Reason(video.ID); reason != "" {
+		result.Error = redact.String(reason)
+	}
+
+	if reason := d.collector.UploadError(video.ID); reason != "" {
+		result.Upload = redact.String(reason)
+	}
+
+	if stat, ok := d.collector.Stat(video.ID); ok && status == statusDownloaded {
+		result.Size = stat.Bytes
+		result.Elapsed = stat.Elapsed.Seconds()
+	}
+
+	return result
+}
+
+// writeResult prints the recorded outcome of the download as a single line of JSON to
+// stdout, which is kept free of progress bars and prompts.
+func (d *downloader) writeResult(ctx context.Context, err error) {
+	result := runResult{
+		Media:   d.config.Media,
+		Videos:  d.results,
+		Aborted: ctx.Err() != nil,
+	}
+
+	if result.Videos == nil {
+		result.Videos = []videoResult{}
+	}
+
+	if err != nil {
+		result.Error = redact.String(err.Error())
+	}
+
+	if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+		stream.Warnf(d.out, "failed to write result: %v", err)
+	}
+}
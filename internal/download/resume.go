@@ -0,0 +1,469 @@
+package download
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"switchtube-downloader/internal/helper/dir"
+	"switchtube-downloader/internal/helper/ui/input"
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/token"
+)
+
+const (
+	// resumeDirName is the cache subdirectory holding one state file per channel.
+	resumeDirName = "resume"
+	// resumeStatePermissions are the permissions of written state files.
+	resumeStatePermissions = 0o600
+)
+
+var (
+	errFailedToLoadResumeState = errors.New("failed to load resume state")
+	errFailedToResumeChannel   = errors.New("failed to resume channel")
+)
+
+// pendingVideo is a video of an earlier run that still needs to be downloaded.
+type pendingVideo struct {
+	ID   string `json:"id"`             // Video ID
+	File string `json:"file,omitempty"` // Planned target file, if it was resolved
+}
+
+// resumeState persists the unfinished part of a channel run.
+type resumeState struct {
+	SavedAt     time.Time      `json:"savedAt"`     // Time the state was written
+	ChannelID   string         `json:"channelId"`   // Channel ID
+	ChannelName string         `json:"channelName"` // Display name of the channel
+	Pending     []pendingVideo `json:"pending"`     // Videos still to download
+	Config      resumeConfig   `json:"config"`      // Settings the pending videos are downloaded with
+}
+
+// resumeConfig holds the settings of a channel run that decide how its pending videos
+// are downloaded and stored. The selection and filters are not kept, as the pending
+// videos were selected already. The names match the fields of models.DownloadConfig.
+type resumeConfig struct {
+	OutputDir          string                    `json:"outputDir"` // Channel folder of the run
+	Tags               []string                  `json:"tags,omitempty"`
+	Note               string                    `json:"note,omitempty"`
+	Quality            models.QualityPolicy      `json:"quality,omitempty"`
+	OnCollision        models.CollisionPolicy    `json:"onCollision,omitempty"`
+	ExternalDownloader models.ExternalDownloader `json:"externalDownloader,omitempty"`
+	Remux              models.Container          `json:"remux,omitempty"`
+	Chapters           models.ChapterMode        `json:"chapters,omitempty"`
+	StagingDir         string                    `json:"stagingDir,omitempty"`
+	RcloneRemote       string                    `json:"rcloneRemote,omitempty"`
+	Fsync              models.FsyncPolicy        `json:"fsync,omitempty"`
+	FileMode           os.FileMode               `json:"fileMode,omitempty"`
+	DirMode            os.FileMode               `json:"dirMode,omitempty"`
+	Segments           int                       `json:"segments,omitempty"`
+	EpisodePad         int                       `json:"episodePad,omitempty"`
+	WriteBuffer        int                       `json:"writeBuffer,omitempty"`
+	CopyBuffer         int                       `json:"copyBuffer,omitempty"`
+	Concurrency        int                       `json:"concurrency,omitempty"`
+	UseEpisode         bool                      `json:"useEpisode,omitempty"`
+	Renumber           bool                      `json:"renumber,omitempty"`
+	InferEpisodes      bool                      `json:"inferEpisodes,omitempty"`
+	NoMtime            bool                      `json:"noMtime,omitempty"`
+	NoManifest         bool                      `json:"noManifest,omitempty"`
+	EmbedMetadata      bool                      `json:"embedMetadata,omitempty"`
+	ContactSheet       bool                      `json:"contactSheet,omitempty"`
+	RcloneMove         bool                      `json:"rcloneMove,omitempty"`
+}
+
+// newResumeConfig returns the settings of config a resume needs, with the output
+// directory made absolute.
+func newResumeConfig(config models.DownloadConfig) resumeConfig {
+	return resumeConfig{
+		OutputDir:          absPath(cmp.Or(config.OutputDir, ".")),
+		Tags:               config.Tags,
+		Note:               config.Note,
+		Quality:            config.Quality,
+		OnCollision:        config.OnCollision,
+		ExternalDownloader: config.ExternalDownloader,
+		Remux:              config.Remux,
+		Chapters:           config.Chapters,
+		StagingDir:         config.StagingDir,
+		RcloneRemote:       config.RcloneRemote,
+		Fsync:              config.Fsync,
+		FileMode:           config.FileMode,
+		DirMode:            config.DirMode,
+		Segments:           config.Segments,
+		EpisodePad:         config.EpisodePad,
+		WriteBuffer:        config.WriteBuffer,
+		CopyBuffer:         config.CopyBuffer,
+		Concurrency:        config.Concurrency,
+		UseEpisode:         config.UseEpisode,
+		Renumber:           config.Renumber,
+		InferEpisodes:      config.InferEpisodes,
+		NoMtime:            config.NoMtime,
+		NoManifest:         config.NoManifest,
+		EmbedMetadata:      config.EmbedMetadata,
+		ContactSheet:       config.ContactSheet,
+		RcloneMove:         config.RcloneMove,
+	}
+}
+
+// downloadConfig returns the configuration the pending videos are downloaded with.
+func (c resumeConfig) downloadConfig() models.DownloadConfig {
+	return models.DownloadConfig{
+		OutputDir:          c.OutputDir,
+		Tags:               c.Tags,
+		Note:               c.Note,
+		Quality:            c.Quality,
+		OnCollision:        cmp.Or(c.OnCollision, models.CollisionRename), // Not kept by older states
+		ExternalDownloader: c.ExternalDownloader,
+		Remux:              c.Remux,
+		Chapters:           c.Chapters,
+		StagingDir:         c.StagingDir,
+		RcloneRemote:       c.RcloneRemote,
+		Fsync:              c.Fsync,
+		FileMode:           c.FileMode,
+		DirMode:            c.DirMode,
+		Segments:           c.Segments,
+		EpisodePad:         c.EpisodePad,
+		WriteBuffer:        c.WriteBuffer,
+		CopyBuffer:         c.CopyBuffer,
+		Concurrency:        c.Concurrency,
+		UseEpisode:         c.UseEpisode,
+		Renumber:           c.Renumber,
+		InferEpisodes:      c.InferEpisodes,
+		NoMtime:            c.NoMtime,
+		NoManifest:         c.NoManifest,
+		EmbedMetadata:      c.EmbedMetadata,
+		ContactSheet:       c.ContactSheet,
+		RcloneMove:         c.RcloneMove,
+	}
+}
+
+// resumeTracker keeps the resume state of a running channel run up to date, so a run
+// that is killed before it finishes can be resumed too. It is safe for concurrent use.
+type resumeTracker struct {
+	out   io.Writer   // Destination of warnings
+	path  string      // State file of the channel
+	state resumeState // State as last written
+	mutex sync.Mutex  // Guards state across parallel downloads
+}
+
+// trackResume starts keeping the resume state of the channel run up to date while its
+// videos are downloaded, see resumeTracker.
+func (d *downloader) trackResume(channelID string, channelName string) {
+	path, err := resumeStatePath(channelID)
+	if err != nil {
+		stream.Warnf(d.out, "failed to update resume state: %v", err)
+
+		return
+	}
+
+	d.resume = &resumeTracker{
+		out:   d.out,
+		path:  path,
+		state: resumeState{ChannelID: channelID, ChannelName: channelName, Config: newResumeConfig(d.config)},
+	}
+}
+
+// start stores the jobs about to be downloaded and the videos that failed already as
+// pending. Does nothing on a nil tracker.
+func (t *resumeTracker) start(jobs []downloadJob, failed []models.Video) {
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.state.Pending = make([]pendingVideo, 0, len(jobs)+len(failed))
+
+	for _, job := range jobs {
+		t.state.Pending = append(t.state.Pending, pendingVideo{ID: job.video.ID, File: absPath(job.filename)})
+	}
+
+	for _, video := range failed {
+		t.state.Pending = append(t.state.Pending, pendingVideo{ID: video.ID})
+	}
+
+	t.save()
+}
+
+// done removes the downloaded video from the pending videos. Does nothing on a nil tracker.
+func (t *resumeTracker) done(videoID string) {
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.state.Pending = slices.DeleteFunc(t.state.Pending, func(video pendingVideo) bool { return video.ID == videoID })
+
+	t.save()
+}
+
+// save writes the state. Caller must hold mutex.
+func (t *resumeTracker) save() {
+	t.state.SavedAt = time.Now()
+
+	if err := saveResumeState(t.path, t.state); err != nil {
+		stream.Warnf(t.out, "failed to update resume state: %v", err)
+	}
+}
+
+// resumeChannel downloads the pending videos of an earlier run without prompting for selection.
+func (d *downloader) resumeChannel(ctx context.Context, state resumeState) error {
+	videos, err := d.getChannelVideos(ctx, state.ChannelID)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToGetChannelVideos, err)
+	}
+
+	d.plannedFiles = make(map[string]string, len(state.Pending))
+	for _, pending := range state.Pending {
+		d.plannedFiles[pending.ID] = pending.File
+	}
+
+	var indices []int
+
+	for i, video := range videos {
+		if filename, ok := d.plannedFiles[video.ID]; ok {
+			indices = append(indices, i)
+
+			// Unresolved files are named like in a regular run
+			if filename == "" {
+				delete(d.plannedFiles, video.ID)
+			}
+		}
+	}
+
+	if len(indices) == 0 {
+		fmt.Fprintln(d.out, i18n.T("Pending videos of channel %s are no longer available", state.ChannelName))
+		d.updateResumeState(state.ChannelID, state.ChannelName, nil, nil)
+
+		return nil
+	}
+
+	// Pending files are known to be incomplete
+	d.config.Force = true
+	d.channelName = state.ChannelName
+
+	d.infof("%s\n", i18n.T("Resuming %d videos of channel: %s", len(indices), state.ChannelName))
+	d.infof("\r\n%s\n\n", i18n.T("Downloading to folder: %s", d.config.OutputDir))
+
+	runAt := time.Now()
+	d.trackResume(state.ChannelID, state.ChannelName)
+	jobs, err := d.downloadSelectedVideos(ctx, videos, indices)
+	if err != nil {
+		return err
+	}
+	d.updateResumeState(state.ChannelID, state.ChannelName, jobs, d.collector.Failed())
+	d.recordHistory(state.ChannelID, state.ChannelName, jobs)
+	d.notify(ctx, state.ChannelName, len(indices))
+
+	if !d.config.NoManifest {
+		m := newManifest(state.ChannelID, state.ChannelName, runAt, d.config.OutputDir, videos, indices, jobs, d.collector)
+		if err := writeManifest(d.config.OutputDir, mergeManifest(d.config.OutputDir, m), d.config.FileMode); err != nil {
+			stream.Warnf(d.out, "failed to write manifest: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// updateResumeState stores the failed videos of a finished channel run for a later
+// resume, or removes the state once nothing is left to download.
+func (d *downloader) updateResumeState(channelID string, channelName string, jobs []downloadJob, failed []models.Video) {
+	path, err := resumeStatePath(channelID)
+	if err != nil {
+		stream.Warnf(d.out, "failed to update resume state: %v", err)
+
+		return
+	}
+
+	if len(failed) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			stream.Warnf(d.out, "failed to remove resume state: %v", err)
+		}
+
+		return
+	}
+
+	files := make(map[string]string, len(jobs))
+	for _, job := range jobs {
+		files[job.video.ID] = job.filename
+	}
+
+	pending := make([]pendingVideo, 0, len(failed))
+	for _, video := range failed {
+		pending = append(pending, pendingVideo{ID: video.ID, File: absPath(files[video.ID])})
+	}
+
+	state := resumeState{
+		SavedAt:     time.Now(),
+		ChannelID:   channelID,
+		ChannelName: channelName,
+		Pending:     pending,
+		Config:      newResumeConfig(d.config),
+	}
+
+	if err := saveResumeState(path, state); err != nil {
+		stream.Warnf(d.out, "failed to update resume state: %v", err)
+
+		return
+	}
+
+	fmt.Fprintln(d.out, i18n.T("Run '%s resume' to retry the %d unfinished videos", filepath.Base(os.Args[0]), len(pending)))
+}
+
+// Resume continues interrupted or partially failed channel runs without prompting for selection.
+// Resumes all pending runs when no channel IDs or URLs are given.
+func Resume(channels []string) error {
+	ctx, stop := newInterruptContext()
+	defer stop()
+
+	states, err := loadResumeStates(channels)
+	if err != nil {
+		return err
+	}
+
+	if len(states) == 0 {
+		fmt.Fprintln(stream.UI(), i18n.T("No interrupted downloads to resume"))
+
+		return nil
+	}
+
+	client, err := newClient(token.NewTokenManager())
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, state := range states {
+		if err := resumeLocked(ctx, newDownloader(state.Config.downloadConfig(), client), state); err != nil {
+			if ctx.Err() != nil || errors.Is(err, input.ErrUserAbort) {
+				return input.ErrUserAbort
+			}
+
+			errs = append(errs, fmt.Errorf("%w %s: %w", errFailedToResumeChannel, state.ChannelName, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// absPath returns path as absolute path, keeping empty and unresolvable paths as they are.
+func absPath(path string) string {
+	if path == "" {
+		return path
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+
+	return abs
+}
+
+// loadResumeStates reads the stored states for the given channels, or all states if none are given.
+func loadResumeStates(channels []string) ([]resumeState, error) {
+	stateDir, err := dir.CacheDir(resumeDirName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errFailedToLoadResumeState, err)
+	}
+
+	var paths []string
+
+	if len(channels) == 0 {
+		paths, err = filepath.Glob(filepath.Join(stateDir, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errFailedToLoadResumeState, err)
+		}
+	}
+
+	for _, channel := range channels {
+		id, _, err := extractIDAndType(channel)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errFailedToExtractType, err)
+		}
+
+		paths = append(paths, filepath.Join(stateDir, url.PathEscape(id)+".json"))
+	}
+
+	states := make([]resumeState, 0, len(paths))
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintln(stream.UI(), i18n.T("No interrupted download found for channel %s", strings.TrimSuffix(filepath.Base(path), ".json")))
+
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errFailedToLoadResumeState, err)
+		}
+
+		var state resumeState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", errFailedToLoadResumeState, path, err)
+		}
+
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+// resumeLocked resumes a channel run while holding the lock of its output directory.
+func resumeLocked(ctx context.Context, d *downloader, state resumeState) error {
+	unlock, err := d.lockOutput()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return d.resumeChannel(ctx, state)
+}
+
+// resumeStatePath returns the path of the state file for channelID.
+func resumeStatePath(channelID string) (string, error) {
+	stateDir, err := dir.CacheDir(resumeDirName)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(stateDir, url.PathEscape(channelID)+".json"), nil
+}
+
+// saveResumeState writes state to path, replacing the previous state at once so a run
+// killed while writing leaves a readable state.
+func saveResumeState(path string, state resumeState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode resume state: %w", err)
+	}
+
+	partial := path + ".part"
+
+	if err := os.WriteFile(partial, data, resumeStatePermissions); err != nil {
+		return fmt.Errorf("failed to write resume state: %w", err)
+	}
+
+	if err := os.Rename(partial, path); err != nil {
+		_ = os.Remove(partial)
+
+		return fmt.Errorf("failed to write resume state: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"switchtube-downloader/internal/helper/ui/input"
+	"switchtube-downloader/internal/i18n"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// countdownInterval is the time between two updates of the countdown.
+const countdownInterval = time.Second
+
+// waitForSchedule delays the start of the downloads until config.StartAt, showing a
+// countdown. Pressing enter starts the downloads right away.
+// Returns an error if ctx is cancelled while waiting.
+func (d *downloader) waitForSchedule(ctx context.Context) error {
+	if time.Until(d.config.StartAt) <= 0 {
+		return nil
+	}
+
+	startNow := make(chan struct{}, 1)
+	stopKeys := input.ListenKeys(func(key byte) {
+		if key == '\n' || key == '\r' {
+			select {
+			case startNow <- struct{}{}:
+			default:
+			}
+		}
+	})
+
+	defer stopKeys()
+
+	timer := time.NewTimer(time.Until(d.config.StartAt))
+	defer timer.Stop()
+
+	ticker := time.NewTicker(countdownInterval)
+	defer ticker.Stop()
+
+	d.printCountdown()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.infof("\n")
+
+			return fmt.Errorf("download cancelled: %w", ctx.Err())
+		case <-startNow:
+			d.infof("\n")
+
+			return nil
+		case <-timer.C:
+			d.infof("\n")
+
+			return nil
+		case <-ticker.C:
+			d.printCountdown()
+		}
+	}
+}
+
+// printCountdown updates the line showing the time left until the scheduled start.
+func (d *downloader) printCountdown() {
+	remaining := max(time.Until(d.config.StartAt), 0).Round(time.Second)
+
+	d.infof("\r%s%s", ansi.EraseLineRight, i18n.T(
+		"Starting at %s (in %s), press enter to start now",
+		d.config.StartAt.Format("15:04"), remaining,
+	))
+}
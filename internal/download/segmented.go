@@ -0,0 +1,173 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"switchtube-downloader/internal/helper/ui/stream"
+)
+
+// minSegmentSize is the smallest byte range worth a separate connection.
+const minSegmentSize = 1 << 20
+
+var (
+	errNotSegmentable     = errors.New("download cannot be segmented")
+	errSegmentIncomplete  = errors.New("segment ended early")
+	errSegmentNotPartial  = errors.New("server ignored range request")
+	errFailedToFetchRange = errors.New("failed to fetch byte range")
+)
+
+// countingWriter wraps an io.Writer and adds the bytes written to a shared counter.
+type countingWriter struct {
+	writer  io.Writer
+	counter *atomic.Int64
+}
+
+// Write implements io.Writer.
+func (cw countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.writer.Write(p)
+	cw.counter.Add(int64(n))
+
+	if err != nil {
+		return n, fmt.Errorf("%w: %w", errFailedToCopyVideoData, err)
+	}
+
+	return n, nil
+}
+
+// downloadSegment downloads the byte range [start, end] of fullURL into file at the same
+// offset, reconnecting from the last byte written if the range stalls.
+func (d *downloader) downloadSegment(ctx context.Context, fullURL string, file *os.File, start int64, end int64, written *atomic.Int64) error {
+	source, _, err := d.openResuming(ctx, d.requestRange(fullURL, start, end))
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToFetchRange, err)
+	}
+
+	defer func() {
+		if err := source.Close(); err != nil {
+			stream.Warnf(d.out, "failed to close response body: %v", err)
+		}
+	}()
+
+	size := end - start + 1
+	buffered, flush := d.newFileWriter(file, io.NewOffsetWriter(file, start))
+	dst := countingWriter{writer: buffered, counter: written}
+
+	n, err := io.CopyBuffer(dst, io.LimitReader(source, size), d.newCopyBuffer())
+	if err == nil {
+		err = flush()
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToCopyVideoData, err)
+	}
+
+	if n != size {
+		return fmt.Errorf("%w: got %d of %d bytes", errSegmentIncomplete, n, size)
+	}
+
+	return nil
+}
+
+// downloadSegmented downloads the job's video in up to d.config.Segments concurrent byte ranges
+// written directly to their offsets in file. Returns errNotSegmentable if the server does not
+// support ranges or the file is too small, so the caller can fall back to a single stream.
+func (d *downloader) downloadSegmented(ctx context.Context, job downloadJob, file *os.File, fullURL string, maxFilenameWidth int) error {
+	total, acceptsRanges := d.headVideo(ctx, fullURL)
+
+	segments := min(int64(d.config.Segments), total/minSegmentSize)
+	if !acceptsRanges || segments < 2 {
+		return errNotSegmentable
+	}
+
+	if err := file.Truncate(total); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToCopyVideoData, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var written atomic.Int64
+
+	done := make(chan struct{})
+	watched := make(chan struct{})
+
+	go func() {
+		defer close(watched)
+		d.watchProgress(job, total, maxFilenameWidth, written.Load, done)
+	}()
+
+	errs := make(chan error, segments)
+	segmentSize := total / segments
+
+	for i := range segments {
+		start := i * segmentSize
+
+		end := start + segmentSize - 1
+		if i == segments-1 {
+			end = total - 1
+		}
+
+		go func() {
+			errs <- d.downloadSegment(ctx, fullURL, file, start, end, &written)
+		}()
+	}
+
+	var firstErr error
+
+	for range segments {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+
+			cancel() // Stop the remaining segments
+		}
+	}
+
+	close(done)
+	<-watched
+
+	return firstErr
+}
+
+// headResult is the answer to a HEAD request for a video file.
+type headResult struct {
+	size   int64 // Size of the file in bytes
+	ranges bool  // Whether the server accepts byte range requests for it
+}
+
+// headVideo returns the size of the resource at fullURL (-1 if unknown) and whether
+// the server accepts byte range requests for it. Successful answers are reused for
+// later calls with the same URL, e.g. by the transfer after sorting by size.
+func (d *downloader) headVideo(ctx context.Context, fullURL string) (int64, bool) {
+	if cached, ok := d.heads.Load(fullURL); ok {
+		head, _ := cached.(headResult)
+
+		return head.size, head.ranges
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, fullURL, http.NoBody)
+	if err != nil {
+		return -1, false
+	}
+
+	resp, err := d.client.makeRequestWithReq(req)
+	if err != nil {
+		return -1, false
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return -1, false
+	}
+
+	head := headResult{size: resp.ContentLength, ranges: resp.Header.Get("Accept-Ranges") == "bytes"}
+	d.heads.Store(fullURL, head)
+
+	return head.size, head.ranges
+}
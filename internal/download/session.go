@@ -0,0 +1,199 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/token"
+)
+
+// callbackInterval is the minimum time between two progress callbacks of a video.
+const callbackInterval = 100 * time.Millisecond
+
+// Listing is a resolved video or channel ready for selection.
+type Listing struct {
+	ID        string         // Video or channel ID
+	Name      string         // Channel name or video title
+	Videos    []models.Video // Videos of the channel, or the single video
+	Channel   models.Channel // Metadata of the channel, empty for a video
+	IsChannel bool           // Whether the listing is a channel
+}
+
+// Session exposes the download engine to interactive frontends like the TUI.
+// Status messages are written to the given writer instead of stdout.
+type Session struct {
+	out    io.Writer
+	client *client
+	active *downloader // Downloader of the running download, if any
+	config models.DownloadConfig
+	mutex  sync.Mutex // Guards active
+}
+
+// NewSession creates a session that downloads with the given configuration.
+func NewSession(config models.DownloadConfig, out io.Writer) (*Session, error) {
+	client, err := newClient(token.NewTokenManager())
+	if err != nil {
+		return nil, err
+	}
+
+	client.out = out
+
+	return &Session{out: out, client: client, config: config}, nil
+}
+
+// Bump moves a pending video of the running download to the front of the queue,
+// so it is started by the next free worker. Returns false if the video is not pending.
+func (s *Session) Bump(videoID string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.active == nil {
+		return false
+	}
+
+	return s.active.queue.bump(videoID)
+}
+
+// Download downloads the videos at indices of listing with the given quality policy,
+// reporting progress through onProgress. Returns the failed videos.
+func (s *Session) Download(
+	ctx context.Context,
+	listing *Listing,
+	indices []int,
+	quality models.QualityPolicy,
+	onProgress ProgressFunc,
+) ([]models.Video, error) {
+	d := s.newDownloader()
+	d.config.Quality = quality
+	d.onProgress = onProgress
+
+	s.mutex.Lock()
+	s.active = d
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		s.active = nil
+		s.mutex.Unlock()
+	}()
+
+	runAt := time.Now()
+
+	if listing.IsChannel {
+		if err := d.useChannelFolder(listing.ID, listing.Name, listing.Videos); err != nil {
+			return nil, err
+		}
+	}
+
+	jobs, err := d.prepareDownloads(ctx, listing.Videos, indices)
+	if err != nil {
+		return nil, err
+	}
+
+	d.downloadVideosParallel(ctx, jobs, 0)
+
+	if listing.IsChannel {
+		d.finishChannelRun(ctx, listing.Channel, runAt, listing.Videos, indices, jobs)
+	} else {
+		d.recordHistory("", "", jobs)
+		d.notify(ctx, listing.Name, len(indices))
+	}
+
+	return d.collector.Failed(), nil
+}
+
+// Lookup resolves a video or channel ID/URL into a listing.
+// Plain IDs are tried as video first and as channel second.
+func (s *Session) Lookup(ctx context.Context, media string) (*Listing, error) {
+	id, downloadType, err := extractIDAndType(media)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errFailedToExtractType, err)
+	}
+
+	d := s.newDownloader()
+
+	if downloadType != channelType {
+		video, err := d.getVideoMetadata(ctx, id)
+		if err == nil {
+			return &Listing{ID: id, Name: video.Title, Videos: []models.Video{*video}, IsChannel: false}, nil
+		}
+
+		if downloadType == videoType {
+			return nil, fmt.Errorf("%w: %w", errFailedToGetVideoInfo, err)
+		}
+	}
+
+	channelInfo, err := d.getChannelMetadata(ctx, id)
+	if err != nil {
+		if downloadType == unknownType {
+			return nil, fmt.Errorf("%w: %w", errInvalidID, err)
+		}
+
+		return nil, fmt.Errorf("%w: %w", errFailedToGetChannelInfo, err)
+	}
+
+	videos, err := d.getChannelVideos(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errFailedToGetChannelVideos, err)
+	}
+
+	return &Listing{ID: id, Name: channelInfo.Name, Videos: videos, Channel: *channelInfo, IsChannel: true}, nil
+}
+
+// TogglePause pauses or resumes all transfers of the running download.
+// Returns whether the transfers are paused now.
+func (s *Session) TogglePause() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.active == nil {
+		return false
+	}
+
+	return s.active.pause.toggle()
+}
+
+// newDownloader creates a downloader writing status messages to the session's writer.
+func (s *Session) newDownloader() *downloader {
+	d := newDownloader(s.config, s.client)
+	d.out = s.out
+
+	return d
+}
+
+// callbackWriter wraps an io.Writer and reports the bytes written through a ProgressFunc.
+type callbackWriter struct {
+	lastReport time.Time    // Time of the last callback
+	writer     io.Writer    // Underlying destination writer
+	onProgress ProgressFunc // Callback receiving the progress
+	videoID    string       // Video being downloaded
+	total      int64        // Expected total bytes
+	written    int64        // Bytes written so far
+}
+
+// newCallbackWriter creates a callbackWriter for the given video.
+func newCallbackWriter(w io.Writer, videoID string, total int64, onProgress ProgressFunc) *callbackWriter {
+	return &callbackWriter{writer: w, onProgress: onProgress, videoID: videoID, total: total}
+}
+
+// Write implements io.Writer and reports progress at most every callbackInterval,
+// and always once the expected total is reached.
+func (cw *callbackWriter) Write(p []byte) (int, error) {
+	n, err := cw.writer.Write(p)
+	cw.written += int64(n)
+
+	if now := time.Now(); now.Sub(cw.lastReport) >= callbackInterval || cw.written == cw.total {
+		cw.lastReport = now
+		cw.onProgress(cw.videoID, cw.written, cw.total)
+	}
+
+	if err != nil {
+		return n, fmt.Errorf("%w: %w", errFailedToCopyVideoData, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,121 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"switchtube-downloader/internal/helper/ui/stream"
+)
+
+const (
+	// stagingPrefix starts the names of the folders in the staging folder, so only they are cleaned up.
+	stagingPrefix = "switchtube-"
+	// stagingMaxAge is the time after which the staged files of an interrupted run are deleted.
+	stagingMaxAge = 24 * time.Hour
+)
+
+var errFailedToMoveIntoPlace = errors.New("failed to move download into place")
+
+// stagingFile returns the path in the staging folder the job's video is downloaded to,
+// in a folder of its own named after the video ID.
+func (d *downloader) stagingFile(job downloadJob) string {
+	return filepath.Join(d.config.StagingDir, stagingPrefix+job.video.ID, filepath.Base(job.filename))
+}
+
+// replacementFile returns the file a video is downloaded to while its previous version
+// stays at filename, e.g. "name.new.mp4" for "name.mp4".
+func replacementFile(filename string) string {
+	ext := filepath.Ext(filename)
+
+	return strings.TrimSuffix(filename, ext) + ".new" + ext
+}
+
+// removeStaged deletes the folder of a staged file once the file was moved into place,
+// unless other files like the source of a failed remux are left in it.
+func removeStaged(staged string) {
+	_ = os.Remove(filepath.Dir(staged))
+}
+
+// isStagingFolder reports whether path is the folder of a video in a staging folder.
+func isStagingFolder(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), stagingPrefix)
+}
+
+// moveIntoPlace moves the staged file to target. Across file systems it is copied next
+// to target first and renamed once complete, so target never holds a partial file.
+func (d *downloader) moveIntoPlace(staged string, target string) error {
+	if err := os.Rename(staged, target); err == nil {
+		return nil
+	}
+
+	partial := target + ".part"
+
+	if err := d.copyFile(staged, partial, time.Time{}); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToMoveIntoPlace, err)
+	}
+
+	if err := os.Rename(partial, target); err != nil {
+		_ = os.Remove(partial)
+
+		return fmt.Errorf("%w: %w", errFailedToMoveIntoPlace, err)
+	}
+
+	if err := os.Remove(staged); err != nil {
+		stream.Warnf(d.out, "failed to delete %s: %v", staged, err)
+	}
+
+	return nil
+}
+
+// cleanStaging deletes the folders that interrupted runs left in the staging folder,
+// those without any file modified for stagingMaxAge.
+func cleanStaging(folder string, out io.Writer) {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			stream.Warnf(out, "failed to clean up staging folder: %v", err)
+		}
+
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !isStagingFolder(entry.Name()) {
+			continue
+		}
+
+		path := filepath.Join(folder, entry.Name())
+		if time.Since(lastModified(path)) < stagingMaxAge {
+			continue
+		}
+
+		if err := os.RemoveAll(path); err != nil {
+			stream.Warnf(out, "failed to delete %s: %v", entry.Name(), err)
+		}
+	}
+}
+
+// lastModified returns the latest modification time of the folder and the files in it.
+func lastModified(folder string) time.Time {
+	var latest time.Time
+
+	_ = filepath.WalkDir(folder, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil //nolint:nilerr // Unreadable entries do not keep the folder alive
+		}
+
+		if info, err := entry.Info(); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+
+		return nil
+	})
+
+	return latest
+}
@@ -0,0 +1,282 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"switchtube-downloader/internal/helper/ui/stream"
+)
+
+// Reconnect behavior for video transfers that stop receiving data.
+const (
+	DefaultStallTimeout = 30 * time.Second // Time without data after which the connection is aborted
+	maxReconnects       = 5                // Reconnects in a row without data before giving up
+)
+
+// stallTimeout is the time a video transfer may receive no data before it is
+// reconnected, 0 to wait forever. Set by SetTimeouts.
+//
+//nolint:gochecknoglobals // Configured once at startup by the global flags
+var stallTimeout = DefaultStallTimeout
+
+var (
+	errStalled          = errors.New("no data received")
+	errTruncated        = errors.New("connection closed before the end of the video")
+	errFailedToResume   = errors.New("failed to resume stalled download")
+	errUnexpectedOffset = errors.New("server resumed at the wrong offset")
+)
+
+// openFunc opens a video transfer from offset bytes after its start on. Returns the data
+// and the number of bytes remaining (-1 if unknown). The transfer ends when ctx is done.
+type openFunc func(ctx context.Context, offset int64) (io.ReadCloser, int64, error)
+
+// resumingReader reads a video transfer. If no data arrives for stallTimeout or the
+// transfer ends before all announced bytes arrived, the transfer is opened again from the
+// last byte read, so the caller sees one uninterrupted stream. Reads wait while the
+// downloader is paused, and time spent paused does not count as stalled.
+type resumingReader struct {
+	ctx      context.Context //nolint:containedctx // Read has no context parameter
+	reqCtx   context.Context //nolint:containedctx // Cancelled with errStalled when the transfer stalls
+	cancel   context.CancelCauseFunc
+	body     io.ReadCloser
+	timer    *time.Timer // Aborts the transfer if a read takes longer than stallTimeout, nil if disabled
+	d        *downloader
+	open     openFunc
+	offset   int64     // Bytes read so far
+	total    int64     // Size of the transfer from the first opening, -1 if unknown
+	attempts int       // Reconnects since data last arrived
+	armedAt  time.Time // Start of the current read, zero while not reading
+	mutex    sync.Mutex
+}
+
+// openVideoStream requests the video at fullURL and returns a reader of its body that
+// reconnects on stalls, together with the size of the video (-1 if unknown).
+func (d *downloader) openVideoStream(ctx context.Context, fullURL string) (*resumingReader, int64, error) {
+	return d.openResuming(ctx, d.requestRange(fullURL, 0, -1))
+}
+
+// openResuming opens a transfer with open and returns a reader of it that reopens it on
+// stalls, together with the size of the transfer (-1 if unknown).
+func (d *downloader) openResuming(ctx context.Context, open openFunc) (*resumingReader, int64, error) {
+	r := &resumingReader{ctx: ctx, d: d, open: open}
+
+	total, err := r.connect()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	r.total = total
+
+	return r, r.total, nil
+}
+
+// requestRange returns an openFunc requesting the bytes from start to end of fullURL,
+// to the end of the video if end is negative.
+func (d *downloader) requestRange(fullURL string, start int64, end int64) openFunc {
+	return func(ctx context.Context, offset int64) (io.ReadCloser, int64, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, http.NoBody)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %w", errFailedToFetchVideoStream, err)
+		}
+
+		from := start + offset
+		wantStatus := http.StatusOK
+
+		switch {
+		case end >= 0:
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", from, end))
+
+			wantStatus = http.StatusPartialContent
+		case from > 0:
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", from))
+
+			wantStatus = http.StatusPartialContent
+		}
+
+		resp, err := d.client.makeRequestWithReq(req)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %w", errFailedToFetchVideoStream, err)
+		}
+
+		if resp.StatusCode != wantStatus {
+			_ = resp.Body.Close()
+
+			if resp.StatusCode == http.StatusOK {
+				return nil, 0, errSegmentNotPartial
+			}
+
+			return nil, 0, statusError(req.URL, resp.StatusCode)
+		}
+
+		if wantStatus == http.StatusPartialContent && rangeStart(resp.Header.Get("Content-Range")) != from {
+			_ = resp.Body.Close()
+
+			return nil, 0, fmt.Errorf("%w: %s", errUnexpectedOffset, resp.Header.Get("Content-Range"))
+		}
+
+		return resp.Body, resp.ContentLength, nil
+	}
+}
+
+// Close closes the current transfer.
+func (r *resumingReader) Close() error {
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+
+	defer r.cancel(nil)
+
+	return r.body.Close() //nolint:wrapcheck // Reported as is by the caller
+}
+
+// Read implements io.Reader.
+func (r *resumingReader) Read(p []byte) (int, error) {
+	for {
+		if err := r.d.pause.wait(r.ctx); err != nil {
+			return 0, err
+		}
+
+		r.arm(true)
+		n, err := r.body.Read(p)
+		r.arm(false)
+
+		r.offset += int64(n)
+		if n > 0 {
+			r.attempts = 0
+		}
+
+		stalled := errors.Is(context.Cause(r.reqCtx), errStalled)
+		truncated := r.total > 0 && r.offset < r.total && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF))
+
+		if err == nil || r.ctx.Err() != nil || !stalled && !truncated {
+			return n, err //nolint:wrapcheck // Read errors like io.EOF must stay unwrapped
+		}
+
+		cause := errStalled
+		if !stalled {
+			cause = errTruncated
+		}
+
+		if r.attempts == maxReconnects {
+			return n, fmt.Errorf("%w at byte %d, gave up after %d reconnects", cause, r.offset, maxReconnects)
+		}
+
+		r.attempts++
+
+		if stalled {
+			stream.Warnf(r.d.out, "no data received for %s, resuming from byte %d", stallTimeout, r.offset)
+		} else {
+			stream.Warnf(r.d.out, "connection closed after %d of %d bytes, resuming", r.offset, r.total)
+		}
+
+		if err := r.reconnect(); err != nil {
+			return n, fmt.Errorf("%w: %w", errFailedToResume, err)
+		}
+
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// connect opens the transfer from the current offset on and makes it the current one.
+// Returns the number of bytes remaining (-1 if unknown).
+func (r *resumingReader) connect() (int64, error) {
+	reqCtx, cancel := context.WithCancelCause(r.ctx)
+
+	body, remaining, err := r.open(reqCtx, r.offset)
+	if err != nil {
+		cancel(nil)
+
+		return 0, err
+	}
+
+	r.reqCtx, r.cancel, r.body = reqCtx, cancel, body
+	if stallTimeout > 0 {
+		// Armed by Read for the duration of every read
+		r.timer = time.AfterFunc(stallTimeout, func() { r.expire(cancel) })
+		r.timer.Stop()
+	}
+
+	return remaining, nil
+}
+
+// arm starts the stall timer for a read if reading, and stops it after the read otherwise.
+func (r *resumingReader) arm(reading bool) {
+	if r.timer == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if reading {
+		r.armedAt = time.Now()
+		r.timer.Reset(stallTimeout)
+	} else {
+		r.armedAt = time.Time{}
+		r.timer.Stop()
+	}
+}
+
+// expire aborts the transfer with cancel once a read received no data for stallTimeout
+// while not paused. If the downloader was paused meanwhile, the timer restarts from the
+// moment it resumed.
+func (r *resumingReader) expire(cancel context.CancelCauseFunc) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.armedAt.IsZero() {
+		return // The read ended meanwhile
+	}
+
+	paused, resumedAt := r.d.pause.state()
+
+	switch {
+	case paused:
+		r.timer.Reset(stallTimeout)
+	case resumedAt.After(r.armedAt) && time.Since(resumedAt) < stallTimeout:
+		r.timer.Reset(stallTimeout - time.Since(resumedAt))
+	default:
+		cancel(errStalled)
+	}
+}
+
+// reconnect aborts the current transfer and resumes from the current offset.
+func (r *resumingReader) reconnect() error {
+	if err := r.Close(); err != nil {
+		stream.Warnf(r.d.out, "failed to close response body: %v", err)
+	}
+
+	_, err := r.connect()
+
+	return err
+}
+
+// rangeStart returns the first byte of a Content-Range header (e.g. "bytes 100-199/200"),
+// or -1 if it cannot be parsed.
+func rangeStart(contentRange string) int64 {
+	spec, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return -1
+	}
+
+	start, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return -1
+	}
+
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+
+	return n
+}
@@ -0,0 +1,32 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"switchtube-downloader/internal/models"
+)
+
+var errStdoutRequiresVideo = errors.New("writing to stdout requires a single video, not a channel")
+
+// streamVideo writes the variant of video to stdout instead of a file, e.g. to pipe it
+// into a player. Segmented and aria2c transfers need a seekable file, so the video is
+// transferred in one piece by the built-in downloader or curl, and a pipe cannot be
+// flushed to disk.
+func (d *downloader) streamVideo(ctx context.Context, video models.Video, variant models.Variant) error {
+	if d.config.ExternalDownloader == models.ExternalAria2c {
+		d.config.ExternalDownloader = models.ExternalNone
+	}
+
+	d.config.Segments = 1
+	d.config.Fsync = models.FsyncNever
+
+	if err := d.waitForSchedule(ctx); err != nil {
+		return err
+	}
+
+	job := downloadJob{video: video, variant: variant, filename: os.Stdout.Name()}
+
+	return d.downloadVideoStream(ctx, job, os.Stdout, 0)
+}
@@ -0,0 +1,157 @@
+package download
+
+import (
+	"cmp"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"switchtube-downloader/internal/helper/dir"
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/history"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/models"
+)
+
+// trashPermissions are the permissions of a trash folder created for removed videos.
+const trashPermissions = 0o755
+
+// removeDeleted deletes the local files of videos of a channel that no longer exist on
+// SwitchTube, or moves them into the trash folder if one is set. Only files inside the
+// output directory are touched, so other copies of the channel are left alone.
+func (d *downloader) removeDeleted(channelID string, videos []models.Video) {
+	entries, err := history.Load()
+	if err != nil {
+		stream.Warnf(d.out, "failed to load history: %v", err)
+
+		return
+	}
+
+	present := make(map[string]bool, len(videos))
+	for _, video := range videos {
+		present[video.ID] = true
+	}
+
+	folder := absPath(cmp.Or(d.config.OutputDir, "."))
+
+	var removed []string
+
+	for _, entry := range history.LatestByVideo(entries) {
+		if entry.ChannelID != channelID || present[entry.VideoID] {
+			continue
+		}
+
+		rel, err := filepath.Rel(folder, entry.File)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		if _, err := os.Stat(entry.File); err == nil {
+			removed = append(removed, entry.File)
+		}
+	}
+
+	slices.Sort(removed)
+
+	for _, file := range removed {
+		if d.config.TrashDir == "" {
+			if err := os.Remove(file); err != nil {
+				stream.Warnf(d.out, "failed to delete %s: %v", file, err)
+
+				continue
+			}
+
+			d.infof("%s\n", i18n.T("Deleted %s, it was removed from the channel", file))
+
+			continue
+		}
+
+		target, err := moveToTrash(file, d.config.TrashDir)
+		if err != nil {
+			stream.Warnf(d.out, "failed to move %s to the trash folder: %v", file, err)
+
+			continue
+		}
+
+		d.infof("%s\n", i18n.T("Moved %s to %s, it was removed from the channel", file, target))
+	}
+}
+
+// syncedFile returns the local file of a video that was downloaded before, recognized by
+// its video ID in the history instead of its filename. If the video was renamed on
+// SwitchTube since, the local file is moved to filename when RenameMoved is set.
+// Returns false if the video was never downloaded, its file is gone or inside an archive.
+func (d *downloader) syncedFile(video models.Video, filename string) (string, bool) {
+	entry, ok := d.lastDownload(video.ID)
+	if !ok || entry.Archive != "" {
+		return "", false
+	}
+
+	if _, err := os.Stat(entry.File); err != nil {
+		return "", false
+	}
+
+	target := absPath(filename)
+	if entry.File == target {
+		return entry.File, true
+	}
+
+	if !d.config.RenameMoved {
+		d.infof("%s\n", i18n.T("%s was renamed, keeping it as %s", video.Title, entry.File))
+
+		return entry.File, true
+	}
+
+	if _, err := os.Stat(target); err == nil {
+		stream.Warnf(d.out, "not renaming %s, %s already exists", entry.File, target)
+
+		return entry.File, true
+	}
+
+	if err := os.Rename(entry.File, target); err != nil {
+		stream.Warnf(d.out, "failed to rename %s: %v", entry.File, err)
+
+		return entry.File, true
+	}
+
+	d.infof("%s\n", i18n.T("Renamed %s to %s", entry.File, target))
+
+	entry.File = target
+	entry.Title = video.Title
+	entry.Episode = video.Episode
+	d.downloaded[video.ID] = entry
+
+	if err := history.Append(entry); err != nil {
+		stream.Warnf(d.out, "failed to update history: %v", err)
+	}
+
+	return target, true
+}
+
+// moveToTrash moves file into trashDir, appending a counter if a file of that name is
+// already in the trash. Returns the new path.
+func moveToTrash(file string, trashDir string) (string, error) {
+	if err := os.MkdirAll(trashDir, trashPermissions); err != nil {
+		return "", err //nolint:wrapcheck // Reported as warning by the caller
+	}
+
+	target := filepath.Join(trashDir, filepath.Base(file))
+	taken := make(map[string]bool)
+
+	for candidate := target; ; candidate = dir.NextFreeFilename(target, taken) {
+		if _, err := os.Stat(candidate); err != nil {
+			target = candidate
+
+			break
+		}
+
+		taken[candidate] = true
+	}
+
+	if err := os.Rename(file, target); err != nil {
+		return "", err //nolint:wrapcheck // Reported as warning by the caller
+	}
+
+	return target, nil
+}
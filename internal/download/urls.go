@@ -0,0 +1,79 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"switchtube-downloader/internal/auth"
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/token"
+)
+
+var errURLRequiresVideo = errors.New("URLs can only be printed for videos, not channels")
+
+// PrintURLs prints the direct download URL of each given video to stdout, e.g. for a
+// download manager. With allVariants every variant is listed with its media type,
+// otherwise only the variant picked by quality. SwitchTube has no signed URLs, so
+// fetching them requires the auth headers, which are printed first with header.
+func PrintURLs(media []string, quality models.QualityPolicy, allVariants bool, header bool) error {
+	ctx, stop := newInterruptContext()
+	defer stop()
+
+	client, err := newClient(token.NewTokenManager())
+	if err != nil {
+		return err
+	}
+
+	d := newDownloader(models.DownloadConfig{Quality: quality}, client)
+
+	if header {
+		credentials, err := client.credentials(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, line := range auth.Lines(credentials) {
+			fmt.Fprintln(os.Stdout, line)
+		}
+	}
+
+	for _, m := range media {
+		id, downloadType, err := extractIDAndType(m)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errFailedToExtractType, err)
+		}
+
+		if downloadType == channelType {
+			return fmt.Errorf("%w: %s", errURLRequiresVideo, m)
+		}
+
+		variants, err := d.getVideoVariants(ctx, id)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errFailedToGetVideoVariants, err)
+		}
+
+		if len(variants) == 0 {
+			return fmt.Errorf("%w: %s", errNoVariantsFound, m)
+		}
+
+		if !allVariants {
+			variants = []models.Variant{d.pickVariant(ctx, variants)}
+		}
+
+		for _, variant := range variants {
+			fullURL, err := videoURL(variant.Path)
+			if err != nil {
+				return err
+			}
+
+			if allVariants {
+				fmt.Fprintf(os.Stdout, "%s\t%s\n", variant.MediaType, fullURL)
+			} else {
+				fmt.Fprintln(os.Stdout, fullURL)
+			}
+		}
+	}
+
+	return nil
+}
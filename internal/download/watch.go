@@ -0,0 +1,253 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"switchtube-downloader/internal/helper/ui/input"
+	"switchtube-downloader/internal/helper/ui/progress"
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/history"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/metrics"
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/systemd"
+	"switchtube-downloader/internal/token"
+)
+
+var errNotAChannel = errors.New("not a channel")
+
+// watcher polls channels and downloads the videos that are not in the history yet.
+type watcher struct {
+	out      io.Writer // Destination of status messages and warnings
+	client   *client
+	baseline map[string]map[string]bool // Videos present when watching started, by channel ID
+	config   models.DownloadConfig
+	backfill bool // Whether videos published before watching started are downloaded too
+}
+
+// Watch polls the given channels every interval and downloads the videos that are not
+// in the download history yet. Unless backfill is set, the videos present when watching
+// starts are considered known, so only videos published from then on are downloaded.
+// Runs until interrupted.
+func Watch(config models.DownloadConfig, channels []string, interval time.Duration, backfill bool) error {
+	ctx, stop := newInterruptContext()
+	defer stop()
+
+	channelIDs := make([]string, 0, len(channels))
+
+	for _, channel := range channels {
+		id, downloadType, err := extractIDAndType(channel)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errFailedToExtractType, err)
+		}
+
+		if downloadType == videoType {
+			return fmt.Errorf("%w: %s", errNotAChannel, channel)
+		}
+
+		channelIDs = append(channelIDs, id)
+	}
+
+	client, err := newClient(token.NewTokenManager())
+	if err != nil {
+		return err
+	}
+
+	if err := checkRclone(config); err != nil {
+		return err
+	}
+
+	// Never prompt while unattended, existing files are kept
+	config.All = true
+	config.Skip = !config.Force
+
+	unlock, err := newDownloader(config, client).lockOutput()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	w := &watcher{
+		out:      progress.Writer(),
+		client:   client,
+		baseline: make(map[string]map[string]bool),
+		config:   config,
+		backfill: backfill,
+	}
+
+	if !backfill {
+		w.seed(ctx, channelIDs)
+	}
+
+	if err := systemd.Ready(fmt.Sprintf("Watching %d channels", len(channelIDs))); err != nil {
+		stream.Warnf(w.out, "%v", err)
+	}
+	defer systemd.Stopping()
+
+	for {
+		systemd.Status(fmt.Sprintf("Checking %d channels", len(channelIDs)))
+
+		for _, channelID := range channelIDs {
+			systemd.Watchdog()
+
+			err := w.check(ctx, channelID)
+			if ctx.Err() != nil || errors.Is(err, input.ErrUserAbort) {
+				return input.ErrUserAbort
+			}
+
+			metrics.CheckFinished(err)
+
+			if err != nil {
+				stream.Warnf(w.out, "%s", i18n.T("failed to check channel %s: %v", channelID, err))
+			}
+		}
+
+		next := time.Now().Add(interval).Format(time.TimeOnly)
+		fmt.Fprintln(w.out, i18n.T("Next check at %s", next))
+		systemd.Status("Next check at " + next)
+
+		if !sleep(ctx, interval) {
+			return input.ErrUserAbort
+		}
+	}
+}
+
+// sleep waits for d, pinging the watchdog of the service manager meanwhile if it has
+// one. Returns false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	var pings <-chan time.Time
+
+	if interval, ok := systemd.WatchdogInterval(); ok {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		pings = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
+		case <-pings:
+			systemd.Watchdog()
+		}
+	}
+}
+
+// seed records the videos the channels have when watching starts as known. A channel
+// whose videos cannot be listed now is seeded at its first successful check instead.
+func (w *watcher) seed(ctx context.Context, channelIDs []string) {
+	d := newDownloader(w.config, w.client)
+
+	for _, channelID := range channelIDs {
+		videos, err := d.getChannelVideos(ctx, channelID)
+		if err != nil {
+			continue
+		}
+
+		w.baseline[channelID] = videoIDs(videos)
+	}
+}
+
+// videoIDs returns the IDs of videos as set.
+func videoIDs(videos []models.Video) map[string]bool {
+	ids := make(map[string]bool, len(videos))
+	for _, video := range videos {
+		ids[video.ID] = true
+	}
+
+	return ids
+}
+
+// check downloads the new videos of a channel.
+func (w *watcher) check(ctx context.Context, channelID string) error {
+	runAt := time.Now()
+	d := newDownloader(w.config, w.client)
+	d.appendManifest = true
+
+	channelInfo, err := d.getChannelMetadata(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToGetChannelInfo, err)
+	}
+
+	videos, err := d.getChannelVideos(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToGetChannelVideos, err)
+	}
+
+	known, err := w.knownVideos(channelID, videos)
+	if err != nil {
+		return err
+	}
+
+	var indices []int
+
+	for i, video := range videos {
+		if !known[video.ID] {
+			indices = append(indices, i)
+		}
+	}
+
+	if len(indices) == 0 {
+		d.infof("%s\n", i18n.T("No new videos in channel: %s", channelInfo.Name))
+
+		return nil
+	}
+
+	d.infof("%s\n", i18n.T("Found %d new videos in channel: %s", len(indices), channelInfo.Name))
+
+	if err := d.useChannelFolder(channelID, channelInfo.Name, videos); err != nil {
+		return err
+	}
+
+	jobs, err := d.downloadSelectedVideos(ctx, videos, indices)
+	if err != nil {
+		return err
+	}
+
+	d.finishChannelRun(ctx, *channelInfo, runAt, videos, indices, jobs)
+
+	return nil
+}
+
+// knownVideos returns the IDs of the videos of a channel that are not downloaded again:
+// those in the history and, unless backfilling, those present when watching started.
+func (w *watcher) knownVideos(channelID string, videos []models.Video) (map[string]bool, error) {
+	entries, err := history.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+
+	for _, entry := range entries {
+		if entry.ChannelID == channelID {
+			known[entry.VideoID] = true
+		}
+	}
+
+	baseline, ok := w.baseline[channelID]
+	if !ok {
+		baseline = make(map[string]bool)
+		if !w.backfill {
+			baseline = videoIDs(videos)
+		}
+
+		w.baseline[channelID] = baseline
+	}
+
+	for id := range baseline {
+		known[id] = true
+	}
+
+	return known, nil
+}
@@ -0,0 +1,36 @@
+// Package browser opens web pages in the default browser of the user.
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+var errFailedToOpenBrowser = errors.New("failed to open browser")
+
+// Open opens url in the default browser and returns without waiting for it to close.
+func Open(url string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToOpenBrowser, err)
+	}
+
+	// Reap the launcher in the background, the browser itself keeps running
+	go func() {
+		_ = cmd.Wait()
+	}()
+
+	return nil
+}
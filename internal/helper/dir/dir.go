@@ -2,37 +2,61 @@
 package dir
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"switchtube-downloader/internal/helper/ui/input"
+	"switchtube-downloader/internal/i18n"
 	"switchtube-downloader/internal/models"
 )
 
 const (
+	// appName names the per-user application directories.
+	appName = "switchtube-downloader"
 	// File and directory permissions.
 	dirPermissions = 0o755
+	// filePermissions are the permissions of created files without a configured mode,
+	// narrowed by the umask like those of os.Create.
+	filePermissions = 0o666
 	// maxFilenameLen is the maximum filename length on most filesystems.
 	maxFilenameLen = 255
+	// publishDateXattr is the extended attribute holding the video's publish date.
+	publishDateXattr = "user.switchtube.published_at"
 )
 
 var (
 	// ErrFailedToCreateFile is returned when file creation fails.
 	ErrFailedToCreateFile = errors.New("failed to create file")
 
+	errFailedToBackUpFile   = errors.New("failed to back up file")
 	errFailedToCreateFolder = errors.New("failed to create folder")
+	errFailedToSetMode      = errors.New("failed to set permissions")
 )
 
+// reservedNames are device names Windows refuses as file or folder names, even with an extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
 // CreateFilename creates a sanitized filename from video title and media type.
 // Returns the full file path with proper extension, optionally prefixed with episode number.
+// The extension is that of the container videos are remuxed into, if configured.
 func CreateFilename(title string, mediaType string, episodeNr string, config models.DownloadConfig) string {
-	// Extract extension from media type (e.g., "video/mp4" -> "mp4")
-	_, extension, found := strings.Cut(mediaType, "/")
-	if !found {
-		extension = "mp4" // fallback
+	extension := Extension(mediaType)
+	if config.Remux != models.ContainerSource {
+		extension = string(config.Remux)
 	}
 
 	sanitizedTitle := sanitizeFilename(title)
@@ -40,6 +64,10 @@ func CreateFilename(title string, mediaType string, episodeNr string, config mod
 
 	var filename string
 
+	if config.EpisodePad > 0 {
+		episodeNr = models.PadEpisode(episodeNr, config.EpisodePad)
+	}
+
 	// Add episode prefix if episode flag is set
 	if config.UseEpisode && episodeNr != "" {
 		filename = fmt.Sprintf("%s_%s.%s", episodeNr, sanitizedTitle, extension)
@@ -47,7 +75,7 @@ func CreateFilename(title string, mediaType string, episodeNr string, config mod
 		filename = fmt.Sprintf("%s.%s", sanitizedTitle, extension)
 	}
 
-	filename = truncateFilename(filename, maxFilenameLen)
+	filename = sanitizePathComponent(filename)
 
 	if config.OutputDir != "" {
 		filename = filepath.Join(config.OutputDir, filename)
@@ -56,50 +84,283 @@ func CreateFilename(title string, mediaType string, episodeNr string, config mod
 	return filepath.Clean(filename)
 }
 
-// OverwriteVideoIfExists checks if a video file exists and prompts to overwrite it.
-// Returns true if the file should be overwritten or does not exist.
-func OverwriteVideoIfExists(filename string, config models.DownloadConfig) bool {
-	if !config.Force {
-		if _, err := os.Stat(filename); err == nil {
-			if config.Skip || !input.Confirm("File %s already exists. Overwrite?", filename) {
-				return false
-			}
+// Extension returns the file extension of a media type without dot, e.g. "mp4" for
+// "video/mp4", falling back to "mp4".
+func Extension(mediaType string) string {
+	_, extension, found := strings.Cut(mediaType, "/")
+	if !found {
+		return "mp4"
+	}
+
+	return extension
+}
+
+// NextFreeFilename returns filename with a "_(n)" counter appended before the extension,
+// picking the lowest n >= 2 that is not already taken.
+func NextFreeFilename(filename string, taken map[string]bool) string {
+	for n := 2; ; n++ {
+		candidate := SuffixedFilename(filename, fmt.Sprintf("(%d)", n))
+		if !taken[candidate] {
+			return candidate
 		}
 	}
+}
+
+// SuffixedFilename returns filename with "_" and suffix appended before the extension,
+// e.g. "Exercise_abc123.mp4" for "Exercise.mp4" and suffix "abc123".
+func SuffixedFilename(filename string, suffix string) string {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filepath.Base(filename), ext)
 
-	return true
+	// Shorten the base name rather than cutting off the suffix
+	suffix = "_" + sanitizeFilename(suffix) + ext
+	name := truncateBytes(base, maxFilenameLen-len(suffix))
+
+	return filepath.Join(filepath.Dir(filename), name+suffix)
 }
 
-// CreateVideoFile creates a video file on disk with the specified filename.
-// Creates parent directories if needed. Returns file handle and error if any.
-func CreateVideoFile(filename string) (*os.File, error) {
-	if err := os.MkdirAll(filepath.Dir(filename), dirPermissions); err != nil {
-		return nil, fmt.Errorf("%w: %w", errFailedToCreateFolder, err)
+// BackupFile renames filename to a numbered backup next to it, e.g. "name.1.mp4" for
+// "name.mp4", picking the lowest number not taken, so the previous version is kept when
+// the file is downloaded again. Returns the backup path, or an empty string if filename
+// does not exist.
+func BackupFile(filename string) (string, error) {
+	if _, err := os.Lstat(filename); err != nil {
+		return "", nil //nolint:nilerr // Nothing to back up
 	}
 
-	fd, err := os.Create(filename)
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filepath.Base(filename), ext)
+
+	for n := 1; ; n++ {
+		// Shorten the base name rather than cutting off the number
+		suffix := fmt.Sprintf(".%d%s", n, ext)
+		backup := filepath.Join(filepath.Dir(filename), truncateBytes(base, maxFilenameLen-len(suffix))+suffix)
+
+		if _, err := os.Lstat(backup); err == nil {
+			continue
+		}
+
+		if err := os.Rename(filename, backup); err != nil {
+			return "", fmt.Errorf("%w: %w", errFailedToBackUpFile, err)
+		}
+
+		return backup, nil
+	}
+}
+
+// OverwriteVideoIfExists checks if a video file exists and prompts to overwrite it.
+// Returns true if the file should be overwritten or does not exist, and with Backup,
+// where it is backed up once the new version is complete. Answering "all" or "skip all"
+// sets Force or Skip on config, so the choice applies to the rest of the run. Returns
+// input.ErrUserAbort if the user quits, and an error if the file exists but prompts are
+// disabled and neither skip nor force is set.
+func OverwriteVideoIfExists(filename string, config *models.DownloadConfig) (bool, error) {
+	if config.Force || config.Backup {
+		return true, nil
+	}
+
+	if _, err := os.Stat(filename); err != nil {
+		return true, nil
+	}
+
+	if config.Skip {
+		return false, nil
+	}
+
+	choice, err := input.ConfirmOverwrite(filename)
+	if err != nil {
+		return false, fmt.Errorf("%w (%s)", err, i18n.T("use --skip or --force"))
+	}
+
+	switch choice {
+	case input.OverwriteYes:
+		return true, nil
+	case input.OverwriteAll:
+		config.Force = true
+
+		return true, nil
+	case input.OverwriteNone:
+		config.Skip = true
+	case input.OverwriteQuit:
+		return false, input.ErrUserAbort
+	case input.OverwriteNo:
+	}
+
+	return false, nil
+}
+
+// CreateVideoFile creates a video file on disk with the specified filename and the file
+// mode of config, which it has from the start instead of only once the file exists.
+// Creates parent directories if needed. An existing file is replaced instead of
+// truncated, see RemoveExisting. Returns file handle and error if any.
+func CreateVideoFile(filename string, config models.DownloadConfig) (*os.File, error) {
+	if err := CreateParentDir(filename, config); err != nil {
+		return nil, err
+	}
+
+	if err := RemoveExisting(filename); err != nil {
+		return nil, err
+	}
+
+	mode := cmp.Or(config.FileMode, filePermissions)
+
+	fd, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrFailedToCreateFile, err)
 	}
 
+	// The umask may have narrowed the configured mode
+	if config.FileMode != 0 {
+		if err := fd.Chmod(config.FileMode); err != nil {
+			_ = fd.Close()
+
+			return nil, fmt.Errorf("%w: %w", errFailedToSetMode, err)
+		}
+	}
+
 	return fd, nil
 }
 
-// CreateChannelFolder creates a folder for the channel using its name.
-// Returns the created folder path and error if any.
-func CreateChannelFolder(channelName string, config models.DownloadConfig) (string, error) {
-	folderName := strings.ReplaceAll(channelName, "/", " - ")
-	folderName = filepath.Clean(folderName)
+// RemoveExisting deletes filename if it exists, so a new file is written in its place
+// instead of into it. Truncating a hardlink or symbolic link would overwrite the file it
+// shares its data with, e.g. the copy of a video linked from another folder.
+func RemoveExisting(filename string) error {
+	if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %w", ErrFailedToCreateFile, err)
+	}
 
-	if config.OutputDir != "" {
-		folderName = filepath.Join(config.OutputDir, folderName)
+	return nil
+}
+
+// ApplyFileMode sets the permissions of a written file to mode, so they do not depend
+// on the umask. Does nothing if mode is 0.
+func ApplyFileMode(filename string, mode os.FileMode) error {
+	if mode == 0 {
+		return nil
+	}
+
+	if err := os.Chmod(filename, mode); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToSetMode, err)
+	}
+
+	return nil
+}
+
+// ApplyPublishDate sets the file's modification time to the video's publish date and
+// records the date in an extended attribute, so sorted listings reflect lecture order.
+func ApplyPublishDate(filename string, published time.Time) error {
+	if err := os.Chtimes(filename, time.Time{}, published); err != nil {
+		return fmt.Errorf("failed to set modification time: %w", err)
 	}
 
-	if err := os.MkdirAll(folderName, dirPermissions); err != nil {
+	return setXattr(filename, publishDateXattr, published.UTC().Format(time.RFC3339))
+}
+
+// CacheDir returns the per-user cache directory sub of the application, creating it if needed.
+func CacheDir(sub string) (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+
+	path := filepath.Join(base, appName, sub)
+	if err := os.MkdirAll(path, dirPermissions); err != nil {
 		return "", fmt.Errorf("%w: %w", errFailedToCreateFolder, err)
 	}
 
-	return folderName, nil
+	return path, nil
+}
+
+// ConfigDir returns the per-user configuration directory of the application, creating it if needed.
+// It holds data that must survive cache cleanups, like the download history.
+func ConfigDir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+
+	path := filepath.Join(base, appName)
+	if err := os.MkdirAll(path, dirPermissions); err != nil {
+		return "", fmt.Errorf("%w: %w", errFailedToCreateFolder, err)
+	}
+
+	return path, nil
+}
+
+// CreateParentDir creates the directory filename will be written to with the folder mode
+// of config, if needed.
+func CreateParentDir(filename string, config models.DownloadConfig) error {
+	return makeDirs(filepath.Dir(filename), config.DirMode)
+}
+
+// ChannelFolderPath returns the folder for a channel, named by the folder template of
+// config with the placeholders {channel}, {id} and {year} replaced by the given values.
+// A "/" in the template nests folders, every folder name is sanitized. Nothing is
+// written, see PlanFolder and CreateFolder.
+func ChannelFolderPath(channelName string, channelID string, year string, config models.DownloadConfig) string {
+	values := strings.NewReplacer(
+		"{channel}", strings.ReplaceAll(channelName, "/", " - "),
+		"{id}", strings.ReplaceAll(channelID, "/", " - "),
+		"{year}", year,
+	)
+
+	var components []string
+
+	for component := range strings.SplitSeq(values.Replace(cmp.Or(config.FolderTemplate, models.DefaultFolderTemplate)), "/") {
+		if runtime.GOOS == "windows" {
+			component = sanitizeFilename(component)
+		}
+
+		// Empty names of doubled slashes are dropped, "." and ".." are escaped
+		if component != "" {
+			components = append(components, sanitizePathComponent(component))
+		}
+	}
+
+	if len(components) == 0 {
+		components = append(components, sanitizePathComponent(""))
+	}
+
+	return filepath.Clean(filepath.Join(append([]string{config.OutputDir}, components...)...))
+}
+
+// makeDirs creates path and its missing parents. The created folders get mode regardless
+// of the umask, existing folders are left as they are. Uses the default permissions if
+// mode is 0.
+func makeDirs(path string, mode os.FileMode) error {
+	if mode == 0 {
+		if err := os.MkdirAll(path, dirPermissions); err != nil {
+			return fmt.Errorf("%w: %w", errFailedToCreateFolder, err)
+		}
+
+		return nil
+	}
+
+	var missing []string
+
+	for current := filepath.Clean(path); ; current = filepath.Dir(current) {
+		if _, err := os.Stat(current); err == nil {
+			break
+		}
+
+		missing = append(missing, current)
+
+		if filepath.Dir(current) == current {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(path, mode); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToCreateFolder, err)
+	}
+
+	for _, folder := range missing {
+		if err := os.Chmod(folder, mode); err != nil {
+			return fmt.Errorf("%w: %w", errFailedToSetMode, err)
+		}
+	}
+
+	return nil
 }
 
 // truncateFilename shortens a filename to fit within maxLen bytes while preserving the extension.
@@ -114,10 +375,42 @@ func truncateFilename(filename string, maxLen int) string {
 	// Truncate the name portion, leaving room for the extension
 	maxNameLen := maxLen - len(ext)
 	if maxNameLen <= 0 {
-		return filename[:maxLen]
+		return truncateBytes(filename, maxLen)
 	}
 
-	return name[:maxNameLen] + ext
+	return truncateBytes(name, maxNameLen) + ext
+}
+
+// truncateBytes shortens s to at most maxLen bytes without splitting a multi-byte character.
+func truncateBytes(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+
+	for maxLen > 0 && !utf8.RuneStart(s[maxLen]) {
+		maxLen--
+	}
+
+	return s[:maxLen]
+}
+
+// sanitizePathComponent makes a single file or folder name safe on all major filesystems.
+// Truncates to maxFilenameLen bytes (keeping the extension), strips trailing dots and
+// spaces that Windows silently drops, and escapes reserved device names like CON or NUL.
+func sanitizePathComponent(name string) string {
+	name = truncateFilename(name, maxFilenameLen)
+	name = strings.TrimRight(name, ". ")
+
+	stem, _, _ := strings.Cut(name, ".")
+	if reservedNames[strings.ToUpper(strings.TrimSpace(stem))] {
+		name = "_" + name
+	}
+
+	if name == "" {
+		return "_"
+	}
+
+	return name
 }
 
 // sanitizeFilename removes or replaces invalid characters in filenames.
@@ -135,6 +428,13 @@ func sanitizeFilename(filename string) string {
 	)
 
 	sanitized := replacer.Replace(filename)
+	sanitized = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+
+		return r
+	}, sanitized)
 	sanitized = strings.TrimSpace(sanitized)
 
 	for strings.Contains(sanitized, "--") {
@@ -0,0 +1,118 @@
+package dir
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// lockFilename is the lock file taken in the output directory of a run.
+	lockFilename = ".switchtube.lock"
+	// lockPermissions are the permissions of the lock file.
+	lockPermissions = 0o644
+)
+
+// ErrFolderLocked is returned when another run is writing into the same folder.
+var ErrFolderLocked = errors.New("folder is used by another download")
+
+var (
+	errFailedToLockFolder = errors.New("failed to lock folder")
+	// errLockHeld is returned by lockFile if another process holds the lock.
+	errLockHeld = errors.New("lock held by another process")
+)
+
+// lockInfo is the content of a lock file, identifying the run holding it.
+type lockInfo struct {
+	StartedAt time.Time `json:"startedAt"` // Time the run took the lock
+	PID       int       `json:"pid"`       // Process ID of the run
+}
+
+// LockFolder takes the lock of folder, creating the folder if needed, so two runs never
+// write into the same folder at once. The lock is an advisory lock on the lock file held
+// by the operating system, so it is released when a run crashes. With force, a run
+// writes into the folder even if another run holds the lock. Returns a function that
+// releases the lock.
+func LockFolder(folder string, force bool) (func(), error) {
+	if err := os.MkdirAll(folder, dirPermissions); err != nil {
+		return nil, fmt.Errorf("%w: %w", errFailedToLockFolder, err)
+	}
+
+	path := filepath.Join(folder, lockFilename)
+
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, lockPermissions)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errFailedToLockFolder, err)
+		}
+
+		if err := lockFile(file); err != nil {
+			_ = file.Close()
+
+			if !errors.Is(err, errLockHeld) {
+				return nil, fmt.Errorf("%w: %w", errFailedToLockFolder, err)
+			}
+
+			if force {
+				return func() {}, nil
+			}
+
+			if holder, err := readLock(path); err == nil {
+				return nil, fmt.Errorf("%w: %s (PID %d, started %s)",
+					ErrFolderLocked, folder, holder.PID, holder.StartedAt.Local().Format(time.DateTime))
+			}
+
+			return nil, fmt.Errorf("%w: %s", ErrFolderLocked, folder)
+		}
+
+		// The previous holder may have removed the file between opening and locking it,
+		// in which case the lock is on a file nobody else sees
+		if current, err := os.Stat(path); err == nil {
+			if opened, err := file.Stat(); err == nil && os.SameFile(current, opened) {
+				return holdLock(file, path)
+			}
+		}
+
+		_ = file.Close()
+	}
+}
+
+// holdLock records this run in the locked file at path. Returns a function that removes
+// the file and releases the lock.
+func holdLock(file *os.File, path string) (func(), error) {
+	data, err := json.Marshal(lockInfo{StartedAt: time.Now(), PID: os.Getpid()})
+	if err == nil {
+		err = file.Truncate(0)
+	}
+
+	if err == nil {
+		_, err = file.WriteAt(append(data, '\n'), 0)
+	}
+
+	if err != nil {
+		_ = file.Close()
+
+		return nil, fmt.Errorf("%w: %w", errFailedToLockFolder, err)
+	}
+
+	return func() {
+		// Removed while still locked, so no other run locks the file being removed
+		_ = os.Remove(path)
+		_ = file.Close()
+	}, nil
+}
+
+// readLock reads the lock file at path.
+func readLock(path string) (lockInfo, error) {
+	var info lockInfo
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, err //nolint:wrapcheck // Only checked for success
+	}
+
+	return info, json.Unmarshal(data, &info) //nolint:wrapcheck // Only checked for success
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !windows
+
+package dir
+
+import "os"
+
+// lockFile is a no-op on platforms without supported file locks.
+func lockFile(_ *os.File) error {
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build linux || darwin
+
+package dir
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFile takes an exclusive flock on file without waiting for it.
+func lockFile(file *os.File) error {
+	err := unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		return errLockHeld
+	}
+
+	return err //nolint:wrapcheck // Wrapped by LockFolder
+}
@@ -0,0 +1,106 @@
+package dir
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FolderCollision is an existing folder whose name differs from a planned one in case
+// only. Case-insensitive file systems, the default on macOS and Windows, treat both
+// as the same folder, so their files end up together.
+type FolderCollision struct {
+	Planned  string // Folder as named by the template
+	Existing string // Existing folder next to it
+}
+
+// FolderPlan describes what writing into a folder changes on disk.
+type FolderPlan struct {
+	Path       string            // Folder the files are written into
+	Create     []string          // Missing folders that are created, outermost first
+	Collisions []FolderCollision // Existing folders that differ from a planned one in case only
+}
+
+// PlanFolder reports which folders of path are missing and which existing folders
+// differ from one of them in case only, without writing anything. On a case-insensitive
+// file system, such a folder is the one written into, so Path is spelled like it.
+func PlanFolder(path string) FolderPlan {
+	var folders []string
+
+	for current := filepath.Clean(path); current != "." && filepath.Dir(current) != current; current = filepath.Dir(current) {
+		folders = append(folders, current)
+	}
+
+	slices.Reverse(folders)
+
+	var plan FolderPlan
+
+	actual := filepath.Clean(path)
+	missing := false
+
+	for i, folder := range folders {
+		parent := filepath.Dir(folder)
+		if i > 0 {
+			parent = actual
+		}
+
+		name := filepath.Base(folder)
+		actual = filepath.Join(parent, name)
+
+		if missing {
+			plan.Create = append(plan.Create, actual)
+
+			continue
+		}
+
+		existing := caseVariant(parent, name)
+
+		if _, err := os.Stat(actual); err != nil {
+			missing = true
+			plan.Create = append(plan.Create, actual)
+		}
+
+		if existing != "" {
+			plan.Collisions = append(plan.Collisions, FolderCollision{Planned: actual, Existing: filepath.Join(parent, existing)})
+
+			// The file system resolved the planned name to the existing folder
+			if !missing {
+				actual = filepath.Join(parent, existing)
+			}
+		}
+	}
+
+	plan.Path = actual
+
+	return plan
+}
+
+// CreateFolder creates the missing folders of plan with mode, or the default permissions
+// if mode is 0. Folders that exist by now, e.g. created by a concurrent or earlier
+// interrupted run, are kept, so creating the same plan again succeeds.
+func CreateFolder(plan FolderPlan, mode os.FileMode) error {
+	return makeDirs(plan.Path, mode)
+}
+
+// caseVariant returns the name of the folder in parent that differs from name in case
+// only, or an empty string if there is none or a folder named exactly name exists.
+func caseVariant(parent string, name string) string {
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return ""
+	}
+
+	variant := ""
+
+	for _, entry := range entries {
+		switch {
+		case entry.Name() == name:
+			return ""
+		case entry.IsDir() && strings.EqualFold(entry.Name(), name):
+			variant = entry.Name()
+		}
+	}
+
+	return variant
+}
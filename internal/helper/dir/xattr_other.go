@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package dir
+
+// setXattr is a no-op on platforms without extended attribute support.
+func setXattr(_ string, _ string, _ string) error {
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build linux || darwin
+
+package dir
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// setXattr stores value in the extended attribute name of the file.
+// Filesystems without xattr support are silently ignored.
+func setXattr(filename string, name string, value string) error {
+	err := unix.Setxattr(filename, name, []byte(value), 0)
+	if err == nil || errors.Is(err, unix.ENOTSUP) {
+		return nil
+	}
+
+	return fmt.Errorf("failed to set extended attribute %s: %w", name, err)
+}
@@ -1,20 +1,37 @@
 package input
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
-	"github.com/charmbracelet/huh"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/i18n"
 	"switchtube-downloader/internal/models"
 )
 
+const (
+	secondsPerMinute = 60
+	minutesPerHour   = 60
+	bytesPerMB       = 1000 * 1000
+	bytesPerGB       = 1000 * bytesPerMB
+)
+
+//nolint:gochecknoglobals // Read-only style
+var detailStyle = lipgloss.NewStyle().Faint(true)
+
 // ErrUserAbort is returned when the user aborts an action (e.g. via Ctrl+C).
 var ErrUserAbort = errors.New("aborted by user")
 
-// SelectVideos shows an interactive multi-select for choosing videos.
-// Returns slice of selected video indices and error if user aborts.
-func SelectVideos(videos []models.Video, all bool, useEpisode bool) ([]int, error) {
+// SelectVideos shows an interactive multi-select for choosing videos, which are already
+// sorted by sort. Pressing s re-sorts the list, pressing tab on a video shows its details
+// fetched with preview, if set.
+// Returns slice of selected video indices in list order and error if user aborts.
+func SelectVideos(videos []models.Video, all bool, useEpisode bool, sort models.VideoSort, preview PreviewFunc) ([]int, error) {
 	// If --all flag is used, select all videos
 	if all || len(videos) == 0 {
 		indices := make([]int, len(videos))
@@ -26,34 +43,117 @@ func SelectVideos(videos []models.Video, all bool, useEpisode bool) ([]int, erro
 		return indices, nil
 	}
 
-	options := make([]huh.Option[int], len(videos))
+	if promptsDisabled {
+		return nil, fmt.Errorf("%w: use --all to download every video or --select to choose them", ErrNoInput)
+	}
+
+	labels := make([]string, len(videos))
 	for i, video := range videos {
-		label := video.Title
+		labels[i] = video.Title
 		if useEpisode && video.Episode != "" {
-			label = video.Episode + "  " + video.Title
+			labels[i] = video.Episode + "  " + video.Title
 		}
+	}
 
-		options[i] = huh.NewOption(label, i).Selected(true)
+	s := newSelector(videos, labels, sort, preview)
+
+	if _, err := tea.NewProgram(s, tea.WithOutput(stream.UI())).Run(); err != nil {
+		return nil, fmt.Errorf("failed to run selection: %w", err)
 	}
 
-	selected := make([]int, 0, len(videos))
+	if s.aborted {
+		return nil, ErrUserAbort
+	}
 
-	form := huh.NewForm(
-		huh.NewGroup(
-			huh.NewMultiSelect[int]().
-				Title("Choose videos to download").
-				Options(options...).
-				Value(&selected),
-		),
-	)
+	return s.selectedIndices(), nil
+}
+
+// SelectOverwrites shows the videos whose files already exist in one list, none
+// selected, to choose which files are overwritten. Every file is kept once the prompt
+// timeout passed. Returns all indices without prompting if AssumeYes was called, and
+// ErrNoInput if prompts are disabled.
+func SelectOverwrites(videos []models.Video, filenames []string) ([]int, error) {
+	if assumeYes {
+		return SelectVideos(videos, true, false, models.SortChannel, nil)
+	}
 
-	if err := form.Run(); err != nil {
-		if errors.Is(err, huh.ErrUserAborted) {
-			return nil, ErrUserAbort
+	if promptsDisabled {
+		return nil, fmt.Errorf("%w: %s", ErrNoInput, i18n.T("use --skip or --force"))
+	}
+
+	s := newSelector(videos, filenames, models.SortChannel, nil)
+	s.title = i18n.T("Choose existing files to overwrite")
+	clear(s.selected)
+
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if promptTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, promptTimeout)
+	}
+
+	defer cancel()
+
+	_, err := tea.NewProgram(s, tea.WithOutput(stream.UI()), tea.WithContext(ctx)).Run()
+	if errors.Is(err, tea.ErrProgramKilled) && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		printTimedOut()
+
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to run selection: %w", err)
+	}
+
+	if s.aborted {
+		return nil, ErrUserAbort
+	}
+
+	return s.selectedIndices(), nil
+}
+
+// VideoDetails returns the duration, publish date and size of a video as aligned
+// columns, with placeholders for unknown values.
+func VideoDetails(video models.Video) []string {
+	length, published, size := "-", "-", "-"
+
+	if d := video.Length().Round(time.Second); d > 0 {
+		length = fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%secondsPerMinute)
+		if d >= time.Hour {
+			length = fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%minutesPerHour, int(d.Seconds())%secondsPerMinute)
 		}
+	}
+
+	if !video.PublishedAt.IsZero() {
+		published = video.PublishedAt.Format(time.DateOnly)
+	}
+
+	if video.Size > 0 {
+		size = formatSize(video.Size)
+	}
+
+	return []string{fmt.Sprintf("%8s", length), fmt.Sprintf("%10s", published), fmt.Sprintf("%9s", size)}
+}
+
+// VariantDetails returns the media type of a variant and the resolution and size read
+// from its file, with placeholders for unknown values.
+func VariantDetails(variant models.Variant, file models.VariantFile) []string {
+	resolution, size := "-", "-"
+
+	if file.Height > 0 {
+		resolution = fmt.Sprintf("%dp", file.Height)
+	}
+
+	if file.Size > 0 {
+		size = formatSize(file.Size)
+	}
+
+	return []string{variant.MediaType, resolution, size}
+}
 
-		return nil, fmt.Errorf("failed to run selection form: %w", err)
+// formatSize formats a size in bytes in MB, or in GB from 1 GB on.
+func formatSize(size int64) string {
+	if size >= bytesPerGB {
+		return fmt.Sprintf("%.1f GB", float64(size)/bytesPerGB)
 	}
 
-	return selected, nil
+	return fmt.Sprintf("%.1f MB", float64(size)/bytesPerMB)
 }
@@ -0,0 +1,9 @@
+package input
+
+import "golang.org/x/sys/unix"
+
+// Requests reading and writing the terminal settings.
+const (
+	ioctlGetTermios = unix.TIOCGETA
+	ioctlSetTermios = unix.TIOCSETA
+)
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+
+package input
+
+import (
+	"os"
+	"time"
+)
+
+// ListenKeys is a no-op on platforms without termios support.
+func ListenKeys(_ func(key byte)) func() {
+	return func() {}
+}
+
+// readKeyWithin reads a single lowercase key from the terminal without waiting for enter.
+// The timeout is ignored on platforms without termios support.
+func readKeyWithin(_ time.Duration) (byte, error) {
+	return readRawKey(os.Stdin.Fd())
+}
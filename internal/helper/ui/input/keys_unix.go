@@ -0,0 +1,142 @@
+//go:build linux || darwin
+
+package input
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"golang.org/x/sys/unix"
+)
+
+// ListenKeys calls onKey with every lowercase key pressed in the terminal until the
+// returned function is called. Line buffering and echo are turned off meanwhile, while
+// Ctrl+C still interrupts the process. Does nothing if prompts are disabled or stdin is
+// not a terminal.
+func ListenKeys(onKey func(key byte)) func() {
+	if promptsDisabled || !term.IsTerminal(os.Stdin.Fd()) {
+		return func() {}
+	}
+
+	// A separately opened terminal can be closed to unblock the pending read
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return func() {}
+	}
+
+	conn, err := tty.SyscallConn()
+	if err != nil {
+		_ = tty.Close()
+
+		return func() {}
+	}
+
+	var saved *unix.Termios
+
+	_ = conn.Control(func(fd uintptr) {
+		termios, err := unix.IoctlGetTermios(int(fd), ioctlGetTermios)
+		if err != nil {
+			return
+		}
+
+		saved = new(unix.Termios)
+		*saved = *termios
+
+		termios.Lflag &^= unix.ICANON | unix.ECHO
+		termios.Cc[unix.VMIN] = 1
+		termios.Cc[unix.VTIME] = 0
+
+		if err := unix.IoctlSetTermios(int(fd), ioctlSetTermios, termios); err != nil {
+			saved = nil
+		}
+	})
+
+	var (
+		stopped bool
+		mutex   sync.Mutex
+	)
+
+	go func() {
+		buf := make([]byte, 1)
+
+		for {
+			if _, err := tty.Read(buf); err != nil {
+				return
+			}
+
+			mutex.Lock()
+			if !stopped {
+				onKey(strings.ToLower(string(buf))[0])
+			}
+			mutex.Unlock()
+		}
+	}()
+
+	return func() {
+		mutex.Lock()
+		stopped = true
+		mutex.Unlock()
+
+		if saved != nil {
+			_ = conn.Control(func(fd uintptr) {
+				_ = unix.IoctlSetTermios(int(fd), ioctlSetTermios, saved)
+			})
+		}
+
+		_ = tty.Close()
+	}
+}
+
+// readKeyWithin reads a single lowercase key from the terminal without waiting for enter.
+// Returns errPromptTimeout if no key was pressed within timeout.
+func readKeyWithin(timeout time.Duration) (byte, error) {
+	// A separately opened terminal supports read deadlines, unlike stdin
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read answer: %w", err)
+	}
+
+	defer func() { _ = tty.Close() }()
+
+	conn, err := tty.SyscallConn()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read answer: %w", err)
+	}
+
+	var (
+		state  *term.State
+		rawErr error
+	)
+
+	if err := conn.Control(func(fd uintptr) { state, rawErr = term.MakeRaw(fd) }); err != nil {
+		return 0, fmt.Errorf("failed to read answer: %w", err)
+	}
+
+	if rawErr != nil {
+		return 0, fmt.Errorf("failed to read answer: %w", rawErr)
+	}
+
+	defer func() {
+		_ = conn.Control(func(fd uintptr) { _ = term.Restore(fd, state) })
+	}()
+
+	if err := tty.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, fmt.Errorf("failed to read answer: %w", err)
+	}
+
+	buf := make([]byte, 1)
+	if _, err := tty.Read(buf); err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return 0, errPromptTimeout
+		}
+
+		return 0, fmt.Errorf("failed to read answer: %w", err)
+	}
+
+	return strings.ToLower(string(buf))[0], nil
+}
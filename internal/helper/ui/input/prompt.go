@@ -1,14 +1,96 @@
 package input
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/x/term"
+
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/models"
+)
+
+// OverwriteChoice is the answer to an overwrite prompt.
+type OverwriteChoice int
+
+// Possible answers to an overwrite prompt.
+const (
+	OverwriteNo   OverwriteChoice = iota // Keep this file
+	OverwriteYes                         // Overwrite this file
+	OverwriteAll                         // Overwrite this and every further file
+	OverwriteNone                        // Keep this and every further file
+	OverwriteQuit                        // Abort the run
+)
+
+// ctrlC is the byte sent for Ctrl+C while the terminal is in raw mode.
+const ctrlC = 3
+
+// ErrNoInput is returned instead of prompting when prompts are disabled with --no-input.
+var ErrNoInput = errors.New("input required but prompts are disabled by --no-input")
+
+var (
+	errInvalidConcurrency    = errors.New("enter a number from 0")
+	errNegativePromptTimeout = errors.New("prompt timeout must not be negative")
+	errPromptTimeout         = errors.New("no answer within the prompt timeout")
 )
 
+//nolint:gochecknoglobals // Set once at startup from the global --no-input flag
+var promptsDisabled bool
+
+//nolint:gochecknoglobals // Set once at startup from the global --yes flag
+var assumeYes bool
+
+//nolint:gochecknoglobals // Set once at startup from the global --prompt-timeout flag
+var promptTimeout time.Duration
+
+// stdin reads the answers to prompts when stdin is not a terminal. A single reader keeps
+// the lines it buffered ahead for the next prompt, e.g. answers piped in at once.
+//
+//nolint:gochecknoglobals // Shared by all prompts, as stdin is
+var stdin = bufio.NewReader(os.Stdin)
+
+// AssumeYes makes every confirmation answer yes without prompting, even if prompts
+// are disabled.
+func AssumeYes() {
+	assumeYes = true
+}
+
+// DisablePrompts makes every prompt fail with ErrNoInput instead of waiting for stdin.
+func DisablePrompts() {
+	promptsDisabled = true
+}
+
+// PromptsDisabled reports whether prompts were disabled with DisablePrompts.
+func PromptsDisabled() bool {
+	return promptsDisabled
+}
+
+// SetPromptTimeout makes confirmations and the overwrite and duplicate prompts take
+// their default answer if the user does not answer within timeout. 0 waits forever.
+func SetPromptTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return errNegativePromptTimeout
+	}
+
+	promptTimeout = timeout
+
+	return nil
+}
+
 // Input prompts the user for a single line of text and returns the entered string.
-func Input(prompt string) string {
+// Returns ErrNoInput if prompts are disabled.
+func Input(prompt string) (string, error) {
+	if promptsDisabled {
+		return "", fmt.Errorf("%w: %s", ErrNoInput, prompt)
+	}
+
 	var value string
 
 	_ = huh.NewForm(
@@ -19,12 +101,21 @@ func Input(prompt string) string {
 		),
 	).Run()
 
-	return value
+	return value, nil
 }
 
-// Confirm prompts the user for a yes/no confirmation and returns true for yes.
-func Confirm(format string, args ...any) bool {
+// Confirm prompts the user for a yes/no confirmation and returns true for yes, or false
+// once the prompt timeout passed. Returns true without prompting if AssumeYes was
+// called, and ErrNoInput if prompts are disabled.
+func Confirm(format string, args ...any) (bool, error) {
 	msg := fmt.Sprintf(format, args...)
+	if assumeYes {
+		return true, nil
+	}
+
+	if promptsDisabled {
+		return false, fmt.Errorf("%w: %s", ErrNoInput, msg)
+	}
 
 	var confirmed bool
 
@@ -32,15 +123,321 @@ func Confirm(format string, args ...any) bool {
 		huh.NewGroup(
 			huh.NewConfirm().
 				Title(msg).
-				Affirmative("Yes").
-				Negative("No").
+				Affirmative(i18n.T("Yes")).
+				Negative(i18n.T("No")).
 				Value(&confirmed),
 		),
-	).Run()
+	).WithTimeout(promptTimeout).Run()
 
 	if errors.Is(err, huh.ErrUserAborted) {
-		return false
+		return false, nil
+	}
+
+	if errors.Is(err, huh.ErrTimeout) {
+		printTimedOut()
+
+		return false, nil
+	}
+
+	return confirmed, nil
+}
+
+// ConfirmOverwrite asks whether the existing file may be overwritten. Besides y/n for
+// this file it accepts a (overwrite all), s (skip all) and q (abort the run). The file
+// is kept once the prompt timeout passed. Returns OverwriteAll without prompting if
+// AssumeYes was called, and ErrNoInput if prompts are disabled.
+func ConfirmOverwrite(filename string) (OverwriteChoice, error) {
+	msg := i18n.T("File %s already exists. Overwrite?", filename)
+	if assumeYes {
+		return OverwriteAll, nil
+	}
+
+	if promptsDisabled {
+		return OverwriteNo, fmt.Errorf("%w: %s", ErrNoInput, msg)
+	}
+
+	fmt.Fprintf(stream.UI(), "%s %s%s ", msg, i18n.T("[y/N, a = all, s = skip all, q = quit]"), timeoutHint())
+
+	key, err := readKey()
+	if errors.Is(err, errPromptTimeout) {
+		printTimedOut()
+
+		return OverwriteNo, nil
+	}
+
+	if err != nil {
+		return OverwriteNo, err
+	}
+
+	choices := map[byte]OverwriteChoice{
+		'y': OverwriteYes, 'j': OverwriteYes, 'a': OverwriteAll, 's': OverwriteNone, 'q': OverwriteQuit, ctrlC: OverwriteQuit,
+	}
+
+	choice := choices[key]
+	if key != ctrlC {
+		fmt.Fprint(stream.UI(), string(key))
+	}
+
+	fmt.Fprintln(stream.UI())
+
+	return choice, nil
+}
+
+// ConfirmDuplicates asks once what to do with the videos titled titles that were
+// downloaded to the paths of existing before: l hardlinks, c copies and s skips them,
+// q aborts the run and any other key or no answer within the prompt timeout downloads
+// them again. Returns models.DuplicateDownload without prompting if AssumeYes was
+// called, and ErrNoInput if prompts are disabled.
+func ConfirmDuplicates(titles []string, existing []string) (models.DuplicatePolicy, error) {
+	msg := i18n.T("%d videos were already downloaded to other folders. Reuse them?", len(titles))
+	if len(titles) == 1 {
+		msg = i18n.T("%s was already downloaded to %s. Reuse it?", titles[0], existing[0])
+	}
+
+	if assumeYes {
+		return models.DuplicateDownload, nil
+	}
+
+	if promptsDisabled {
+		return models.DuplicateDownload, fmt.Errorf("%w: %s", ErrNoInput, msg)
+	}
+
+	if len(titles) > 1 {
+		for i, title := range titles {
+			fmt.Fprintf(stream.UI(), "  %s: %s\n", title, existing[i])
+		}
+	}
+
+	fmt.Fprintf(stream.UI(), "%s %s%s ", msg, i18n.T("[l = link, c = copy, s = skip, d = download again, q = quit]"), timeoutHint())
+
+	key, err := readKey()
+	if errors.Is(err, errPromptTimeout) {
+		printTimedOut()
+
+		return models.DuplicateDownload, nil
+	}
+
+	if err != nil {
+		return models.DuplicateDownload, err
+	}
+
+	if key != ctrlC {
+		fmt.Fprint(stream.UI(), string(key))
+	}
+
+	fmt.Fprintln(stream.UI())
+
+	if key == ctrlC || key == 'q' {
+		return models.DuplicateDownload, ErrUserAbort
+	}
+
+	choices := map[byte]models.DuplicatePolicy{'l': models.DuplicateLink, 'c': models.DuplicateCopy, 's': models.DuplicateSkip}
+
+	choice, ok := choices[key]
+	if !ok {
+		return models.DuplicateDownload, nil
 	}
 
-	return confirmed
+	return choice, nil
+}
+
+// SelectQuality asks which variant to download of all selected videos: the highest or
+// lowest quality, the best up to a resolution, or one chosen per video. The highest quality is taken once the prompt
+// timeout passed. Returns ErrNoInput if prompts are disabled.
+func SelectQuality() (models.QualityPolicy, error) {
+	msg := i18n.T("Choose the quality of the selected videos")
+	if promptsDisabled {
+		return models.QualityHighest, fmt.Errorf("%w: %s", ErrNoInput, msg)
+	}
+
+	quality := models.QualityHighest
+
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[models.QualityPolicy]().
+				Title(msg).
+				Options(
+					huh.NewOption(i18n.T("Highest"), models.QualityHighest),
+					huh.NewOption(i18n.T("Lowest"), models.QualityLowest),
+					huh.NewOption(i18n.T("Up to %s", "1080p"), models.QualityPolicy("1080p")),
+					huh.NewOption(i18n.T("Up to %s", "720p"), models.QualityPolicy("720p")),
+					huh.NewOption(i18n.T("Up to %s", "480p"), models.QualityPolicy("480p")),
+					huh.NewOption(i18n.T("Choose per video"), models.QualityPerVideo),
+				).
+				Value(&quality),
+		),
+	).WithTimeout(promptTimeout).Run()
+
+	switch {
+	case errors.Is(err, huh.ErrUserAborted):
+		return models.QualityHighest, ErrUserAbort
+	case errors.Is(err, huh.ErrTimeout):
+		printTimedOut()
+
+		return models.QualityHighest, nil
+	}
+
+	return quality, nil
+}
+
+// SelectVariant asks which of the variants of the video titled title to download, listed
+// from the highest to the lowest quality. Returns the index of the chosen variant, the
+// first once the prompt timeout passed, and ErrNoInput if prompts are disabled.
+func SelectVariant(title string, variants []models.Variant) (int, error) {
+	msg := i18n.T("Quality of %s", title)
+	if promptsDisabled {
+		return 0, fmt.Errorf("%w: %s", ErrNoInput, msg)
+	}
+
+	options := make([]huh.Option[int], len(variants))
+
+	for i, variant := range variants {
+		label := variant.MediaType
+
+		switch i {
+		case 0:
+			label += " " + i18n.T("(highest)")
+		case len(variants) - 1:
+			label += " " + i18n.T("(lowest)")
+		}
+
+		options[i] = huh.NewOption(label, i)
+	}
+
+	var chosen int
+
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[int]().Title(msg).Options(options...).Value(&chosen),
+		),
+	).WithTimeout(promptTimeout).Run()
+
+	switch {
+	case errors.Is(err, huh.ErrUserAborted):
+		return 0, ErrUserAbort
+	case errors.Is(err, huh.ErrTimeout):
+		printTimedOut()
+
+		return 0, nil
+	}
+
+	return chosen, nil
+}
+
+// printTimedOut ends the pending prompt line with a note that the default answer was taken.
+func printTimedOut() {
+	fmt.Fprintln(stream.UI(), i18n.T("no answer within %s, using the default", promptTimeout))
+}
+
+// timeoutHint returns the note on the prompt timeout appended to key prompts, empty if
+// prompts wait forever.
+func timeoutHint() string {
+	if promptTimeout <= 0 {
+		return ""
+	}
+
+	return " " + i18n.T("(default in %s)", promptTimeout)
+}
+
+// readKey reads a single lowercase key from stdin without waiting for enter if
+// stdin is a terminal, or the first character of the next line otherwise. Returns
+// errPromptTimeout if no key was pressed in the terminal within the prompt timeout.
+func readKey() (byte, error) {
+	fd := os.Stdin.Fd()
+
+	if !term.IsTerminal(fd) {
+		line, err := stdin.ReadString('\n')
+		if line = strings.TrimSpace(line); line == "" {
+			if err != nil {
+				return 0, fmt.Errorf("failed to read answer: %w", err)
+			}
+
+			return '\n', nil
+		}
+
+		return strings.ToLower(line)[0], nil
+	}
+
+	if promptTimeout > 0 {
+		return readKeyWithin(promptTimeout)
+	}
+
+	return readRawKey(fd)
+}
+
+// readRawKey reads a single lowercase key from the terminal fd without waiting for enter.
+func readRawKey(fd uintptr) (byte, error) {
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read answer: %w", err)
+	}
+
+	defer func() {
+		_ = term.Restore(fd, state)
+	}()
+
+	buf := make([]byte, 1)
+	if _, err := os.Stdin.Read(buf); err != nil {
+		return 0, fmt.Errorf("failed to read answer: %w", err)
+	}
+
+	return strings.ToLower(string(buf))[0], nil
+}
+
+// SetupAnswers holds the preferences asked for by AskSetup.
+type SetupAnswers struct {
+	OutputDir   string // Default output directory, empty for the current directory
+	Color       string // Color preference, one of the stream.Color constants
+	Concurrency int    // Default number of videos downloaded at once, 0 for all
+}
+
+// AskSetup asks for the preferences of the first-run setup: the default output directory,
+// whether output is colored and how many videos are downloaded at once, starting from
+// answers. Returns ErrUserAbort if the user quits and ErrNoInput if prompts are disabled.
+func AskSetup(answers SetupAnswers) (SetupAnswers, error) {
+	if promptsDisabled {
+		return answers, fmt.Errorf("%w: %s", ErrNoInput, i18n.T("Set up the downloader"))
+	}
+
+	concurrency := strconv.Itoa(answers.Concurrency)
+
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title(i18n.T("Where should downloads be saved?")).
+				Description(i18n.T("Leave empty for the current directory")).
+				Value(&answers.OutputDir),
+			huh.NewSelect[string]().
+				Title(i18n.T("Colored output")).
+				Options(
+					huh.NewOption(i18n.T("Automatic"), stream.ColorAuto),
+					huh.NewOption(i18n.T("Always"), stream.ColorAlways),
+					huh.NewOption(i18n.T("Never"), stream.ColorNever),
+				).
+				Value(&answers.Color),
+			huh.NewInput().
+				Title(i18n.T("How many videos should be downloaded at once?")).
+				Description(i18n.T("0 downloads all selected videos at once")).
+				Validate(func(value string) error {
+					if n, err := strconv.Atoi(strings.TrimSpace(value)); err != nil || n < 0 {
+						return errInvalidConcurrency
+					}
+
+					return nil
+				}).
+				Value(&concurrency),
+		),
+	).Run()
+	if errors.Is(err, huh.ErrUserAborted) {
+		return answers, ErrUserAbort
+	}
+
+	if err != nil {
+		return answers, fmt.Errorf("failed to run setup: %w", err)
+	}
+
+	answers.OutputDir = strings.TrimSpace(answers.OutputDir)
+	answers.Concurrency, _ = strconv.Atoi(strings.TrimSpace(concurrency))
+
+	return answers, nil
 }
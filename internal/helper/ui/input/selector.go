@@ -0,0 +1,454 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"switchtube-downloader/internal/helper/ui/styles"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// selectorChrome is the number of lines used by the title, size footer, full title and help around the list.
+	selectorChrome = 6
+	// rowPrefixWidth is the width of the cursor and checkbox in front of each title.
+	rowPrefixWidth = 6
+	// minTitleWidth is the narrowest title column shown next to the details, narrower
+	// terminals hide the details instead.
+	minTitleWidth = 16
+	// maxSelectorRows is the maximum number of videos shown at once.
+	maxSelectorRows = 20
+	// previewTimeout bounds the lookup of the details shown in the preview pane.
+	previewTimeout = 15 * time.Second
+)
+
+// sortCycle is the order in which the s key switches through the sorts of the list.
+//
+//nolint:gochecknoglobals // Read-only list of sorts
+var sortCycle = []models.VideoSort{
+	models.SortChannel, models.SortEpisode, models.SortTitle, models.SortDate, models.SortDuration,
+}
+
+var (
+	selectorTitleStyle  = lipgloss.NewStyle().Foreground(styles.Cyan).Bold(true)
+	selectorCursorStyle = lipgloss.NewStyle().Foreground(styles.Cyan).Bold(true)
+	selectorHelpStyle   = lipgloss.NewStyle().Faint(true)
+	previewKeyStyle     = lipgloss.NewStyle().Foreground(styles.Cyan)
+)
+
+// Preview holds the details of a video shown in the preview pane of the selector.
+type Preview struct {
+	Video    models.Video     // Full metadata of the video, including the description
+	Variants []models.Variant // Available variants, from the highest to the lowest quality
+	Size     int64            // Size of the variant to download in bytes, 0 if unknown
+}
+
+// PreviewFunc fetches the details shown in the preview pane of a video.
+type PreviewFunc func(ctx context.Context, video models.Video) (Preview, error)
+
+// previewMsg carries the fetched details of the video at index.
+type previewMsg struct {
+	err     error
+	preview Preview
+	index   int
+}
+
+// previewState is the preview of a video, once requested.
+type previewState struct {
+	err     error
+	preview Preview
+	loading bool
+}
+
+// selector is the Bubble Tea model of the video selection.
+type selector struct {
+	fetch      PreviewFunc          // Fetches the preview pane details, nil to disable it
+	title      string               // Shown above the list
+	previews   map[int]previewState // Requested previews by video index
+	videos     []models.Video
+	labels     []string
+	selected   []bool
+	order      []int            // Video indices in the order they are listed
+	sort       models.VideoSort // Current sort of the list
+	cursor     int              // Highlighted row
+	offset     int              // First visible row
+	width      int
+	height     int
+	previewing bool // Whether the preview pane of the highlighted video is shown
+	confirmed  bool
+	aborted    bool
+}
+
+// newSelector creates a selector with all videos selected, listed in the given order
+// as they are already sorted by it.
+func newSelector(videos []models.Video, labels []string, sort models.VideoSort, fetch PreviewFunc) *selector {
+	selected := make([]bool, len(videos))
+	order := make([]int, len(videos))
+
+	for i := range videos {
+		selected[i] = true
+		order[i] = i
+	}
+
+	return &selector{
+		fetch:    fetch,
+		title:    i18n.T("Choose videos to download"),
+		previews: make(map[int]previewState),
+		videos:   videos,
+		labels:   labels,
+		selected: selected,
+		order:    order,
+		sort:     sort,
+		width:    80,
+		height:   maxSelectorRows + selectorChrome,
+	}
+}
+
+// Init implements tea.Model.
+func (s *selector) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model.
+func (s *selector) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		// Terminals that do not report their size send zero, keep the defaults then
+		if msg.Width > 0 && msg.Height > 0 {
+			s.width, s.height = msg.Width, msg.Height
+		}
+
+		s.scrollToCursor()
+	case previewMsg:
+		s.previews[msg.index] = previewState{preview: msg.preview, err: msg.err}
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			s.aborted = true
+
+			return s, tea.Quit
+		}
+
+		if s.previewing {
+			return s.handlePreviewKey(msg)
+		}
+
+		return s.handleListKey(msg)
+	}
+
+	return s, nil
+}
+
+// View implements tea.Model.
+func (s *selector) View() string {
+	if s.confirmed || s.aborted {
+		return ""
+	}
+
+	if s.previewing {
+		return s.viewPreview()
+	}
+
+	return s.viewList()
+}
+
+// handleListKey handles keys while the list is shown.
+func (s *selector) handleListKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		s.cursor = max(s.cursor-1, 0)
+	case "down", "j":
+		s.cursor = min(s.cursor+1, len(s.videos)-1)
+	case " ", "x":
+		s.toggle()
+	case "a":
+		all := s.selectedCount() < len(s.videos)
+		for i := range s.selected {
+			s.selected[i] = all
+		}
+	case "s":
+		s.resort()
+	case "tab", "right", "l":
+		if s.fetch != nil {
+			s.previewing = true
+
+			return s, s.loadPreview(s.order[s.cursor])
+		}
+	case "enter":
+		s.confirmed = true
+
+		return s, tea.Quit
+	}
+
+	s.scrollToCursor()
+
+	return s, nil
+}
+
+// handlePreviewKey handles keys while the preview pane is shown.
+func (s *selector) handlePreviewKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "tab", "left", "h", "esc", "backspace", "q":
+		s.previewing = false
+	case " ", "x":
+		s.toggle()
+	}
+
+	return s, nil
+}
+
+// help returns the key help below the list, wrapped to the terminal width.
+func (s *selector) help() string {
+	help := i18n.T("↑/↓: move • space: toggle • a: toggle all • s: sort • enter: confirm • ctrl+c: abort")
+	if s.fetch != nil {
+		help = i18n.T("↑/↓: move • space: toggle • a: toggle all • s: sort • tab: details • enter: confirm • ctrl+c: abort")
+	}
+
+	return selectorHelpStyle.Width(max(s.width-1, 1)).Render(help)
+}
+
+// listRows returns the number of videos that fit on screen.
+func (s *selector) listRows() int {
+	helpLines := lipgloss.Height(s.help())
+
+	return max(min(s.height-selectorChrome-helpLines+1, maxSelectorRows), 1)
+}
+
+// loadPreview fetches the preview of the video at index in the background, unless
+// it was requested before.
+func (s *selector) loadPreview(index int) tea.Cmd {
+	if _, ok := s.previews[index]; ok {
+		return nil
+	}
+
+	s.previews[index] = previewState{loading: true}
+	fetch, video := s.fetch, s.videos[index]
+
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), previewTimeout)
+		defer cancel()
+
+		preview, err := fetch(ctx, video)
+
+		return previewMsg{preview: preview, err: err, index: index}
+	}
+}
+
+// resort switches the list to the next sort of the cycle. The videos are sorted from
+// the channel order, and the cursor stays on the highlighted video.
+func (s *selector) resort() {
+	next := (slices.Index(sortCycle, s.sort) + 1) % len(sortCycle)
+	s.sort = sortCycle[next]
+	highlighted := s.order[s.cursor]
+
+	for i := range s.order {
+		s.order[i] = i
+	}
+
+	slices.SortStableFunc(s.order, func(a int, b int) int {
+		return s.sort.Compare(s.videos[a], s.videos[b])
+	})
+
+	s.cursor = slices.Index(s.order, highlighted)
+}
+
+// scrollToCursor moves the visible window of the list so the cursor stays visible.
+func (s *selector) scrollToCursor() {
+	rows := s.listRows()
+
+	if s.cursor < s.offset {
+		s.offset = s.cursor
+	} else if s.cursor >= s.offset+rows {
+		s.offset = s.cursor - rows + 1
+	}
+}
+
+// selectedCount returns the number of selected videos.
+func (s *selector) selectedCount() int {
+	count := 0
+
+	for _, selected := range s.selected {
+		if selected {
+			count++
+		}
+	}
+
+	return count
+}
+
+// selectedIndices returns the selected video indices in the order they are listed.
+func (s *selector) selectedIndices() []int {
+	indices := make([]int, 0, len(s.videos))
+
+	for _, i := range s.order {
+		if s.selected[i] {
+			indices = append(indices, i)
+		}
+	}
+
+	return indices
+}
+
+// sizeFooter returns the number of selected videos and their estimated total size.
+// Sizes fetched for the preview are used for videos whose size was not known before.
+func (s *selector) sizeFooter() string {
+	var (
+		total   int64
+		unknown int
+	)
+
+	for i, selected := range s.selected {
+		if !selected {
+			continue
+		}
+
+		size := max(s.videos[i].Size, s.previews[i].preview.Size)
+		if size <= 0 {
+			unknown++
+
+			continue
+		}
+
+		total += size
+	}
+
+	footer := i18n.T("%d selected, ~%s", s.selectedCount(), formatSize(total))
+	if unknown > 0 {
+		footer += " " + i18n.T("(%d without known size)", unknown)
+	}
+
+	return footer
+}
+
+// titleWidth returns the width of the title column, so each row fits into the terminal,
+// and whether the details fit next to it.
+func (s *selector) titleWidth() (int, bool) {
+	width := 0
+	for _, label := range s.labels {
+		width = max(width, ansi.StringWidth(label))
+	}
+
+	detailsWidth := ansi.StringWidth("  " + strings.Join(VideoDetails(models.Video{}), "  "))
+
+	if available := s.width - rowPrefixWidth - detailsWidth - 1; available >= minTitleWidth {
+		return min(width, available), true
+	}
+
+	return max(min(width, s.width-rowPrefixWidth-1), 1), false
+}
+
+// toggle selects or deselects the highlighted video.
+func (s *selector) toggle() {
+	i := s.order[s.cursor]
+	s.selected[i] = !s.selected[i]
+}
+
+// viewList renders the scrollable video list with checkboxes.
+func (s *selector) viewList() string {
+	var b strings.Builder
+
+	title := i18n.T("%s (%d/%d selected)", s.title, s.selectedCount(), len(s.videos))
+	b.WriteString(selectorTitleStyle.Render(title) + " " + selectorHelpStyle.Render(i18n.T("sorted by %s", s.sort)) + "\n")
+
+	width, showDetails := s.titleWidth()
+	end := min(s.offset+s.listRows(), len(s.videos))
+
+	for row := s.offset; row < end; row++ {
+		i := s.order[row]
+		check := "[ ]"
+		if s.selected[i] {
+			check = "[" + styles.Success.Render("x") + "]"
+		}
+
+		label := ansi.Truncate(s.labels[i], width, "…")
+		label += strings.Repeat(" ", width-ansi.StringWidth(label))
+
+		details := ""
+		if showDetails {
+			details = "  " + detailStyle.Render(strings.Join(VideoDetails(s.videos[i]), "  "))
+		}
+
+		if row == s.cursor {
+			b.WriteString(selectorCursorStyle.Render(">") + " " + check + " " + selectorCursorStyle.Render(label) + details + "\n")
+		} else {
+			b.WriteString("  " + check + " " + label + details + "\n")
+		}
+	}
+
+	if hidden := len(s.videos) - end; hidden > 0 {
+		b.WriteString(selectorHelpStyle.Render(i18n.T("… and %d more", hidden)) + "\n")
+	}
+
+	b.WriteString(selectorHelpStyle.Render(s.sizeFooter()) + "\n")
+
+	// Show the full title of the highlighted video if it does not fit into the column
+	if label := s.labels[s.order[s.cursor]]; ansi.StringWidth(label) > width {
+		b.WriteString(selectorCursorStyle.Render(ansi.Truncate(label, max(s.width-1, 1), "…")) + "\n")
+	}
+
+	b.WriteString(s.help())
+
+	return b.String()
+}
+
+// viewPreview renders the details of the highlighted video.
+func (s *selector) viewPreview() string {
+	var b strings.Builder
+
+	index := s.order[s.cursor]
+	b.WriteString(selectorTitleStyle.Width(max(s.width-1, 1)).Render(s.labels[index]) + "\n")
+
+	state := s.previews[index]
+	video := s.videos[index]
+
+	switch {
+	case state.loading:
+		b.WriteString(i18n.T("Fetching video information...") + "\n")
+	case state.err != nil:
+		b.WriteString(styles.Error.Render("[ERROR]") + " " + state.err.Error() + "\n")
+	default:
+		video = state.preview.Video
+		video.Size = max(state.preview.Size, s.videos[index].Size)
+	}
+
+	details := VideoDetails(video)
+	row := func(key string, value string) {
+		fmt.Fprintf(&b, "%s %s\n", previewKeyStyle.Render(key+":"), strings.TrimSpace(value))
+	}
+
+	row(i18n.T("Duration"), details[0])
+	row(i18n.T("Published"), details[1])
+	row(i18n.T("Size"), details[2])
+
+	if video.License != "" {
+		row(i18n.T("License"), video.License)
+	}
+
+	if !state.loading && state.err == nil {
+		b.WriteString(previewKeyStyle.Render(i18n.T("Variants")+":") + "\n")
+
+		for _, variant := range state.preview.Variants {
+			b.WriteString("  " + variant.MediaType + "\n")
+		}
+	}
+
+	if video.Description != "" {
+		b.WriteString("\n" + lipgloss.NewStyle().Width(max(s.width-1, 1)).Render(video.Description) + "\n")
+	}
+
+	check := i18n.T("not selected")
+	if s.selected[index] {
+		check = i18n.T("selected")
+	}
+
+	b.WriteString("\n" + selectorHelpStyle.Render(check+" • "+i18n.T("space: toggle • tab/←: back to the list")))
+
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package progress
+
+import (
+	"time"
+
+	"switchtube-downloader/internal/helper/ui/styles"
+	"switchtube-downloader/internal/i18n"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+//nolint:gochecknoglobals // Read-only style
+var stylePaused = lipgloss.NewStyle().Foreground(styles.Cyan).Bold(true)
+
+// Batch aggregates the downloads of a multi-file run for the live stats line.
+type Batch struct {
+	lastSample time.Time // Time of the last speed sample
+	written    int64     // Bytes written by all downloads
+	sampled    int64     // Bytes written at the last speed sample
+	speed      float64   // Smoothed total speed in bytes per second
+	jobs       int       // Number of downloads in the run
+	active     int       // Downloads currently transferring
+	done       int       // Finished downloads, successful or not
+	paused     bool      // Whether the transfers are paused
+}
+
+// NewBatch creates a Batch for a run of jobs downloads.
+func NewBatch(jobs int) *Batch {
+	return &Batch{jobs: jobs, lastSample: time.Now()}
+}
+
+// Render renders the stats line as of the last sample.
+func (b *Batch) Render() string {
+	displaySpeed, unit := formatSpeed(b.speed)
+	queued := b.queued()
+
+	line := styleDim.Render(i18n.T(
+		"Total %6.2f %s · %d active · %d queued · %d/%d done · %s",
+		displaySpeed, unit, b.active, queued, b.done, b.jobs, formatSize(b.written),
+	))
+
+	if b.paused {
+		return stylePaused.Render(i18n.T("Paused, press p to resume")) + "  " + line
+	}
+
+	return line
+}
+
+// sample updates the smoothed total speed with the bytes written since the last sample.
+func (b *Batch) sample() {
+	now := time.Now()
+	if interval := now.Sub(b.lastSample); interval >= minUpdateGap {
+		b.speed = smoothSpeed(b.speed, float64(b.written-b.sampled)/interval.Seconds(), interval)
+		b.lastSample = now
+		b.sampled = b.written
+	}
+}
+
+// queued returns the number of downloads that did not start yet.
+func (b *Batch) queued() int {
+	return max(b.jobs-b.active-b.done, 0)
+}
+
+// Set replaces the aggregated state and samples the total speed, for callers that only
+// know the progress of each download.
+func (b *Batch) Set(written int64, active int, done int) {
+	b.written = written
+	b.active = active
+	b.done = done
+	b.sample()
+}
+
+// SetPaused marks the transfers as paused or resumed. The speed restarts from zero.
+func (b *Batch) SetPaused(paused bool) {
+	b.paused = paused
+	b.speed = 0
+}
+
+// EndBatch draws the final stats line and stops drawing the progress region.
+func EndBatch() {
+	stopActive()
+}
+
+// JobFinished marks a download of the active batch as finished.
+func JobFinished() {
+	displayMutex.Lock()
+	defer displayMutex.Unlock()
+
+	if active != nil && active.batch != nil {
+		active.batch.active--
+		active.batch.done++
+	}
+}
+
+// JobStarted marks a download of the active batch as started.
+func JobStarted() {
+	displayMutex.Lock()
+	defer displayMutex.Unlock()
+
+	if active != nil && active.batch != nil {
+		active.batch.active++
+	}
+}
+
+// SetPaused marks the transfers of the active batch or single download as paused or
+// resumed.
+func SetPaused(paused bool) {
+	displayMutex.Lock()
+	defer displayMutex.Unlock()
+
+	if active == nil {
+		return
+	}
+
+	active.paused = paused
+
+	if active.batch != nil {
+		active.batch.SetPaused(paused)
+	}
+}
+
+// StartBatch starts drawing the progress region of a run of jobs downloads, with the
+// stats line of the run above the bars.
+func StartBatch(jobs int) {
+	displayMutex.Lock()
+	defer displayMutex.Unlock()
+
+	active = newRenderer(NewBatch(jobs))
+}
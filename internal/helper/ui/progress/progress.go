@@ -2,11 +2,10 @@ package progress
 
 import (
 	"fmt"
-	"os"
+	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/lipgloss"
-	xterm "github.com/charmbracelet/x/term"
 )
 
 const (
@@ -14,6 +13,11 @@ const (
 	minBarWidth = 10
 	// statsWidth is the fixed width of the stats suffix (e.g. " 100.0%  99.99 Gb/s").
 	statsWidth = 22
+	// detailsWidth is the fixed width of the average speed, size and ETA suffix
+	// (e.g. "  avg  12.34 Mb/s    312.5 MiB / 1.2 GiB    ETA 01:23").
+	detailsWidth = 53
+	// defaultWidth is the terminal width assumed if it cannot be determined.
+	defaultWidth = 80
 )
 
 var (
@@ -25,22 +29,56 @@ var (
 	)
 )
 
-// barWidth calculates how wide the progress bar should be given the current
-// terminal width and the filename column width.
-func barWidth(filenameWidth int) int {
+// barWidth calculates how wide the progress bar should be given the terminal width,
+// the filename column width and the width of the stats suffix.
+// Returns false if the bar would be narrower than minBarWidth.
+func barWidth(width int, filenameWidth int, suffixWidth int) (int, bool) {
 	const minPrefixGap = 1
 
-	w, _, err := xterm.GetSize(os.Stdout.Fd())
-	if err != nil || w <= 0 {
-		w = 80
+	available := width - filenameWidth - minPrefixGap - suffixWidth
+	if available < minBarWidth {
+		return minBarWidth, false
 	}
 
-	available := w - filenameWidth - minPrefixGap - statsWidth
-	if available < minBarWidth {
-		return minBarWidth
+	return available, true
+}
+
+// formatETA formats the remaining time as mm:ss, or h:mm:ss for long downloads.
+func formatETA(eta time.Duration) string {
+	if eta < 0 {
+		return "--:--"
+	}
+
+	eta = eta.Round(time.Second)
+	hours := int(eta / time.Hour)
+	minutes := int(eta % time.Hour / time.Minute)
+	seconds := int(eta % time.Minute / time.Second)
+
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
 	}
 
-	return available
+	return fmt.Sprintf("%02d:%02d", minutes, seconds)
+}
+
+// formatSize converts a byte count to binary units (e.g. "312.5 MiB").
+func formatSize(bytes int64) string {
+	const unit = 1024
+
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	value := float64(bytes) / unit
+	for _, suffix := range []string{"KiB", "MiB", "GiB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+
+		value /= unit
+	}
+
+	return fmt.Sprintf("%.1f TiB", value)
 }
 
 // formatSpeed converts bytes per second to appropriate units (Gb/s, Mb/s, Kb/s, b/s).
@@ -63,14 +101,40 @@ func formatSpeed(bytePerSec float64) (float64, string) {
 	}
 }
 
-// renderProgressBar renders a progress bar sized to the terminal width.
-func renderProgressBar(percentage float64, bytePerSec float64, filenameWidth int) string {
-	bw := barWidth(filenameWidth)
+// renderProgressBar renders a progress bar sized to the given terminal width. speed is
+// the current speed shown next to the percentage, average the speed since the start.
+// The average, the transferred size and the ETA (negative if unknown) are only shown if
+// the terminal is wide enough. A total of -1 means the size is unknown.
+func renderProgressBar(
+	percentage float64,
+	speed float64,
+	average float64,
+	written int64,
+	total int64,
+	eta time.Duration,
+	filenameWidth int,
+	width int,
+) string {
+	displaySpeed, unit := formatSpeed(speed)
+	stats := fmt.Sprintf("%5.1f%% %s", percentage, styleDim.Render(fmt.Sprintf("%6.2f %s", displaySpeed, unit)))
 
-	pb.Width = bw
-	renderedBar := pb.ViewAs(percentage / 100)
+	bw, fits := barWidth(width, filenameWidth, statsWidth+detailsWidth)
+	if fits {
+		totalSize := "?"
+		if total >= 0 {
+			totalSize = formatSize(total)
+		}
+
+		averageSpeed, averageUnit := formatSpeed(average)
+		stats += styleDim.Render(fmt.Sprintf(
+			"  avg %6.2f %-4s  %10s / %-10s ETA %s",
+			averageSpeed, averageUnit, formatSize(written), totalSize, formatETA(eta),
+		))
+	} else {
+		bw, _ = barWidth(width, filenameWidth, statsWidth)
+	}
 
-	displaySpeed, unit := formatSpeed(bytePerSec)
+	pb.Width = bw
 
-	return fmt.Sprintf("%s %5.1f%% %s", renderedBar, percentage, styleDim.Render(fmt.Sprintf("%6.2f %s", displaySpeed, unit)))
+	return fmt.Sprintf("%s %s", pb.ViewAs(percentage/100), stats)
 }
@@ -4,111 +4,172 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"path/filepath"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/charmbracelet/x/ansi"
 )
 
 const (
-	refreshRateMs = 100
-	minUpdateGap  = 50 * time.Millisecond
+	minUpdateGap = 50 * time.Millisecond
+	// speedHalfLife is the time after which a speed sample counts half as much in the
+	// smoothed current speed, so the speed recovers within seconds after a stall.
+	speedHalfLife = 2 * time.Second
 )
 
-//nolint:gochecknoglobals // displayMutex is used across multiple goroutines for progress bar synchronization
-var displayMutex sync.Mutex // Prevents concurrent display updates
-
 var errFailedToCopyData = errors.New("failed to copy data")
 
-// progressWriter wraps an io.Writer and tracks progress.
-type progressWriter struct {
-	startTime       time.Time // Start time for speed calculation
-	lastUpdate      time.Time // Last progress update time
-	writer          io.Writer // Underlying destination writer
-	filename        string    // File being downloaded
-	total           int64     // Expected total bytes
-	written         int64     // Bytes written so far
-	rowIndex        int       // Row index for multi-line progress display
-	longestFilename int       // Longest filename for alignment
+// bar is the progress of a single download, drawn by the active renderer.
+type bar struct {
+	startTime     time.Time    // Start time for speed calculation
+	lastSample    time.Time    // Time of the last speed sample
+	current       func() int64 // Returns the bytes written so far
+	filename      string       // File being downloaded
+	total         int64        // Expected total bytes, -1 if unknown
+	written       int64        // Bytes written at the last update
+	sampled       int64        // Bytes written at the last speed sample
+	smoothedSpeed float64      // Exponentially smoothed current speed, also used for the ETA
+	finished      bool         // Whether the download ended, its line then shows the average speed
+	nameWidth     int          // Width of the filename column for alignment
+}
+
+// countingWriter wraps an io.Writer and counts the bytes written.
+type countingWriter struct {
+	writer  io.Writer
+	written atomic.Int64
+}
+
+// Write implements io.Writer.
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.writer.Write(p)
+	cw.written.Add(int64(n))
+
+	return n, err //nolint:wrapcheck // Wrapped once by Copy
+}
+
+// Copy copies data from src to dst through buffer while showing a progress bar for filename.
+// total is -1 if the size is unknown, nameWidth aligns the bars of a batch (0 for none),
+// a nil buffer allocates one of the io.Copy default size. Returns error if data copying fails.
+func Copy(src io.Reader, dst io.Writer, buffer []byte, total int64, filename string, nameWidth int) error {
+	cw := &countingWriter{writer: dst}
+
+	release := add(newBar(filename, total, nameWidth, cw.written.Load))
+	defer release()
+
+	if _, err := io.CopyBuffer(cw, src, buffer); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToCopyData, err)
+	}
+
+	return nil
 }
 
-// Write implements io.Writer and updates progress.
-func (pw *progressWriter) Write(p []byte) (int, error) {
-	n, err := pw.writer.Write(p)
-	pw.written += int64(n)
+// Track shows a progress bar for a download whose written bytes are reported by current,
+// e.g. a file written by another process or by concurrent segments, until done is closed.
+// nameWidth aligns the bars of a batch (0 for none).
+func Track(filename string, total int64, nameWidth int, current func() int64, done <-chan struct{}) {
+	release := add(newBar(filename, total, nameWidth, current))
+	defer release()
+
+	<-done
+}
 
+// newBar creates the bar of a download that starts now.
+func newBar(filename string, total int64, nameWidth int, current func() int64) *bar {
 	now := time.Now()
-	if now.Sub(pw.lastUpdate) >= minUpdateGap {
-		pw.lastUpdate = now
-		pw.displayProgress()
+
+	return &bar{
+		startTime:  now,
+		lastSample: now,
+		current:    current,
+		filename:   filename,
+		total:      total,
+		nameWidth:  nameWidth,
 	}
+}
+
+// render renders the line of the download as of the last update for a terminal of the
+// given width.
+func (b *bar) render(width int) string {
+	written := b.written
+	percentage, speed, average, eta := b.stats()
+
+	basename := filepath.Base(b.filename)
 
-	if err != nil {
-		return n, fmt.Errorf("%w: %w", errFailedToCopyData, err)
+	// Add padding for alignment if needed, measured in terminal cells so accented and
+	// wide characters line up
+	if nameWidth := ansi.StringWidth(basename); nameWidth < b.nameWidth {
+		basename += strings.Repeat(" ", b.nameWidth-nameWidth)
 	}
 
-	return n, nil
+	return basename + " " + renderProgressBar(percentage, speed, average, written, b.total, eta, max(b.nameWidth, ansi.StringWidth(basename)), width)
 }
 
-// displayProgress renders the progress bar to stdout.
-func (pw *progressWriter) displayProgress() {
+// stats returns the percentage done, the current and the average speed in bytes per
+// second and the estimated remaining time, negative if unknown, as of the last update.
+func (b *bar) stats() (float64, float64, float64, time.Duration) {
 	const divByZeroGuard = 0.001
 
-	elapsed := max(time.Since(pw.startTime).Seconds(), divByZeroGuard)
+	written := b.written
+
+	elapsed := max(time.Since(b.startTime).Seconds(), divByZeroGuard)
 
 	percentage := 0.0
-	if pw.total > 0 {
-		percentage = (float64(pw.written) / float64(pw.total)) * 100
+	if b.total > 0 {
+		percentage = min(float64(written)/float64(b.total), 1) * 100
 	}
 
-	speed := (float64(pw.written) / elapsed)
-
-	displayMutex.Lock()
-	defer displayMutex.Unlock()
+	average := float64(written) / elapsed
+	eta := b.estimateRemaining()
 
-	basename := filepath.Base(pw.filename)
-
-	// Add padding for alignment if needed
-	if (pw.longestFilename > 0) && (len(basename) < pw.longestFilename) {
-		basename += strings.Repeat(" ", pw.longestFilename-len(basename))
+	// The current speed is unknown until the first sample and meaningless once finished
+	speed := b.smoothedSpeed
+	if b.finished || b.sampled == 0 && written > 0 {
+		speed = average
 	}
 
-	fmt.Print(ansi.SaveCurrentCursorPosition)
+	return percentage, speed, average, eta
+}
+
+// update fetches the bytes written so far, samples the speed and returns how many bytes
+// were added since the last update.
+func (b *bar) update() int64 {
+	previous := b.written
+	b.written = b.current()
 
-	if pw.rowIndex > 0 {
-		fmt.Print(ansi.CursorUp(pw.rowIndex))
+	now := time.Now()
+	if interval := now.Sub(b.lastSample); interval >= minUpdateGap {
+		b.smoothedSpeed = smoothSpeed(b.smoothedSpeed, float64(b.written-b.sampled)/interval.Seconds(), interval)
+		b.lastSample = now
+		b.sampled = b.written
 	}
-	fmt.Printf("\r%s%s %s", ansi.EraseLineRight, basename, renderProgressBar(percentage, speed, pw.longestFilename))
-	fmt.Print(ansi.RestoreCurrentCursorPosition)
+
+	return b.written - previous
 }
 
-// BarWithRow copies data from src to dst while displaying a progress bar.
-// rowIndex positions the progress bar for multi-file downloads (0 for single file).
-// Returns error if data copying fails.
-func BarWithRow(src io.Reader, dst io.Writer, total int64, filename string, rowIndex int, longestFilename int) error {
-	pw := &progressWriter{
-		writer:          dst,
-		total:           total,
-		written:         0,
-		startTime:       time.Now(),
-		lastUpdate:      time.Now(),
-		filename:        filename,
-		rowIndex:        rowIndex,
-		longestFilename: longestFilename,
+// estimateRemaining returns the time until completion at the smoothed speed as of the
+// last update, or -1 if unknown.
+func (b *bar) estimateRemaining() time.Duration {
+	if b.total <= 0 || b.smoothedSpeed <= 0 {
+		return -1
 	}
 
-	if _, err := io.Copy(pw, src); err != nil {
-		return fmt.Errorf("%w: %w", errFailedToCopyData, err)
-	}
+	remaining := float64(max(b.total-b.written, 0)) / b.smoothedSpeed
 
-	// Final update
-	pw.displayProgress()
+	return time.Duration(remaining * float64(time.Second))
+}
 
-	if rowIndex == 0 {
-		fmt.Println()
+// smoothSpeed returns the exponentially weighted moving average of the previous smoothed
+// speed and a sample taken over interval. The weight of the sample grows with the
+// interval, so the result does not depend on how often samples are taken.
+func smoothSpeed(previous float64, sample float64, interval time.Duration) float64 {
+	if previous == 0 {
+		return sample
 	}
 
-	return nil
+	weight := 1 - math.Exp2(-interval.Seconds()/speedHalfLife.Seconds())
+
+	return weight*sample + (1-weight)*previous
 }
@@ -0,0 +1,392 @@
+package progress
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+	xterm "github.com/charmbracelet/x/term"
+
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/redact"
+)
+
+const (
+	// localRefreshRate is the interval the region is redrawn at on a local terminal.
+	localRefreshRate = 100 * time.Millisecond
+	// remoteRefreshRate is the interval the region is redrawn at over SSH, where every
+	// redraw is sent over the network.
+	remoteRefreshRate = 500 * time.Millisecond
+	// maxRefreshRate is the longest interval the region is redrawn at on slow terminals.
+	maxRefreshRate = 2 * time.Second
+	// slowDrawRatio is the share of the interval (1/slowDrawRatio) a redraw may take
+	// before the interval is doubled.
+	slowDrawRatio = 10
+)
+
+var errNegativeRefreshRate = errors.New("refresh rate must not be negative")
+
+// refreshRate is the interval the region is redrawn at, 0 to adapt it to the terminal.
+//
+//nolint:gochecknoglobals // Set once at startup from the global --refresh-rate flag
+var refreshRate time.Duration
+
+//nolint:gochecknoglobals // displayMutex guards the active renderer across the download goroutines
+var (
+	displayMutex sync.Mutex // Guards active and all state drawn by it
+	active       *renderer  // Renderer of the running downloads, nil if none
+)
+
+// renderer draws the bars of the running downloads and the stats line of the batch in
+// a region at the bottom of the terminal. It is the only writer of that region: bars
+// and the batch only update their state, and a single goroutine redraws the whole
+// region at a fixed rate. Finished bars and other output are printed above the region,
+// so it never grows beyond the running downloads and scrolls along with the terminal.
+type renderer struct {
+	out       io.Writer     // Stream the region is drawn to
+	batch     *Batch        // Stats of the run, nil for a single download
+	stop      chan struct{} // Closed to stop the redraw goroutine
+	done      chan struct{} // Closed once the redraw goroutine stopped
+	bars      []*bar        // Running downloads in the order they started
+	pending   []byte        // Output without trailing newline, printed once the line is complete
+	lines     int           // Number of lines of the region drawn last
+	terminal  bool          // Whether the region is redrawn in place, false for logs and pipes
+	snapshots bool          // Whether the progress is written to the progress file
+	paused    bool          // Whether the transfers are paused, shown by the batch if there is one
+}
+
+// lineWriter prints output above the progress region while downloads are running.
+type lineWriter struct{}
+
+// Write implements io.Writer. Complete lines are printed above the region, the rest is
+// kept until its line is complete. Credentials are masked, as messages may quote errors.
+func (lineWriter) Write(p []byte) (int, error) {
+	displayMutex.Lock()
+	defer displayMutex.Unlock()
+
+	redacted := []byte(redact.String(string(p)))
+
+	if active == nil {
+		if _, err := stream.UI().Write(redacted); err != nil {
+			return 0, err //nolint:wrapcheck // Plain passthrough to the UI stream
+		}
+
+		return len(p), nil
+	}
+
+	active.print(redacted)
+
+	return len(p), nil
+}
+
+// Writer returns a writer for status messages that keeps them from tearing the progress
+// bars: while downloads are running the messages are printed above the bars, otherwise
+// they are written to the UI stream directly.
+func Writer() io.Writer {
+	return lineWriter{}
+}
+
+// stderrWriter prints output like lineWriter if the UI stream is stderr, and to stderr
+// otherwise.
+type stderrWriter struct{}
+
+// Write implements io.Writer.
+func (stderrWriter) Write(p []byte) (int, error) {
+	if stream.UI() == os.Stderr {
+		return lineWriter{}.Write(p)
+	}
+
+	if _, err := os.Stderr.WriteString(redact.String(string(p))); err != nil {
+		return 0, err //nolint:wrapcheck // Plain passthrough to stderr
+	}
+
+	return len(p), nil
+}
+
+// ErrWriter returns a writer for diagnostics that must never end up on stdout, e.g. retry
+// notes: like Writer while the UI stream is stderr, and stderr otherwise.
+func ErrWriter() io.Writer {
+	return stderrWriter{}
+}
+
+// newRenderer creates a renderer for the batch, nil for a single download, and starts
+// redrawing it if the UI stream is a terminal and writing the progress file if one is
+// set. Caller must hold displayMutex.
+func newRenderer(batch *Batch) *renderer {
+	r := &renderer{
+		out:       stream.UI(),
+		batch:     batch,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		terminal:  stream.IsTerminal(),
+		snapshots: progressFile != "",
+	}
+
+	if !r.terminal && !r.snapshots {
+		close(r.done)
+
+		return r
+	}
+
+	if r.terminal {
+		fmt.Fprint(r.out, ansi.HideCursor)
+	}
+
+	go r.run()
+
+	return r
+}
+
+// SetRefreshRate makes the progress bars redraw at a fixed interval instead of one
+// adapted to the terminal, e.g. less often to save CPU on small devices. 0 adapts it.
+func SetRefreshRate(rate time.Duration) error {
+	if rate < 0 {
+		return errNegativeRefreshRate
+	}
+
+	refreshRate = rate
+
+	return nil
+}
+
+// add starts drawing b and returns a function that prints its final line above the
+// region and stops drawing it. Without active renderer, one is started for the bar.
+func add(b *bar) func() {
+	displayMutex.Lock()
+	defer displayMutex.Unlock()
+
+	owned := active == nil
+	if owned {
+		active = newRenderer(nil)
+	}
+
+	r := active
+	r.bars = append(r.bars, b)
+
+	return func() {
+		displayMutex.Lock()
+		r.bars = slices.DeleteFunc(r.bars, func(other *bar) bool { return other == b })
+		r.update(b)
+		b.finished = true
+		r.print([]byte(b.render(r.width()) + "\n"))
+		displayMutex.Unlock()
+
+		if owned {
+			stopActive()
+		}
+	}
+}
+
+// stopActive draws the final state of the active renderer and stops it.
+func stopActive() {
+	displayMutex.Lock()
+	r := active
+	displayMutex.Unlock()
+
+	if r == nil {
+		return
+	}
+
+	if r.terminal || r.snapshots {
+		close(r.stop)
+	}
+
+	<-r.done
+
+	displayMutex.Lock()
+	defer displayMutex.Unlock()
+
+	if len(r.pending) > 0 {
+		r.print([]byte("\n"))
+	}
+
+	r.refresh()
+
+	if r.terminal {
+		r.draw()
+		fmt.Fprint(r.out, ansi.ShowCursor)
+	} else if r.batch != nil {
+		fmt.Fprintln(r.out, r.batch.Render())
+	}
+
+	if r.snapshots {
+		_ = writeSnapshot(progressFile, r.snapshot(false)) // Checked at startup, pollers see the last state otherwise
+	}
+
+	active = nil
+}
+
+// clear erases the region drawn last and moves the cursor to its first line.
+// Caller must hold displayMutex.
+func (r *renderer) clear() string {
+	if r.lines == 0 {
+		return "\r"
+	}
+
+	return "\r" + ansi.CursorUp(r.lines) + ansi.EraseScreenBelow
+}
+
+// draw redraws the region as of the last refresh: the stats line of the batch followed
+// by the running bars. Lines are cut to the terminal width, as wrapped lines would shift
+// the region. Caller must hold displayMutex.
+func (r *renderer) draw() {
+	width := r.width()
+
+	var lines []string
+
+	for _, b := range r.bars {
+		lines = append(lines, b.render(width))
+	}
+
+	if r.batch != nil {
+		lines = append([]string{r.batch.Render()}, lines...)
+	} else if r.paused {
+		lines = append([]string{stylePaused.Render(i18n.T("Paused, press p to resume"))}, lines...)
+	}
+
+	var out strings.Builder
+
+	out.WriteString(r.clear())
+
+	for _, line := range lines {
+		out.WriteString(ansi.Truncate(line, width-1, "") + "\n")
+	}
+
+	r.lines = len(lines)
+
+	fmt.Fprint(r.out, out.String())
+}
+
+// print writes the complete lines of p above the region and redraws it.
+// Caller must hold displayMutex.
+func (r *renderer) print(p []byte) {
+	r.pending = append(r.pending, p...)
+
+	end := bytes.LastIndexByte(r.pending, '\n')
+	if end == -1 {
+		return
+	}
+
+	complete := r.pending[:end+1]
+	r.pending = slices.Clone(r.pending[end+1:])
+
+	if !r.terminal {
+		_, _ = r.out.Write(complete)
+
+		return
+	}
+
+	fmt.Fprint(r.out, r.clear())
+	_, _ = r.out.Write(complete)
+	r.lines = 0
+	r.draw()
+}
+
+// refresh fetches the progress of the running bars and samples the speeds. Drawing and
+// snapshots only read the state of the last refresh, so they do not skew the speeds.
+// Caller must hold displayMutex.
+func (r *renderer) refresh() {
+	for _, b := range r.bars {
+		r.update(b)
+	}
+
+	if r.batch != nil {
+		r.batch.sample()
+	}
+}
+
+// update fetches the progress of b and adds its new bytes to the batch.
+// Caller must hold displayMutex.
+func (r *renderer) update(b *bar) {
+	written := b.update()
+	if r.batch != nil {
+		r.batch.written += written
+	}
+}
+
+// run redraws the region on a terminal and rewrites the progress file if set until the
+// renderer is stopped. Without fixed refresh rate, the redraw interval is doubled up to
+// maxRefreshRate whenever a redraw takes longer than a tenth of it, as the terminal
+// cannot keep up, e.g. a serial console or a slow link.
+func (r *renderer) run() {
+	defer close(r.done)
+
+	interval := initialRefreshRate()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	snapshotTicker := time.NewTicker(snapshotInterval)
+	defer snapshotTicker.Stop()
+
+	redraws, snapshots := ticker.C, snapshotTicker.C
+	if !r.terminal {
+		redraws = nil
+	}
+
+	if !r.snapshots {
+		snapshots = nil
+	}
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-snapshots:
+			displayMutex.Lock()
+			// On a terminal the redraws refresh the progress often enough
+			if !r.terminal {
+				r.refresh()
+			}
+
+			s := r.snapshot(true)
+			displayMutex.Unlock()
+
+			_ = writeSnapshot(progressFile, s) // Checked at startup, the next snapshot retries
+		case <-redraws:
+			displayMutex.Lock()
+			started := time.Now()
+			r.refresh()
+			r.draw()
+			elapsed := time.Since(started)
+			displayMutex.Unlock()
+
+			if refreshRate == 0 && elapsed > interval/slowDrawRatio && interval < maxRefreshRate {
+				interval = min(2*interval, maxRefreshRate)
+				ticker.Reset(interval)
+			}
+		}
+	}
+}
+
+// initialRefreshRate returns the interval the region is first redrawn at: the fixed
+// refresh rate if set, otherwise less often over SSH than on a local terminal.
+func initialRefreshRate() time.Duration {
+	if refreshRate > 0 {
+		return refreshRate
+	}
+
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" {
+		return remoteRefreshRate
+	}
+
+	return localRefreshRate
+}
+
+// width returns the width of the terminal, 80 if unknown.
+func (r *renderer) width() int {
+	w, _, err := xterm.GetSize(stream.UI().Fd())
+	if err != nil || w <= 0 {
+		return defaultWidth
+	}
+
+	return w
+}
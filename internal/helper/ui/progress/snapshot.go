@@ -0,0 +1,249 @@
+package progress
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// snapshotInterval is the interval the progress file is rewritten at.
+	snapshotInterval = time.Second
+	// progressFilePermissions are the permissions of the progress file.
+	progressFilePermissions = 0o644
+)
+
+var errFailedToWriteProgressFile = errors.New("failed to write progress file")
+
+// progressFile is the file the progress is written to as JSON, empty if none.
+//
+//nolint:gochecknoglobals // Set once at startup from the global --progress-file flag
+var progressFile string
+
+// snapshot is the progress of the running downloads as written to the progress file.
+type snapshot struct {
+	UpdatedAt time.Time      `json:"updatedAt"`       // Time the snapshot was taken
+	Running   bool           `json:"running"`         // Whether downloads are running, false once they ended
+	Batch     *batchSnapshot `json:"batch,omitempty"` // Stats of the run, nil for a single download
+	Files     []fileSnapshot `json:"files"`           // Running downloads in the order they started
+}
+
+// batchSnapshot is the stats line of a run in the progress file.
+type batchSnapshot struct {
+	Jobs    int     `json:"jobs"`    // Number of downloads in the run
+	Active  int     `json:"active"`  // Downloads currently transferring
+	Queued  int     `json:"queued"`  // Downloads not started yet
+	Done    int     `json:"done"`    // Finished downloads, successful or not
+	Written int64   `json:"written"` // Bytes written by all downloads
+	Speed   float64 `json:"speed"`   // Smoothed total speed in bytes per second
+}
+
+// fileSnapshot is the progress of a single download in the progress file.
+type fileSnapshot struct {
+	File         string  `json:"file"`         // File being downloaded
+	Written      int64   `json:"written"`      // Bytes written so far
+	Total        int64   `json:"total"`        // Expected total bytes, -1 if unknown
+	Percent      float64 `json:"percent"`      // Share written, 0 if the total is unknown
+	Speed        float64 `json:"speed"`        // Smoothed current speed in bytes per second
+	AverageSpeed float64 `json:"averageSpeed"` // Speed since the start in bytes per second
+	ETA          int64   `json:"eta"`          // Estimated remaining seconds, -1 if unknown
+}
+
+// SetProgressFile makes running downloads rewrite a JSON snapshot of their progress to
+// path every second, for status bars and dashboards to poll. An idle snapshot is written
+// right away, so an unwritable path fails at startup. Empty disables the progress file.
+func SetProgressFile(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	if err := writeSnapshot(path, snapshot{UpdatedAt: time.Now(), Files: []fileSnapshot{}}); err != nil {
+		return err
+	}
+
+	progressFile = path
+
+	return nil
+}
+
+// snapshot returns the progress of the running downloads as of the last refresh for the
+// progress file. Caller must hold displayMutex.
+func (r *renderer) snapshot(running bool) snapshot {
+	s := snapshot{UpdatedAt: time.Now(), Running: running, Files: snapshotFiles(r.bars)}
+
+	if r.batch != nil {
+		s.Batch = &batchSnapshot{
+			Jobs:    r.batch.jobs,
+			Active:  r.batch.active,
+			Queued:  r.batch.queued(),
+			Done:    r.batch.done,
+			Written: r.batch.written,
+			Speed:   r.batch.speed,
+		}
+	}
+
+	return s
+}
+
+// snapshotFiles returns the progress of bars as of their last update.
+func snapshotFiles(bars []*bar) []fileSnapshot {
+	files := make([]fileSnapshot, 0, len(bars))
+
+	for _, b := range bars {
+		percentage, speed, average, eta := b.stats()
+
+		if eta >= 0 {
+			eta = eta.Round(time.Second)
+		}
+
+		files = append(files, fileSnapshot{
+			File:         b.filename,
+			Written:      b.written,
+			Total:        b.total,
+			Percent:      percentage,
+			Speed:        speed,
+			AverageSpeed: average,
+			ETA:          int64(eta / time.Second),
+		})
+	}
+
+	return files
+}
+
+// report is a download that reports its progress through Report instead of a bar.
+type report struct {
+	bar     *bar         // Progress as of the last snapshot, never drawn
+	written atomic.Int64 // Bytes written as of the last Report
+	total   atomic.Int64 // Expected total bytes as of the last Report, -1 if unknown
+}
+
+//nolint:gochecknoglobals // reportMutex guards the reported downloads across the download goroutines
+var (
+	reportMutex sync.Mutex         // Guards reports and reportStop
+	reports     []*report          // Reported downloads in the order they started
+	reportIDs   map[string]*report // Reported downloads by ID
+	reportStop  chan struct{}      // Closed to stop writing the reports, nil if not writing
+)
+
+// StartReport adds the download with the given ID, e.g. a video ID, to the progress file
+// until the returned function is called. Used by downloads that report their progress
+// through a callback instead of a bar, e.g. with --quiet or in the server, as no
+// renderer writes the progress file for them. The progress is set with Report. Does
+// nothing without progress file.
+func StartReport(id string, filename string) func() {
+	if progressFile == "" {
+		return func() {}
+	}
+
+	reportMutex.Lock()
+	defer reportMutex.Unlock()
+
+	rep := &report{}
+	rep.total.Store(-1)
+	rep.bar = newBar(filename, -1, 0, rep.written.Load)
+
+	if reportIDs == nil {
+		reportIDs = make(map[string]*report)
+	}
+
+	reports = append(reports, rep)
+	reportIDs[id] = rep
+
+	if reportStop == nil {
+		reportStop = make(chan struct{})
+		go writeReports(reportStop)
+	}
+
+	return func() {
+		reportMutex.Lock()
+		defer reportMutex.Unlock()
+
+		reports = slices.DeleteFunc(reports, func(other *report) bool { return other == rep })
+		if reportIDs[id] == rep {
+			delete(reportIDs, id)
+		}
+
+		if len(reports) == 0 {
+			close(reportStop)
+			reportStop = nil
+		}
+	}
+}
+
+// Report sets the bytes written and the expected total, -1 if unknown, of the download
+// started with StartReport under id. Reports of other downloads are ignored.
+func Report(id string, written int64, total int64) {
+	reportMutex.Lock()
+	rep := reportIDs[id]
+	reportMutex.Unlock()
+
+	if rep != nil {
+		rep.written.Store(written)
+		rep.total.Store(total)
+	}
+}
+
+// writeReports rewrites the progress file with the reported downloads every
+// snapshotInterval until stop is closed, and writes the final snapshot then.
+func writeReports(stop <-chan struct{}) {
+	ticker := time.NewTicker(snapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			_ = writeSnapshot(progressFile, snapshot{UpdatedAt: time.Now(), Files: []fileSnapshot{}}) // Checked at startup
+
+			return
+		case <-ticker.C:
+			reportMutex.Lock()
+
+			bars := make([]*bar, 0, len(reports))
+			for _, rep := range reports {
+				rep.bar.total = rep.total.Load()
+				rep.bar.update()
+				bars = append(bars, rep.bar)
+			}
+
+			s := snapshot{UpdatedAt: time.Now(), Running: true, Files: snapshotFiles(bars)}
+			reportMutex.Unlock()
+
+			_ = writeSnapshot(progressFile, s) // Checked at startup, the next snapshot retries
+		}
+	}
+}
+
+// writeSnapshot replaces the progress file at path with s. The snapshot is written next
+// to it and renamed, so a poller never reads a partial file.
+func writeSnapshot(path string, s snapshot) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToWriteProgressFile, err)
+	}
+
+	partial, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToWriteProgressFile, err)
+	}
+
+	_, err = partial.Write(append(data, '\n'))
+	err = errors.Join(err, partial.Chmod(progressFilePermissions), partial.Close())
+
+	if err == nil {
+		err = os.Rename(partial.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(partial.Name())
+
+		return fmt.Errorf("%w: %w", errFailedToWriteProgressFile, err)
+	}
+
+	return nil
+}
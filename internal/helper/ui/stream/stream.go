@@ -0,0 +1,109 @@
+// Package stream selects the stream progress bars, tables, prompts and status messages
+// are written to, so stdout stays free for machine-readable results.
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/x/term"
+	"github.com/muesli/termenv"
+
+	"switchtube-downloader/internal/i18n"
+)
+
+// Supported streams for human-facing output.
+const (
+	Stderr = "stderr"
+	Stdout = "stdout"
+)
+
+// Supported color preferences.
+const (
+	ColorAuto   = "auto"   // Colored if the stream is a terminal and NO_COLOR is unset
+	ColorAlways = "always" // Colored even if the stream is redirected
+	ColorNever  = "never"  // Plain text
+)
+
+var (
+	errInvalidColor  = errors.New("invalid color preference")
+	errInvalidStream = errors.New("invalid stream")
+)
+
+//nolint:gochecknoglobals // Set once at startup from the global --ui-stream flag
+var ui = os.Stderr
+
+// logWarnings is whether Warnf writes through the logger instead of to the given writer.
+//
+//nolint:gochecknoglobals // Set once at startup if the log goes to the journal
+var logWarnings bool
+
+// UI returns the stream human-facing output is written to, stderr by default.
+func UI() *os.File {
+	return ui
+}
+
+// IsTerminal reports whether human-facing output is shown in a terminal.
+func IsTerminal() bool {
+	return term.IsTerminal(ui.Fd())
+}
+
+// Select writes human-facing output to the named stream. Styles are rendered for that
+// stream, so colors survive when only the other stream is redirected.
+func Select(name string) error {
+	switch name {
+	case Stderr:
+		ui = os.Stderr
+	case Stdout:
+		ui = os.Stdout
+	default:
+		return fmt.Errorf("%w: %q (expected stderr or stdout)", errInvalidStream, name)
+	}
+
+	output := termenv.NewOutput(ui)
+	renderer := lipgloss.DefaultRenderer()
+	renderer.SetOutput(output)
+	renderer.SetColorProfile(output.EnvColorProfile())
+
+	return nil
+}
+
+// SetColor applies the color preference to the styles rendered for the selected stream.
+// An empty preference is ColorAuto. Call it after Select.
+func SetColor(preference string) error {
+	switch preference {
+	case "", ColorAuto:
+	case ColorAlways:
+		lipgloss.DefaultRenderer().SetColorProfile(termenv.ANSI)
+	case ColorNever:
+		lipgloss.DefaultRenderer().SetColorProfile(termenv.Ascii)
+	default:
+		return fmt.Errorf("%w: %q (expected auto, always or never)", errInvalidColor, preference)
+	}
+
+	return nil
+}
+
+// LogWarnings makes Warnf write warnings through the logger, e.g. when the log goes to
+// the journal, which then records them with warning priority.
+func LogWarnings() {
+	logWarnings = true
+}
+
+// Warnf prints a warning as a line to w, e.g. the UI stream or the writer for status
+// messages of the progress package, or through the logger after LogWarnings.
+func Warnf(w io.Writer, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+
+	if logWarnings {
+		log.Warn(message)
+
+		return
+	}
+
+	fmt.Fprintln(w, i18n.T("Warning: %s", message))
+}
@@ -2,15 +2,27 @@
 package table
 
 import (
+	"cmp"
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"switchtube-downloader/internal/helper/ui/input"
+	"switchtube-downloader/internal/helper/ui/stream"
 	"switchtube-downloader/internal/helper/ui/styles"
+	"switchtube-downloader/internal/history"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/settings"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/lipgloss/table"
 )
 
-const createAccessTokenURL = "https://tube.switch.ch/access_tokens"
+const accessTokensPath = "access_tokens"
 
 var (
 	borderStyle = lipgloss.NewStyle().Foreground(styles.Cyan)
@@ -40,31 +52,209 @@ func newTable() *table.Table {
 // DisplayInstructions shows token creation instructions in a table.
 func DisplayInstructions() {
 	t := newTable().
-		Headers("Token creation instructions").
-		Row("1. Visit: " + createAccessTokenURL).
-		Row("2. Click 'Create New Token'").
-		Row("3. Copy the generated token").
-		Row("4. Paste it below")
+		Headers(i18n.T("Token creation instructions")).
+		Row(i18n.T("1. Visit: %s", settings.BaseURL()+accessTokensPath)).
+		Row(i18n.T("2. Click 'Create New Token'")).
+		Row(i18n.T("3. Copy the generated token")).
+		Row(i18n.T("4. Paste it below"))
 
-	fmt.Println(t.Render())
+	fmt.Fprintln(stream.UI(), t.Render())
 }
 
-// DisplayTokenInfo shows token information in a table.
-func DisplayTokenInfo(service string, username string, valid bool, maskedToken string, tokenLength int) {
+// DisplayList shows rows of values below the given column headers.
+func DisplayList(headers []string, rows [][]string) {
+	t := newTable().Headers(headers...).Rows(rows...)
+
+	fmt.Fprintln(stream.UI(), t.Render())
+}
+
+// DisplayVideos lists the videos of a channel with their duration, publish date and
+// size, for output that is not a terminal where the interactive selection is unavailable.
+// Instead of a table, every video is a line of tab-separated fields below a header line,
+// so long titles cannot wrap and misalign the columns and piped output stays parseable.
+func DisplayVideos(videos []models.Video) {
+	var b strings.Builder
+
+	writeRow := func(fields ...string) {
+		for i, field := range fields {
+			// Tabs and line breaks in titles would shift the fields
+			fields[i] = strings.Join(strings.Fields(field), " ")
+		}
+
+		b.WriteString(strings.Join(fields, "\t") + "\n")
+	}
+
+	writeRow("#", "ID", i18n.T("Episode"), i18n.T("Title"), i18n.T("Duration"), i18n.T("Published"), i18n.T("Size"))
+
+	for i, video := range videos {
+		writeRow(append([]string{strconv.Itoa(i + 1), video.ID, video.Episode, video.Title}, input.VideoDetails(video)...)...)
+	}
+
+	fmt.Fprint(stream.UI(), b.String())
+}
+
+// DisplayVideoInfo shows the metadata of a video, its description and the details of its
+// variants, from the highest to the lowest quality, with the details read from the file
+// of each variant in files.
+func DisplayVideoInfo(video models.Video, variants []models.Variant, files []models.VariantFile) {
+	details := input.VideoDetails(video)
+	overview := newTable().
+		Headers(i18n.T("Video"), video.Title).
+		Row("ID", video.ID).
+		Row(i18n.T("Episode"), cmp.Or(video.Episode, "-")).
+		Row(i18n.T("Duration"), strings.TrimSpace(details[0])).
+		Row(i18n.T("Published"), strings.TrimSpace(details[1])).
+		Row(i18n.T("License"), cmp.Or(video.License, "-")).
+		Row(i18n.T("Page"), cmp.Or(video.URL, "-")).
+		Row(i18n.T("Thumbnail"), cmp.Or(video.ThumbnailURL, "-"))
+
+	fmt.Fprintln(stream.UI(), overview.Render())
+
+	if video.Description != "" {
+		fmt.Fprintln(stream.UI(), video.Description)
+	}
+
+	rows := make([][]string, len(variants))
+	for i, variant := range variants {
+		rows[i] = append([]string{strconv.Itoa(i + 1)}, input.VariantDetails(variant, files[i])...)
+	}
+
+	DisplayList([]string{"#", i18n.T("Media type"), i18n.T("Resolution"), i18n.T("Size")}, rows)
+}
+
+// DisplayTokenInfo shows token information in a table. The account of the profile is
+// shown if known.
+func DisplayTokenInfo(service string, username string, valid bool, maskedToken string, tokenLength int, profile models.TokenProfile) {
 	var status string
 	if valid {
-		status = styles.Success.Render("Valid")
+		status = styles.Success.Render(i18n.T("Valid"))
 	} else {
-		status = styles.Error.Render("Invalid")
+		status = styles.Error.Render(i18n.T("Invalid"))
 	}
 
 	t := newTable().
-		Headers("Field", "Value").
-		Row("Service", service).
-		Row("User", username).
-		Row("Token", maskedToken).
-		Row("Length", fmt.Sprintf("%d characters", tokenLength)).
-		Row("Status", status)
-
-	fmt.Println(t.Render())
+		Headers(i18n.T("Field"), i18n.T("Value")).
+		Row(i18n.T("Service"), service).
+		Row(i18n.T("User"), username).
+		Row(i18n.T("Token"), maskedToken).
+		Row(i18n.T("Length"), i18n.T("%d characters", tokenLength)).
+		Row(i18n.T("Status"), status)
+
+	if profile.Name != "" {
+		t.Row(i18n.T("Account"), profile.Name)
+	}
+
+	fmt.Fprintln(stream.UI(), t.Render())
+}
+
+// RenderDownloadStats renders the per-video transfer statistics followed by the
+// total downloaded, the overall average speed and the slowest file. The average speed
+// is the total over the wall time from the first start to the last finish, as parallel
+// downloads overlap.
+func RenderDownloadStats(stats []models.DownloadStat) string {
+	t := newTable().Headers(i18n.T("File"), i18n.T("Size"), i18n.T("Time"), i18n.T("Speed"))
+
+	var (
+		totalBytes int64
+		first      time.Time
+		last       time.Time
+	)
+
+	for _, stat := range stats {
+		t.Row(filepath.Base(stat.File), FormatBytes(stat.Bytes), formatDuration(stat.Elapsed), FormatBytes(int64(stat.Speed()))+"/s")
+
+		totalBytes += stat.Bytes
+
+		if first.IsZero() || stat.Started.Before(first) {
+			first = stat.Started
+		}
+
+		if finished := stat.Started.Add(stat.Elapsed); finished.After(last) {
+			last = finished
+		}
+	}
+
+	average := models.DownloadStat{Bytes: totalBytes, Elapsed: last.Sub(first)}
+	slowest := slices.MinFunc(stats, func(a models.DownloadStat, b models.DownloadStat) int {
+		return cmp.Compare(a.Speed(), b.Speed())
+	})
+
+	summary := newTable().
+		Headers(i18n.T("Summary"), i18n.T("Value")).
+		Row(i18n.T("Total downloaded"), i18n.T("%s in %d files", FormatBytes(totalBytes), len(stats))).
+		Row(i18n.T("Average speed"), FormatBytes(int64(average.Speed()))+"/s").
+		Row(i18n.T("Slowest file"), fmt.Sprintf("%s (%s/s)", filepath.Base(slowest.File), FormatBytes(int64(slowest.Speed()))))
+
+	return t.Render() + "\n" + summary.Render()
+}
+
+// DisplayHistoryStats shows the totals of the download history, followed by the
+// downloads per channel and the busiest days.
+func DisplayHistoryStats(summary history.Summary) {
+	channelCount := len(summary.Channels)
+	if slices.ContainsFunc(summary.Channels, func(c history.ChannelSummary) bool { return c.ChannelID == "" }) {
+		channelCount-- // Videos downloaded on their own
+	}
+
+	overview := newTable().
+		Headers(i18n.T("Summary"), i18n.T("Value")).
+		Row(i18n.T("Total downloaded"), i18n.T("%s in %d downloads", FormatBytes(summary.Bytes), summary.Downloads)).
+		Row(i18n.T("Distinct videos"), strconv.Itoa(summary.Videos)).
+		Row(i18n.T("Channels"), strconv.Itoa(channelCount)).
+		Row(i18n.T("First download"), summary.First.Local().Format(time.DateTime)).
+		Row(i18n.T("Latest download"), summary.Last.Local().Format(time.DateTime))
+
+	channels := newTable().Headers(i18n.T("Channel"), i18n.T("Videos"), i18n.T("Size"))
+
+	for _, channel := range summary.Channels {
+		name := channel.ChannelName
+		if channel.ChannelID == "" {
+			name = i18n.T("Single videos")
+		}
+
+		channels.Row(cmp.Or(name, channel.ChannelID), strconv.Itoa(channel.Videos), FormatBytes(channel.Bytes))
+	}
+
+	days := newTable().Headers(i18n.T("Busiest days"), i18n.T("Downloads"), i18n.T("Size"))
+
+	for _, day := range summary.Days {
+		days.Row(day.Day.Format(time.DateOnly), strconv.Itoa(day.Downloads), FormatBytes(day.Bytes))
+	}
+
+	fmt.Fprintln(stream.UI(), overview.Render())
+	fmt.Fprintln(stream.UI(), channels.Render())
+
+	if len(summary.Days) > 0 {
+		fmt.Fprintln(stream.UI(), days.Render())
+	}
+}
+
+// FormatBytes converts a byte count to a human readable size (e.g. "12.3 MB").
+func FormatBytes(n int64) string {
+	const unit = 1000
+
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	value := float64(n)
+	suffixes := []string{"kB", "MB", "GB", "TB"}
+
+	for _, suffix := range suffixes {
+		value /= unit
+		if value < unit || suffix == suffixes[len(suffixes)-1] {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+	}
+
+	return fmt.Sprintf("%d B", n)
+}
+
+// formatDuration rounds d for display (e.g. "1m23s").
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+
+	return d.Round(time.Second).String()
 }
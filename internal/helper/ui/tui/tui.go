@@ -0,0 +1,454 @@
+// Package tui provides a full-screen interface for browsing, selecting and downloading videos.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"switchtube-downloader/internal/download"
+	progressbar "switchtube-downloader/internal/helper/ui/progress"
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/settings"
+
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// screen identifies the step the interface is currently showing.
+type screen int
+
+const (
+	screenInput screen = iota
+	screenLoading
+	screenSelect
+	screenQuality
+	screenDownload
+	screenDone
+)
+
+// qualityOption is a selectable entry of the quality screen.
+type qualityOption struct {
+	label  string
+	policy models.QualityPolicy
+}
+
+var qualityOptions = []qualityOption{
+	{label: "Highest quality", policy: models.QualityHighest},
+	{label: "Lowest quality (smallest files)", policy: models.QualityLowest},
+}
+
+// lookupMsg carries the result of resolving the entered ID or URL.
+type lookupMsg struct {
+	listing *download.Listing
+	err     error
+}
+
+// progressMsg carries the progress of a single video.
+type progressMsg struct {
+	videoID string
+	written int64
+	total   int64
+}
+
+// downloadDoneMsg is sent once all selected videos have been processed.
+type downloadDoneMsg struct {
+	err     error
+	failed  []models.Video
+	aborted bool
+}
+
+// videoProgress is the last reported progress of a video.
+type videoProgress struct {
+	written int64
+	total   int64
+}
+
+// model is the Bubble Tea model of the interface.
+type model struct {
+	err        error                    // Error shown on the input screen
+	session    *download.Session        // Download engine
+	send       func(tea.Msg)            // Sends messages from download goroutines
+	cancel     context.CancelFunc       // Cancels the running download
+	listing    *download.Listing        // Resolved video or channel
+	selected   map[int]bool             // Selected video indices
+	progress   map[string]videoProgress // Progress per video ID
+	batch      *progressbar.Batch       // Aggregate stats of the running download
+	failed     []models.Video           // Failed videos of the last download
+	spinner    spinner.Model
+	input      textinput.Model
+	bar        progress.Model
+	screen     screen
+	cursor     int // Highlighted row on list screens
+	offset     int // First visible row of the video list
+	quality    int // Index into qualityOptions
+	width      int
+	height     int
+	useEpisode bool
+	aborted    bool
+}
+
+// newModel creates the model showing the input screen.
+func newModel(session *download.Session, useEpisode bool) *model {
+	input := textinput.New()
+	input.Placeholder = settings.BaseURL() + "channels/..."
+	input.Prompt = "> "
+	input.Focus()
+
+	return &model{
+		session:    session,
+		spinner:    spinner.New(spinner.WithSpinner(spinner.Dot)),
+		input:      input,
+		bar:        progress.New(progress.WithDefaultGradient(), progress.WithoutPercentage()),
+		selected:   make(map[int]bool),
+		progress:   make(map[string]videoProgress),
+		useEpisode: useEpisode,
+		width:      80,
+		height:     24,
+	}
+}
+
+// Init implements tea.Model.
+func (m *model) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update implements tea.Model.
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.bar.Width = max(m.width-m.titleWidth()-progressPadding, minTitleWidth)
+		m.scrollToCursor()
+
+		return m, nil
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	case lookupMsg:
+		return m.handleLookup(msg)
+	case progressMsg:
+		m.progress[msg.videoID] = videoProgress{written: msg.written, total: msg.total}
+		m.updateBatch()
+
+		return m, nil
+	case downloadDoneMsg:
+		m.screen = screenDone
+		m.failed = msg.failed
+		m.aborted = msg.aborted
+		m.err = msg.err
+		m.cancel = nil
+
+		return m, nil
+	case spinner.TickMsg:
+		if m.screen != screenLoading && m.screen != screenDownload {
+			return m, nil
+		}
+
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	if m.screen == screenInput {
+		m.input, cmd = m.input.Update(msg)
+	}
+
+	return m, cmd
+}
+
+// View implements tea.Model.
+func (m *model) View() string {
+	switch m.screen {
+	case screenInput:
+		return m.viewInput()
+	case screenLoading:
+		return m.viewLoading()
+	case screenSelect:
+		return m.viewSelect()
+	case screenQuality:
+		return m.viewQuality()
+	case screenDownload:
+		return m.viewDownload()
+	case screenDone:
+		return m.viewDone()
+	}
+
+	return ""
+}
+
+// handleKey dispatches key presses to the handler of the current screen.
+func (m *model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		if m.cancel != nil {
+			m.cancel() // Wait for the download to wind down
+
+			return m, nil
+		}
+
+		return m, tea.Quit
+	}
+
+	switch m.screen {
+	case screenInput:
+		return m.handleInputKey(msg)
+	case screenSelect:
+		return m.handleSelectKey(msg)
+	case screenQuality:
+		return m.handleQualityKey(msg)
+	case screenDownload:
+		return m.handleDownloadKey(msg)
+	case screenDone:
+		return m.handleDoneKey(msg)
+	case screenLoading:
+	}
+
+	return m, nil
+}
+
+// handleDoneKey handles keys on the summary screen.
+func (m *model) handleDoneKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.input.Reset()
+		m.err = nil
+		m.screen = screenInput
+
+		return m, textinput.Blink
+	case "q", "esc":
+		return m, tea.Quit
+	}
+
+	return m, nil
+}
+
+// handleDownloadKey handles keys on the download screen.
+func (m *model) handleDownloadKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	indices := m.selectedIndices()
+
+	switch msg.String() {
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = min(m.cursor+1, len(indices)-1)
+	case "t":
+		m.session.Bump(m.listing.Videos[indices[m.cursor]].ID)
+	case "p":
+		m.batch.SetPaused(m.session.TogglePause())
+	}
+
+	m.scrollToCursor()
+
+	return m, nil
+}
+
+// handleInputKey handles keys on the input screen.
+func (m *model) handleInputKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		return m, tea.Quit
+	case "enter":
+		media := m.input.Value()
+		if media == "" {
+			return m, nil
+		}
+
+		m.err = nil
+		m.screen = screenLoading
+
+		return m, tea.Batch(m.spinner.Tick, m.lookup(media))
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+
+	return m, cmd
+}
+
+// handleLookup shows the resolved listing, or the error on the input screen.
+func (m *model) handleLookup(msg lookupMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.err = msg.err
+		m.screen = screenInput
+
+		return m, textinput.Blink
+	}
+
+	m.listing = msg.listing
+	m.cursor, m.offset = 0, 0
+	m.selected = make(map[int]bool, len(msg.listing.Videos))
+
+	for i := range msg.listing.Videos {
+		m.selected[i] = true
+	}
+
+	m.screen = screenSelect
+	if !msg.listing.IsChannel {
+		m.cursor = m.quality
+		m.screen = screenQuality
+	}
+
+	return m, nil
+}
+
+// handleQualityKey handles keys on the quality screen.
+func (m *model) handleQualityKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = min(m.cursor+1, len(qualityOptions)-1)
+	case "esc":
+		m.cursor, m.offset = 0, 0
+
+		m.screen = screenSelect
+		if !m.listing.IsChannel {
+			m.screen = screenInput
+		}
+	case "enter":
+		m.quality = m.cursor
+		m.progress = make(map[string]videoProgress)
+		m.batch = progressbar.NewBatch(len(m.selectedIndices()))
+		m.cursor, m.offset = 0, 0
+		m.screen = screenDownload
+
+		return m, tea.Batch(m.spinner.Tick, m.startDownload())
+	}
+
+	return m, nil
+}
+
+// handleSelectKey handles keys on the video selection screen.
+func (m *model) handleSelectKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	videos := m.listing.Videos
+
+	switch msg.String() {
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = min(m.cursor+1, len(videos)-1)
+	case " ", "x":
+		m.selected[m.cursor] = !m.selected[m.cursor]
+	case "a":
+		all := len(m.selectedIndices()) < len(videos)
+		for i := range videos {
+			m.selected[i] = all
+		}
+	case "esc":
+		m.screen = screenInput
+
+		return m, textinput.Blink
+	case "enter":
+		if len(m.selectedIndices()) > 0 {
+			m.cursor = m.quality
+			m.screen = screenQuality
+		}
+
+		return m, nil
+	}
+
+	m.scrollToCursor()
+
+	return m, nil
+}
+
+// lookup resolves media in the background.
+func (m *model) lookup(media string) tea.Cmd {
+	session := m.session
+
+	return func() tea.Msg {
+		listing, err := session.Lookup(context.Background(), media)
+
+		return lookupMsg{listing: listing, err: err}
+	}
+}
+
+// scrollToCursor moves the visible window of the video list so the cursor stays visible.
+func (m *model) scrollToCursor() {
+	height := m.listHeight()
+	if m.screen == screenDownload {
+		height = m.downloadRows()
+	}
+
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+}
+
+// selectedIndices returns the selected video indices in list order.
+func (m *model) selectedIndices() []int {
+	indices := make([]int, 0, len(m.selected))
+
+	for i := range m.listing.Videos {
+		if m.selected[i] {
+			indices = append(indices, i)
+		}
+	}
+
+	return indices
+}
+
+// startDownload downloads the selected videos in the background, reporting progress as messages.
+func (m *model) startDownload() tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	session, send, listing := m.session, m.send, m.listing
+	indices := m.selectedIndices()
+	quality := qualityOptions[m.quality].policy
+
+	return func() tea.Msg {
+		failed, err := session.Download(ctx, listing, indices, quality, func(videoID string, written int64, total int64) {
+			send(progressMsg{videoID: videoID, written: written, total: total})
+		})
+
+		return downloadDoneMsg{err: err, failed: failed, aborted: ctx.Err() != nil}
+	}
+}
+
+// updateBatch aggregates the progress of all videos into the stats of the running download.
+func (m *model) updateBatch() {
+	var (
+		written      int64
+		active, done int
+	)
+
+	for _, p := range m.progress {
+		written += p.written
+
+		if p.total > 0 && p.written >= p.total {
+			done++
+		} else {
+			active++
+		}
+	}
+
+	m.batch.Set(written, active, done)
+}
+
+// Run starts the full-screen interface and blocks until the user quits.
+func Run(config models.DownloadConfig) error {
+	// Prompts cannot be shown inside the interface, existing files are skipped unless forced
+	config.Skip = !config.Force
+
+	session, err := download.NewSession(config, io.Discard)
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+
+	m := newModel(session, config.UseEpisode)
+	program := tea.NewProgram(m, tea.WithAltScreen(), tea.WithOutput(stream.UI()))
+	m.send = program.Send
+
+	if _, err := program.Run(); err != nil {
+		return fmt.Errorf("failed to run interface: %w", err)
+	}
+
+	return nil
+}
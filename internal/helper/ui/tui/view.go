@@ -0,0 +1,192 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"switchtube-downloader/internal/helper/ui/styles"
+	"switchtube-downloader/internal/i18n"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// chromeLines is the number of lines used by header and footer around lists.
+	chromeLines = 6
+	// batchLines is the number of lines used by the aggregate stats on the download screen.
+	batchLines = 2
+	// minTitleWidth is the minimum width of the title column on the download screen.
+	minTitleWidth = 20
+	// progressPadding is the width of the gaps and the percentage next to a progress bar.
+	progressPadding = 10
+)
+
+var (
+	titleStyle  = lipgloss.NewStyle().Foreground(styles.Cyan).Bold(true).MarginBottom(1)
+	cursorStyle = lipgloss.NewStyle().Foreground(styles.Cyan).Bold(true)
+	helpStyle   = lipgloss.NewStyle().Faint(true).MarginTop(1)
+)
+
+// downloadRows returns the number of progress rows that fit on the download screen.
+func (m *model) downloadRows() int {
+	return max(m.listHeight()-batchLines, 1)
+}
+
+// header renders the title line shown on every screen.
+func (m *model) header(subtitle string) string {
+	return titleStyle.Render("SwitchTube Downloader · " + subtitle)
+}
+
+// listHeight returns the number of list rows that fit on screen.
+func (m *model) listHeight() int {
+	return max(m.height-chromeLines, 1)
+}
+
+// titleWidth returns the width of the title column on the download screen.
+func (m *model) titleWidth() int {
+	return max(m.width/3, minTitleWidth)
+}
+
+// videoLabel returns the display label of the video at index i.
+func (m *model) videoLabel(i int) string {
+	video := m.listing.Videos[i]
+	if m.useEpisode && video.Episode != "" {
+		return video.Episode + "  " + video.Title
+	}
+
+	return video.Title
+}
+
+// viewDone renders the summary screen.
+func (m *model) viewDone() string {
+	var b strings.Builder
+
+	b.WriteString(m.header(m.listing.Name) + "\n")
+
+	switch {
+	case m.err != nil:
+		b.WriteString(styles.Error.Render("[ERROR]") + " " + m.err.Error() + "\n")
+	case m.aborted:
+		b.WriteString(styles.Error.Render("[ERROR]") + " " + i18n.T("Download aborted by user") + "\n")
+	default:
+		total := len(m.selectedIndices())
+		fmt.Fprintf(&b, "%s %s\n", styles.Success.Render(i18n.T("Download complete!")), i18n.T("%d/%d videos successful", total-len(m.failed), total))
+	}
+
+	if len(m.failed) > 0 && !m.aborted {
+		b.WriteString(styles.Error.Render(i18n.T("Failed downloads:")) + "\n")
+
+		for _, video := range m.failed {
+			b.WriteString("  - " + video.Title + "\n")
+		}
+	}
+
+	b.WriteString(helpStyle.Render(i18n.T("enter: download more • q: quit")))
+
+	return b.String()
+}
+
+// viewDownload renders one progress row per selected video.
+func (m *model) viewDownload() string {
+	var b strings.Builder
+
+	b.WriteString(m.header(m.spinner.View()+" "+i18n.T("Downloading %s", m.listing.Name)) + "\n")
+
+	indices := m.selectedIndices()
+	b.WriteString(m.batch.Render() + "\n\n")
+
+	titleWidth := m.titleWidth()
+	end := min(m.offset+m.downloadRows(), len(indices))
+
+	for row, i := range indices[m.offset:end] {
+		video := m.listing.Videos[i]
+		title := ansi.Truncate(m.videoLabel(i), titleWidth, "…")
+		title += strings.Repeat(" ", titleWidth-ansi.StringWidth(title))
+
+		percent := 0.0
+		if p, ok := m.progress[video.ID]; ok && p.total > 0 {
+			percent = float64(p.written) / float64(p.total)
+		}
+
+		if m.offset+row == m.cursor {
+			title = cursorStyle.Render(title)
+		}
+
+		fmt.Fprintf(&b, "%s %s %5.1f%%\n", title, m.bar.ViewAs(percent), percent*100)
+	}
+
+	if hidden := len(indices) - end; hidden > 0 {
+		b.WriteString(i18n.T("… and %d more", hidden) + "\n")
+	}
+
+	b.WriteString(helpStyle.Render(i18n.T("↑/↓: move • t: download next • p: pause/resume • ctrl+c: abort")))
+
+	return b.String()
+}
+
+// viewInput renders the input screen.
+func (m *model) viewInput() string {
+	var b strings.Builder
+
+	b.WriteString(m.header(i18n.T("Enter a video or channel ID or URL")) + "\n")
+	b.WriteString(m.input.View() + "\n")
+
+	if m.err != nil {
+		b.WriteString("\n" + styles.Error.Render("[ERROR]") + " " + m.err.Error() + "\n")
+	}
+
+	b.WriteString(helpStyle.Render(i18n.T("enter: look up • esc: quit")))
+
+	return b.String()
+}
+
+// viewLoading renders the spinner while the listing is fetched.
+func (m *model) viewLoading() string {
+	return m.header(i18n.T("Loading")) + "\n" + m.spinner.View() + " " + i18n.T("Fetching video information...")
+}
+
+// viewQuality renders the quality choice.
+func (m *model) viewQuality() string {
+	var b strings.Builder
+
+	b.WriteString(m.header(i18n.T("Choose the quality")) + "\n")
+
+	for i, option := range qualityOptions {
+		if i == m.cursor {
+			b.WriteString(cursorStyle.Render("> "+i18n.T(option.label)) + "\n")
+		} else {
+			b.WriteString("  " + i18n.T(option.label) + "\n")
+		}
+	}
+
+	b.WriteString(helpStyle.Render(i18n.T("↑/↓: move • enter: start download • esc: back")))
+
+	return b.String()
+}
+
+// viewSelect renders the scrollable video list with checkboxes.
+func (m *model) viewSelect() string {
+	var b strings.Builder
+
+	videos := m.listing.Videos
+	b.WriteString(m.header(i18n.T("%s (%d/%d selected)", m.listing.Name, len(m.selectedIndices()), len(videos))) + "\n")
+
+	for i := m.offset; i < min(m.offset+m.listHeight(), len(videos)); i++ {
+		check := "[ ]"
+		if m.selected[i] {
+			check = "[" + styles.Success.Render("x") + "]"
+		}
+
+		line := ansi.Truncate(m.videoLabel(i), max(m.width-6, minTitleWidth), "…")
+		if i == m.cursor {
+			b.WriteString(cursorStyle.Render(">") + " " + check + " " + cursorStyle.Render(line) + "\n")
+		} else {
+			b.WriteString("  " + check + " " + line + "\n")
+		}
+	}
+
+	b.WriteString(helpStyle.Render(i18n.T("↑/↓: move • space: toggle • a: toggle all • enter: continue • esc: back")))
+
+	return b.String()
+}
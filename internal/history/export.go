@@ -0,0 +1,106 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats of the ledger.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// tagSeparator joins the tags of an entry in the CSV export.
+const tagSeparator = ";"
+
+// csvHeader names the CSV columns like the JSON fields of Entry.
+//
+//nolint:gochecknoglobals // Read-only list of columns
+var csvHeader = []string{"downloadedAt", "publishedAt", "videoId", "title", "episode", "channelId", "channelName", "file", "size", "tags", "note"}
+
+var (
+	errUnknownFormat  = errors.New("unknown export format")
+	errFailedToExport = errors.New("failed to export history")
+)
+
+// Export writes entries to w in format, FormatCSV with a header row or FormatJSON as
+// indented array. Times are written in RFC 3339, tags in CSV joined by semicolons.
+func Export(w io.Writer, entries []Entry, format string) error {
+	switch format {
+	case FormatCSV:
+		return exportCSV(w, entries)
+	case FormatJSON:
+		return exportJSON(w, entries)
+	default:
+		return fmt.Errorf("%w: %q (expected %s or %s)", errUnknownFormat, format, FormatCSV, FormatJSON)
+	}
+}
+
+// exportCSV writes entries as CSV with a header row.
+func exportCSV(w io.Writer, entries []Entry) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToExport, err)
+	}
+
+	for _, entry := range entries {
+		record := []string{
+			formatTime(entry.DownloadedAt),
+			formatTime(entry.PublishedAt),
+			entry.VideoID,
+			entry.Title,
+			entry.Episode,
+			entry.ChannelID,
+			entry.ChannelName,
+			entry.Location(),
+			strconv.FormatInt(entry.Size, 10),
+			strings.Join(entry.Tags, tagSeparator),
+			entry.Note,
+		}
+
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("%w: %w", errFailedToExport, err)
+		}
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToExport, err)
+	}
+
+	return nil
+}
+
+// exportJSON writes entries as indented JSON array, empty if there are none.
+func exportJSON(w io.Writer, entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(entries); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToExport, err)
+	}
+
+	return nil
+}
+
+// formatTime formats t in RFC 3339, or returns an empty string if t is unset.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,183 @@
+// Package history records every downloaded video in a per-user ledger.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"switchtube-downloader/internal/helper/dir"
+)
+
+const (
+	// historyFilename is the ledger file in the config directory, one JSON entry per line.
+	historyFilename = "history.jsonl"
+	// historyPermissions are the permissions of the ledger file.
+	historyPermissions = 0o600
+)
+
+var (
+	errFailedToLoadHistory  = errors.New("failed to load history")
+	errFailedToWriteHistory = errors.New("failed to write history")
+)
+
+// Entry records a single downloaded video.
+type Entry struct {
+	DownloadedAt time.Time `json:"downloadedAt"`          // Time the download finished
+	PublishedAt  time.Time `json:"publishedAt,omitzero"`  // Publish date of the video
+	VideoID      string    `json:"videoId"`               // Video ID
+	Title        string    `json:"title"`                 // Video title
+	Episode      string    `json:"episode,omitempty"`     // Episode number as set by the uploader
+	ChannelID    string    `json:"channelId,omitempty"`   // Channel ID, empty for single videos
+	ChannelName  string    `json:"channelName,omitempty"` // Display name of the channel
+	File         string    `json:"file"`                  // Absolute path of the downloaded file, or its path inside Archive
+	Archive      string    `json:"archive,omitempty"`     // Absolute path of the archive holding the file, empty for none
+	Remote       string    `json:"remote,omitempty"`      // Path on the rclone remote the file was uploaded to, empty for none
+	Moved        bool      `json:"moved,omitempty"`       // Whether the local file was deleted once uploaded
+	Size         int64     `json:"size"`                  // File size in bytes
+	Tags         []string  `json:"tags,omitempty"`        // Tags given with --tag, e.g. the course and semester
+	Note         string    `json:"note,omitempty"`        // Note given with --note
+}
+
+// Location returns where the video of the entry is stored: its file, the archive
+// followed by the path inside it, or the remote path if the file was moved there.
+func (e Entry) Location() string {
+	switch {
+	case e.Archive != "":
+		return e.Archive + ":" + e.File
+	case e.Moved:
+		return e.Remote
+	default:
+		return e.File
+	}
+}
+
+// Append adds entries to the ledger.
+func Append(entries ...Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	path, err := historyPath()
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToWriteHistory, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, historyPermissions)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToWriteHistory, err)
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			_ = file.Close()
+
+			return fmt.Errorf("%w: %w", errFailedToWriteHistory, err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		_ = file.Close()
+
+		return fmt.Errorf("%w: %w", errFailedToWriteHistory, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToWriteHistory, err)
+	}
+
+	return nil
+}
+
+// Load returns all entries of the ledger in the order they were recorded.
+// Returns no entries if nothing was downloaded yet. Malformed lines are skipped.
+func Load() ([]Entry, error) {
+	path, err := historyPath()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errFailedToLoadHistory, err)
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errFailedToLoadHistory, err)
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var entries []Entry
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", errFailedToLoadHistory, err)
+	}
+
+	return entries, nil
+}
+
+// FilterByTag returns the entries tagged with tag, compared case-insensitively.
+// Returns all entries if tag is empty.
+func FilterByTag(entries []Entry, tag string) []Entry {
+	if tag == "" {
+		return entries
+	}
+
+	return slices.DeleteFunc(slices.Clone(entries), func(entry Entry) bool {
+		return !slices.ContainsFunc(entry.Tags, func(t string) bool { return strings.EqualFold(t, tag) })
+	})
+}
+
+// NormalizeTags trims the tags and drops empty and duplicate ones, keeping the order.
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.ContainsFunc(normalized, func(t string) bool { return strings.EqualFold(t, tag) }) {
+			normalized = append(normalized, tag)
+		}
+	}
+
+	return normalized
+}
+
+// LatestByVideo returns the most recent entry of every video, keyed by video ID.
+func LatestByVideo(entries []Entry) map[string]Entry {
+	latest := make(map[string]Entry, len(entries))
+	for _, entry := range entries {
+		latest[entry.VideoID] = entry
+	}
+
+	return latest
+}
+
+// historyPath returns the path of the ledger file.
+func historyPath() (string, error) {
+	configDir, err := dir.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, historyFilename), nil
+}
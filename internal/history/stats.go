@@ -0,0 +1,105 @@
+package history
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// Summary aggregates the ledger for an overview of everything downloaded.
+type Summary struct {
+	First     time.Time        // Time of the first recorded download
+	Last      time.Time        // Time of the latest recorded download
+	Channels  []ChannelSummary // Downloads per channel, most bytes first
+	Days      []DaySummary     // Busiest days, most bytes first
+	Bytes     int64            // Bytes downloaded in total
+	Downloads int              // Recorded downloads, including repeated ones
+	Videos    int              // Distinct videos downloaded
+}
+
+// ChannelSummary aggregates the downloads of a single channel.
+type ChannelSummary struct {
+	ChannelID   string // Channel ID, empty for videos downloaded on their own
+	ChannelName string // Latest display name of the channel
+	Bytes       int64  // Bytes downloaded from the channel
+	Videos      int    // Distinct videos downloaded from the channel
+}
+
+// DaySummary aggregates the downloads of a single day.
+type DaySummary struct {
+	Day       time.Time // Midnight of the day in local time
+	Bytes     int64     // Bytes downloaded on the day
+	Downloads int       // Downloads finished on the day
+}
+
+// Summarize aggregates entries into totals, a per-channel breakdown and the busiest
+// days, at most days of them.
+func Summarize(entries []Entry, days int) Summary {
+	var summary Summary
+
+	channels := make(map[string]*ChannelSummary)
+	daily := make(map[time.Time]*DaySummary)
+	videos := make(map[string]bool)
+	channelVideos := make(map[string]map[string]bool)
+
+	for _, entry := range entries {
+		summary.Bytes += entry.Size
+		summary.Downloads++
+
+		if summary.First.IsZero() || entry.DownloadedAt.Before(summary.First) {
+			summary.First = entry.DownloadedAt
+		}
+
+		if entry.DownloadedAt.After(summary.Last) {
+			summary.Last = entry.DownloadedAt
+		}
+
+		videos[entry.VideoID] = true
+
+		channel, ok := channels[entry.ChannelID]
+		if !ok {
+			channel = &ChannelSummary{ChannelID: entry.ChannelID}
+			channels[entry.ChannelID] = channel
+			channelVideos[entry.ChannelID] = make(map[string]bool)
+		}
+
+		channel.Bytes += entry.Size
+		channel.ChannelName = cmp.Or(entry.ChannelName, channel.ChannelName)
+		channelVideos[entry.ChannelID][entry.VideoID] = true
+
+		local := entry.DownloadedAt.Local()
+		midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.Local)
+
+		day, ok := daily[midnight]
+		if !ok {
+			day = &DaySummary{Day: midnight}
+			daily[midnight] = day
+		}
+
+		day.Bytes += entry.Size
+		day.Downloads++
+	}
+
+	summary.Videos = len(videos)
+
+	for id, channel := range channels {
+		channel.Videos = len(channelVideos[id])
+		summary.Channels = append(summary.Channels, *channel)
+	}
+
+	slices.SortFunc(summary.Channels, func(a ChannelSummary, b ChannelSummary) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(a.ChannelName, b.ChannelName))
+	})
+
+	for _, day := range daily {
+		summary.Days = append(summary.Days, *day)
+	}
+
+	slices.SortFunc(summary.Days, func(a DaySummary, b DaySummary) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), b.Day.Compare(a.Day))
+	})
+
+	summary.Days = summary.Days[:min(len(summary.Days), max(days, 0))]
+
+	return summary
+}
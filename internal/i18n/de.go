@@ -0,0 +1,220 @@
+package i18n
+
+// german holds the German translations, keyed by the English message.
+//
+//nolint:gochecknoglobals // Read-only message catalog
+var german = map[string]string{
+	// Downloads
+	"%d/%d videos successful":                                               "%d/%d Videos erfolgreich",
+	"Channel: %s (%d videos)":                                               "Kanal: %s (%d Videos)",
+	"Download aborted by user":                                              "Download vom Benutzer abgebrochen",
+	"Download complete! %d/%d videos successful":                            "Download abgeschlossen! %d/%d Videos erfolgreich",
+	"Download complete!":                                                    "Download abgeschlossen!",
+	"Downloaded %s":                                                         "%s heruntergeladen",
+	"Downloading %s":                                                        "Lade %s herunter",
+	"Downloading to folder: %s":                                             "Speichere in Ordner: %s",
+	"Kept the previous version of %s as %s":                                 "Vorherige Version von %s als %s behalten",
+	"Dry run, videos would be written to: %s":                               "Testlauf, Videos würden gespeichert in: %s",
+	"would create %s":                                                       "würde %s erstellen",
+	"all folders exist":                                                     "alle Ordner existieren",
+	"Failed uploads, kept locally:":                                         "Fehlgeschlagene Uploads, lokal behalten:",
+	"Failed to upload %s: %v":                                               "Hochladen von %s fehlgeschlagen: %v",
+	"Skipping %s, it was moved to %s":                                       "Überspringe %s, es wurde nach %s verschoben",
+	"Failed downloads:":                                                     "Fehlgeschlagene Downloads:",
+	"Failed to get video variants for %s: %v":                               "Varianten für %s konnten nicht geladen werden: %v",
+	"Failed to prepare %s: %v":                                              "%s konnte nicht vorbereitet werden: %v",
+	"Filename collision for %s: %s is already used by another video":        "Namenskonflikt bei %s: %s wird bereits von einem anderen Video verwendet",
+	"Found %d videos in channel: %s":                                        "%d Videos im Kanal gefunden: %s",
+	"No channels cached yet":                                                "Noch keine Kanäle zwischengespeichert",
+	"No interrupted download found for channel %s":                          "Kein unterbrochener Download für Kanal %s gefunden",
+	"No interrupted downloads to resume":                                    "Keine unterbrochenen Downloads zum Fortsetzen",
+	"No variants found for %s":                                              "Keine Varianten für %s gefunden",
+	"No videos found in this channel":                                       "Keine Videos in diesem Kanal gefunden",
+	"No videos match the filters":                                           "Keine Videos entsprechen den Filtern",
+	"Skipping %d videos that do not match the filters":                      "Überspringe %d Videos, die nicht den Filtern entsprechen",
+	"No videos selected for download":                                       "Keine Videos zum Herunterladen ausgewählt",
+	"Pending videos of channel %s are no longer available":                  "Ausstehende Videos des Kanals %s sind nicht mehr verfügbar",
+	"Resuming %d videos of channel: %s":                                     "Setze %d Videos des Kanals fort: %s",
+	"Run '%s resume' to retry the %d unfinished videos":                     "Führe '%s resume' aus, um die %d unvollständigen Videos erneut zu versuchen",
+	"Replacing %s with %s: both are named %s":                               "Ersetze %s durch %s: beide heißen %s",
+	"Skipping %s: %s is already used by another video":                      "Überspringe %s: %s wird bereits von einem anderen Video verwendet",
+	"Total %6.2f %s · %d active · %d queued · %d/%d done · %s":              "Gesamt %6.2f %s · %d aktiv · %d wartend · %d/%d fertig · %s",
+	"Paused, press p to resume":                                             "Pausiert, p zum Fortsetzen drücken",
+	"Starting at %s (in %s), press enter to start now":                      "Start um %s (in %s), Enter drücken, um sofort zu starten",
+	"Found %d new videos in channel: %s":                                    "%d neue Videos im Kanal gefunden: %s",
+	"failed to check channel %s: %v":                                        "Prüfen von Kanal %s fehlgeschlagen: %v",
+	"Next check at %s":                                                      "Nächste Prüfung um %s",
+	"No new videos in channel: %s":                                          "Keine neuen Videos im Kanal: %s",
+	"%s was renamed, keeping it as %s":                                      "%s wurde umbenannt, behalte es als %s",
+	"Renamed %s to %s":                                                      "%s in %s umbenannt",
+	"Deleted %s, it was removed from the channel":                           "%s gelöscht, es wurde aus dem Kanal entfernt",
+	"Moved %s to %s, it was removed from the channel":                       "%s nach %s verschoben, es wurde aus dem Kanal entfernt",
+	"Skipping %s, it was already downloaded to %s":                          "Überspringe %s, es wurde bereits nach %s heruntergeladen",
+	"Linked %s to %s":                                                       "%s nach %s verlinkt",
+	"Copied %s to %s":                                                       "%s nach %s kopiert",
+	"Opening %s":                                                            "Öffne %s",
+	"Playing %s with %s":                                                    "Spiele %s mit %s ab",
+	"No leftover files found in %s":                                         "Keine übrig gebliebenen Dateien in %s gefunden",
+	"Delete %d files?":                                                      "%d Dateien löschen?",
+	"%d files do not belong to an unfinished channel download and are kept": "%d Dateien gehören zu keinem unvollständigen Kanal-Download und werden behalten",
+	"Resume the unfinished downloads of %d channels?":                       "Die unvollständigen Downloads von %d Kanälen fortsetzen?",
+	"Deleted %s":             "%s gelöscht",
+	"Reason":                 "Grund",
+	"unfinished download":    "unvollständiger Download",
+	"partial file":           "Teildatei",
+	"aria2c control file":    "aria2c-Steuerdatei",
+	"partial archive":        "Teilarchiv",
+	"staged download":        "bereitgestellter Download",
+	"remux source":           "Remux-Quelle",
+	"unfinished replacement": "unvollständige Ersatzdatei",
+	"SwitchTube is unavailable (status %d), retrying in %s": "SwitchTube ist nicht verfügbar (Status %d), neuer Versuch in %s",
+	"Throttled by SwitchTube (status %d), waiting %s":       "Von SwitchTube gedrosselt (Status %d), warte %s",
+	"using cached data from %s: %v":                         "verwende zwischengespeicherte Daten vom %s: %v",
+	"Warning: %s":                                           "Warnung: %s",
+
+	// Prompts
+	"Are you sure you want to delete the stored token?": "Soll das gespeicherte Token wirklich gelöscht werden?",
+	"Choose videos to download":                         "Videos zum Herunterladen auswählen",
+	"Choose existing files to overwrite":                "Zu überschreibende vorhandene Dateien auswählen",
+	"Choose the quality of the selected videos":         "Qualität der ausgewählten Videos wählen",
+	"Highest":                                "Höchste",
+	"Lowest":                                 "Niedrigste",
+	"Up to %s":                               "Bis %s",
+	"Choose per video":                       "Pro Video wählen",
+	"Quality of %s":                          "Qualität von %s",
+	"(highest)":                              "(höchste)",
+	"(lowest)":                               "(niedrigste)",
+	"Do you want to replace it?":             "Soll es ersetzt werden?",
+	"Enter your access token":                "Access Token eingeben",
+	"File %s already exists. Overwrite?":     "Datei %s existiert bereits. Überschreiben?",
+	"No":                                     "Nein",
+	"Yes":                                    "Ja",
+	"[y/N, a = all, s = skip all, q = quit]": "[j/N, a = alle, s = alle überspringen, q = beenden]",
+	"(default in %s)":                        "(Standard in %s)",
+	"no answer within %s, using the default": "keine Antwort innerhalb von %s, Standard wird verwendet",
+	"%s was already downloaded to %s. Reuse it?":                      "%s wurde bereits nach %s heruntergeladen. Wiederverwenden?",
+	"%d videos were already downloaded to other folders. Reuse them?": "%d Videos wurden bereits in andere Ordner heruntergeladen. Wiederverwenden?",
+	"[l = link, c = copy, s = skip, d = download again, q = quit]":    "[l = verlinken, c = kopieren, s = überspringen, d = erneut herunterladen, q = beenden]",
+	"use --skip or --force":                                           "verwende --skip oder --force",
+	"Set up the downloader":                                           "Downloader einrichten",
+	"Where should downloads be saved?":                                "Wo sollen Downloads gespeichert werden?",
+	"Leave empty for the current directory":                           "Leer lassen für das aktuelle Verzeichnis",
+	"Colored output":                                                  "Farbige Ausgabe",
+	"Automatic":                                                       "Automatisch",
+	"Always":                                                          "Immer",
+	"Never":                                                           "Nie",
+	"How many videos should be downloaded at once?":                   "Wie viele Videos sollen gleichzeitig heruntergeladen werden?",
+	"0 downloads all selected videos at once":                         "0 lädt alle ausgewählten Videos gleichzeitig herunter",
+	"Welcome! No access token is stored yet, so let's set up the downloader.":       "Willkommen! Es ist noch kein Access Token gespeichert, richten wir also den Downloader ein.",
+	"Saved your preferences to %s, the flags of each command still take precedence": "Einstellungen in %s gespeichert, die Flags der einzelnen Befehle haben weiterhin Vorrang",
+
+	// Tables
+	"%d characters":               "%d Zeichen",
+	"%s in %d files":              "%s in %d Dateien",
+	"%s in %d downloads":          "%s in %d Downloads",
+	"Busiest days":                "Aktivste Tage",
+	"Channels":                    "Kanäle",
+	"Distinct videos":             "Verschiedene Videos",
+	"Downloads":                   "Downloads",
+	"First download":              "Erster Download",
+	"Latest download":             "Letzter Download",
+	"Single videos":               "Einzelne Videos",
+	"No downloads recorded yet":   "Noch keine Downloads aufgezeichnet",
+	"1. Visit: %s":                "1. Öffne: %s",
+	"2. Click 'Create New Token'": "2. Klicke auf 'Create New Token'",
+	"3. Copy the generated token": "3. Kopiere das erzeugte Token",
+	"4. Paste it below":           "4. Füge es unten ein",
+	"Average speed":               "Durchschnittliche Geschwindigkeit",
+	"Cached":                      "Zwischengespeichert",
+	"Channel":                     "Kanal",
+	"Downloaded":                  "Heruntergeladen",
+	"Episode":                     "Episode",
+	"Field":                       "Feld",
+	"File":                        "Datei",
+	"Invalid":                     "Ungültig",
+	"Length":                      "Länge",
+	"License":                     "Lizenz",
+	"Media type":                  "Medientyp",
+	"Duration":                    "Dauer",
+	"Page":                        "Seite",
+	"Published":                   "Veröffentlicht",
+	"Resolution":                  "Auflösung",
+	"Service":                     "Dienst",
+	"Size":                        "Grösse",
+	"Slowest file":                "Langsamste Datei",
+	"Speed":                       "Geschwindigkeit",
+	"Status":                      "Status",
+	"Summary":                     "Zusammenfassung",
+	"Time":                        "Dauer",
+	"Thumbnail":                   "Vorschaubild",
+	"Title":                       "Titel",
+	"Token creation instructions": "Anleitung zum Erstellen eines Tokens",
+	"Token":                       "Token",
+	"Total downloaded":            "Insgesamt heruntergeladen",
+	"User":                        "Benutzer",
+	"Valid":                       "Gültig",
+	"Value":                       "Wert",
+	"Video":                       "Video",
+	"Videos":                      "Videos",
+
+	// Token management
+	"Account":                  "Konto",
+	"Operation cancelled":      "Vorgang abgebrochen",
+	"Token deletion cancelled": "Löschen des Tokens abgebrochen",
+	"Token is valid and successfully stored in keyring":              "Token ist gültig und wurde im Schlüsselbund gespeichert",
+	"Token successfully deleted from keyring":                        "Token wurde aus dem Schlüsselbund gelöscht",
+	"Token validation failed":                                        "Überprüfung des Tokens fehlgeschlagen",
+	"Validating token with SwitchTube API...":                        "Überprüfe Token mit der SwitchTube API...",
+	"Validating token...":                                            "Überprüfe Token...",
+	"Create a token in the browser and paste it into the page at %s": "Erstelle ein Token im Browser und füge es auf der Seite %s ein",
+	"Create a new token on SwitchTube":                               "Erstelle ein neues Token auf SwitchTube",
+	"Copy the generated token and paste it here":                     "Kopiere das erstellte Token und füge es hier ein",
+	"Save token":                        "Token speichern",
+	"SwitchTube rejected the token: %v": "SwitchTube hat das Token abgelehnt: %v",
+	"The token was accepted, you can close this page and return to the terminal.": "Das Token wurde akzeptiert, du kannst diese Seite schließen und zum Terminal zurückkehren.",
+
+	// Errors
+	"Check your access token with 'token validate' or store a new one with 'token set'": "Prüfe dein Access Token mit 'token validate' oder speichere ein neues mit 'token set'",
+	"Download failed":              "Download fehlgeschlagen",
+	"Interface failed":             "Oberfläche fehlgeschlagen",
+	"Offline listing failed":       "Offline-Auflistung fehlgeschlagen",
+	"Watch failed":                 "Beobachten fehlgeschlagen",
+	"Opening failed":               "Öffnen fehlgeschlagen",
+	"Cleaning failed":              "Aufräumen fehlgeschlagen",
+	"Serve failed":                 "Bereitstellen fehlgeschlagen",
+	"Listening on %s":              "Lausche auf %s",
+	"Resume failed":                "Fortsetzen fehlgeschlagen",
+	"Loading the history failed":   "Laden des Verlaufs fehlgeschlagen",
+	"Exporting the history failed": "Export des Verlaufs fehlgeschlagen",
+	"Reading the config failed":    "Lesen der Konfiguration fehlgeschlagen",
+	"Changing the config failed":   "Ändern der Konfiguration fehlgeschlagen",
+	"Editing the config failed":    "Bearbeiten der Konfiguration fehlgeschlagen",
+	"Wait for the other download to finish, or pass --force-lock if it is no longer running": "Warte, bis der andere Download fertig ist, oder übergib --force-lock, falls er nicht mehr läuft",
+	"SwitchTube could not handle the request, try again later":                               "SwitchTube konnte die Anfrage nicht bearbeiten, versuche es später erneut",
+	"The video or channel does not exist or is not accessible with your token":               "Das Video oder der Kanal existiert nicht oder ist mit deinem Token nicht zugänglich",
+
+	// Interactive interface
+	"%s (%d/%d selected)":                "%s (%d/%d ausgewählt)",
+	"%d selected, ~%s":                   "%d ausgewählt, ~%s",
+	"(%d without known size)":            "(%d ohne bekannte Größe)",
+	"Choose the quality":                 "Qualität auswählen",
+	"Enter a video or channel ID or URL": "Video- oder Kanal-ID oder URL eingeben",
+	"Fetching video information...":      "Lade Videoinformationen...",
+	"Highest quality":                    "Höchste Qualität",
+	"Loading":                            "Lade",
+	"Lowest quality (smallest files)":    "Niedrigste Qualität (kleinste Dateien)",
+	"↑/↓: move • t: download next • p: pause/resume • ctrl+c: abort":                                      "↑/↓: bewegen • t: als Nächstes herunterladen • p: pausieren/fortsetzen • ctrl+c: abbrechen",
+	"collision: %s and %s differ in case only, they are the same folder on macOS and Windows":             "Kollision: %s und %s unterscheiden sich nur in Gross-/Kleinschreibung, unter macOS und Windows sind sie derselbe Ordner",
+	"enter: download more • q: quit":                                                                      "enter: weitere herunterladen • q: beenden",
+	"enter: look up • esc: quit":                                                                          "enter: suchen • esc: beenden",
+	"… and %d more":                                                                                       "… und %d weitere",
+	"↑/↓: move • enter: start download • esc: back":                                                       "↑/↓: bewegen • enter: Download starten • esc: zurück",
+	"↑/↓: move • space: toggle • a: toggle all • enter: continue • esc: back":                             "↑/↓: bewegen • space: auswählen • a: alle auswählen • enter: weiter • esc: zurück",
+	"↑/↓: move • space: toggle • a: toggle all • s: sort • enter: confirm • ctrl+c: abort":                "↑/↓: bewegen • space: auswählen • a: alle auswählen • s: sortieren • enter: bestätigen • ctrl+c: abbrechen",
+	"↑/↓: move • space: toggle • a: toggle all • s: sort • tab: details • enter: confirm • ctrl+c: abort": "↑/↓: bewegen • space: auswählen • a: alle auswählen • s: sortieren • tab: Details • enter: bestätigen • ctrl+c: abbrechen",
+	"sorted by %s": "sortiert nach %s",
+	"space: toggle • tab/←: back to the list": "space: auswählen • tab/←: zurück zur Liste",
+	"Variants":     "Varianten",
+	"not selected": "nicht ausgewählt",
+	"selected":     "ausgewählt",
+}
@@ -0,0 +1,90 @@
+// Package i18n translates user-facing messages into the language of the user.
+//
+// Messages are looked up by their English text, so untranslated messages and the
+// English language need no catalog entry.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Language is a supported user interface language.
+type Language string
+
+// Supported languages.
+const (
+	English Language = "en"
+	German  Language = "de"
+)
+
+var errUnsupportedLanguage = errors.New("unsupported language")
+
+//nolint:gochecknoglobals // Selected once at startup from --lang or the locale
+var current = English
+
+//nolint:gochecknoglobals // Read-only message catalogs
+var catalogs = map[Language]map[string]string{
+	German: german,
+}
+
+// Current returns the selected language.
+func Current() Language {
+	return current
+}
+
+// Detect selects the language of the locale given by LC_ALL, LC_MESSAGES or LANG,
+// in this order. Keeps English if the locale is not set or its language unsupported.
+func Detect() {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(env); value != "" {
+			if lang, ok := parse(value); ok {
+				current = lang
+			}
+
+			return
+		}
+	}
+}
+
+// SetLanguage selects the language given by tag, e.g. "de", "de-CH" or "de_CH.UTF-8".
+func SetLanguage(tag string) error {
+	lang, ok := parse(tag)
+	if !ok {
+		return fmt.Errorf("%w: %q (expected en or de)", errUnsupportedLanguage, tag)
+	}
+
+	current = lang
+
+	return nil
+}
+
+// T returns msg in the selected language, formatted with args like fmt.Sprintf if any are given.
+func T(msg string, args ...any) string {
+	if translated, ok := catalogs[current][msg]; ok {
+		msg = translated
+	}
+
+	if len(args) == 0 {
+		return msg
+	}
+
+	return fmt.Sprintf(msg, args...)
+}
+
+// parse extracts a supported language from a locale or language tag.
+func parse(tag string) (Language, bool) {
+	code := strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(code, "_-.@"); i >= 0 {
+		code = code[:i] // Drop territory, encoding and modifier
+	}
+
+	switch lang := Language(code); lang {
+	case English, German:
+		return lang, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,190 @@
+// Package metrics counts downloads, transferred bytes, failures and durations of a
+// long-running serve or watch process and exposes them in the Prometheus text format,
+// so archiving jobs can be monitored like any other service.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Path is the path the metrics are served at.
+	Path = "/metrics"
+	// contentType is the media type of the Prometheus text format.
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	readHeaderTimeout = 10 * time.Second // Maximum time to read the request headers
+	shutdownTimeout   = 5 * time.Second  // Maximum time to finish open requests on shutdown
+)
+
+var errFailedToServeMetrics = errors.New("failed to serve metrics")
+
+// durationBuckets are the upper bounds in seconds of the download duration histogram,
+// from short clips to recordings of whole lectures.
+//
+//nolint:gochecknoglobals // Read-only bucket bounds
+var durationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}
+
+// registry holds the values of all metrics.
+type registry struct {
+	started         time.Time  // Start of the process
+	lastCheck       time.Time  // End of the last successful channel check, zero if none
+	succeeded       int64      // Videos downloaded successfully
+	failed          int64      // Videos that failed
+	bytes           int64      // Bytes written by successful downloads
+	durationSum     float64    // Total download time in seconds
+	durationBuckets []int64    // Downloads per duration bucket, not cumulative
+	checksOK        int64      // Channel checks of the watch mode that succeeded
+	checksFailed    int64      // Channel checks of the watch mode that failed
+	mutex           sync.Mutex // Guards all fields across parallel downloads
+}
+
+//nolint:gochecknoglobals // Process-wide metrics, recorded by the download engine
+var recorded = &registry{started: time.Now(), durationBuckets: make([]int64, len(durationBuckets))}
+
+// DownloadSucceeded records a video downloaded in elapsed with bytes written to disk.
+func DownloadSucceeded(bytes int64, elapsed time.Duration) {
+	recorded.mutex.Lock()
+	defer recorded.mutex.Unlock()
+
+	recorded.succeeded++
+	recorded.bytes += bytes
+	recorded.durationSum += elapsed.Seconds()
+
+	for i, bound := range durationBuckets {
+		if elapsed.Seconds() <= bound {
+			recorded.durationBuckets[i]++
+
+			break
+		}
+	}
+}
+
+// DownloadFailed records a video that failed.
+func DownloadFailed() {
+	recorded.mutex.Lock()
+	defer recorded.mutex.Unlock()
+
+	recorded.failed++
+}
+
+// CheckFinished records a channel check of the watch mode, failed if err is not nil.
+func CheckFinished(err error) {
+	recorded.mutex.Lock()
+	defer recorded.mutex.Unlock()
+
+	if err != nil {
+		recorded.checksFailed++
+
+		return
+	}
+
+	recorded.checksOK++
+	recorded.lastCheck = time.Now()
+}
+
+// Handler serves the recorded metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		_ = recorded.write(w) // The scraper went away, nothing left to report to
+	})
+}
+
+// Serve serves the metrics on addr until ctx is done, for processes without HTTP API of
+// their own. The listener is opened before returning, so an unusable address fails right
+// away, and the returned function stops serving.
+func Serve(ctx context.Context, addr string) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errFailedToServeMetrics, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET "+Path, Handler())
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: readHeaderTimeout}
+
+	go func() {
+		_ = server.Serve(listener) // Ends with http.ErrServerClosed once shut down
+	}()
+
+	return func() {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+		defer cancel()
+
+		_ = server.Shutdown(shutdownCtx)
+	}, nil
+}
+
+// write writes all metrics in the Prometheus text format to w.
+func (r *registry) write(w io.Writer) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var out []byte
+
+	metric := func(name string, kind string, help string) {
+		out = fmt.Appendf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	sample := func(name string, labels string, value string) {
+		out = fmt.Appendf(out, "%s%s %s\n", name, labels, value)
+	}
+
+	metric("switchtube_downloads_total", "counter", "Videos downloaded, by result.")
+	sample("switchtube_downloads_total", `{result="succeeded"}`, strconv.FormatInt(r.succeeded, 10))
+	sample("switchtube_downloads_total", `{result="failed"}`, strconv.FormatInt(r.failed, 10))
+
+	metric("switchtube_downloaded_bytes_total", "counter", "Bytes written to disk by successful downloads.")
+	sample("switchtube_downloaded_bytes_total", "", strconv.FormatInt(r.bytes, 10))
+
+	metric("switchtube_download_duration_seconds", "histogram", "Time spent downloading a video.")
+
+	var cumulative int64
+
+	for i, bound := range durationBuckets {
+		cumulative += r.durationBuckets[i]
+		sample("switchtube_download_duration_seconds_bucket", `{le="`+formatFloat(bound)+`"}`, strconv.FormatInt(cumulative, 10))
+	}
+
+	sample("switchtube_download_duration_seconds_bucket", `{le="+Inf"}`, strconv.FormatInt(r.succeeded, 10))
+	sample("switchtube_download_duration_seconds_sum", "", formatFloat(r.durationSum))
+	sample("switchtube_download_duration_seconds_count", "", strconv.FormatInt(r.succeeded, 10))
+
+	metric("switchtube_watch_checks_total", "counter", "Channel checks of the watch mode, by result.")
+	sample("switchtube_watch_checks_total", `{result="succeeded"}`, strconv.FormatInt(r.checksOK, 10))
+	sample("switchtube_watch_checks_total", `{result="failed"}`, strconv.FormatInt(r.checksFailed, 10))
+
+	metric("switchtube_watch_last_check_timestamp_seconds", "gauge", "Time of the last successful channel check, 0 if none.")
+	sample("switchtube_watch_last_check_timestamp_seconds", "", strconv.FormatInt(unixOrZero(r.lastCheck), 10))
+
+	metric("switchtube_start_time_seconds", "gauge", "Start time of the process.")
+	sample("switchtube_start_time_seconds", "", strconv.FormatInt(r.started.Unix(), 10))
+
+	_, err := w.Write(out)
+
+	return err //nolint:wrapcheck // Plain passthrough to the response
+}
+
+// formatFloat formats v as a Prometheus sample value.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// unixOrZero returns t in seconds since the epoch, 0 for the zero time.
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
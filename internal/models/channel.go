@@ -0,0 +1,12 @@
+package models
+
+// Channel represents a SwitchTube channel. Fields other than the name are empty if the
+// API omits them.
+type Channel struct {
+	ID           string `json:"id"`            // The channel ID
+	Name         string `json:"name"`          // Display name of the channel
+	Description  string `json:"description"`   // The channel description
+	ThumbnailURL string `json:"thumbnail_url"` //nolint:tagliatelle // API returns snake_case
+	License      string `json:"license"`       // Default license of the channel's videos
+	URL          string `json:"url"`           // Page of the channel on SwitchTube
+}
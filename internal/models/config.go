@@ -1,12 +1,406 @@
 // Package models defines the structures used in the application.
 package models
 
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultFolderTemplate names channel folders after the channel.
+	DefaultFolderTemplate = "{channel}"
+	// StdoutOutput as output directory writes a single video to stdout instead of a file.
+	StdoutOutput = "-"
+)
+
+// CollisionPolicy decides what happens when two videos of one run map to the same filename.
+type CollisionPolicy string
+
+// Supported collision policies.
+const (
+	CollisionRename        CollisionPolicy = "rename"         // Append a "_(2)" style counter
+	CollisionRenameEpisode CollisionPolicy = "rename-episode" // Append the episode number, or else a counter
+	CollisionRenameID      CollisionPolicy = "rename-id"      // Append the video ID
+	CollisionSkip          CollisionPolicy = "skip"           // Keep the first video, skip the later ones
+	CollisionOverwrite     CollisionPolicy = "overwrite"      // Let the later video overwrite the earlier one
+	CollisionError         CollisionPolicy = "error"          // Fail the later video
+)
+
+// DuplicatePolicy decides what happens when a video was already downloaded to another path.
+type DuplicatePolicy string
+
+// Supported duplicate policies.
+const (
+	DuplicateDownload DuplicatePolicy = "download" // Download the video again
+	DuplicateAsk      DuplicatePolicy = "ask"      // Ask for every duplicate
+	DuplicateLink     DuplicatePolicy = "link"     // Link the existing file, copying it if it cannot be linked
+	DuplicateCopy     DuplicatePolicy = "copy"     // Copy the existing file
+	DuplicateSkip     DuplicatePolicy = "skip"     // Keep only the existing file
+)
+
+// LinkMode decides how a duplicate is linked to the file downloaded before.
+type LinkMode string
+
+// Supported link modes.
+const (
+	LinkHard     LinkMode = "hard"    // Hardlink sharing the data of the file
+	LinkSymbolic LinkMode = "symlink" // Symbolic link pointing to the file by a relative path
+)
+
+// ArchiveFormat names the format of the archive the videos are written into.
+type ArchiveFormat string
+
+// Supported archive formats.
+const (
+	ArchiveTar   ArchiveFormat = "tar"    // Uncompressed tar
+	ArchiveTarGz ArchiveFormat = "tar.gz" // Gzip compressed tar
+	ArchiveZip   ArchiveFormat = "zip"    // Zip with uncompressed entries
+)
+
+// ChapterMode decides what happens with the chapters listed in a video description.
+type ChapterMode string
+
+// Supported chapter modes.
+const (
+	ChaptersNone  ChapterMode = ""      // Ignore chapters
+	ChaptersFile  ChapterMode = "file"  // Write an ffmetadata file next to the video
+	ChaptersEmbed ChapterMode = "embed" // Embed the chapters into the video with ffmpeg
+)
+
+// Container names a container format downloaded videos are remuxed into.
+type Container string
+
+// Supported containers.
+const (
+	ContainerSource Container = ""    // Keep the container served by SwitchTube
+	ContainerMKV    Container = "mkv" // Matroska
+	ContainerMP4    Container = "mp4" // MPEG-4
+)
+
+// FsyncPolicy decides when downloaded data is flushed from the OS cache to disk.
+type FsyncPolicy string
+
+// Supported fsync policies.
+const (
+	FsyncNever  FsyncPolicy = ""       // Leave flushing to the OS
+	FsyncEnd    FsyncPolicy = "end"    // Flush once a video is complete
+	FsyncAlways FsyncPolicy = "always" // Flush after every write, e.g. of a full write buffer
+)
+
+// QualityPolicy decides which video variant is downloaded.
+type QualityPolicy string
+
+// Supported quality policies.
+const (
+	QualityHighest  QualityPolicy = "highest"   // Best available variant
+	QualityLowest   QualityPolicy = "lowest"    // Smallest available variant
+	QualityPerVideo QualityPolicy = "per-video" // Variant chosen by the user for every video
+	QualityAsk      QualityPolicy = "ask"       // Policy chosen by the user after the video selection
+)
+
+// Height returns the frame height of a resolution policy like "720p", which takes the
+// best variant not above that height, or 0 for the other policies.
+func (q QualityPolicy) Height() int {
+	digits, ok := strings.CutSuffix(string(q), "p")
+	if !ok {
+		return 0
+	}
+
+	height, err := strconv.Atoi(digits)
+	if err != nil || height <= 0 {
+		return 0
+	}
+
+	return height
+}
+
+// ExternalDownloader names a tool the byte transfer is delegated to.
+type ExternalDownloader string
+
+// Supported external downloaders.
+const (
+	ExternalNone   ExternalDownloader = ""       // Built-in downloader
+	ExternalAria2c ExternalDownloader = "aria2c" // Multi-connection downloads with aria2c
+	ExternalCurl   ExternalDownloader = "curl"   // Transfer with curl, e.g. for custom proxy setups
+)
+
+// DownloadOrder decides in which order pending videos are handed to the download workers.
+type DownloadOrder string
+
+// Supported download orders.
+const (
+	OrderSelection DownloadOrder = "selection" // Order of the channel listing
+	OrderEpisode   DownloadOrder = "episode"   // Ascending episode numbers
+	OrderSmallest  DownloadOrder = "smallest"  // Smallest files first
+	OrderLargest   DownloadOrder = "largest"   // Largest files first
+)
+
+// VideoSort decides in which order the videos of a channel are listed for selection.
+type VideoSort string
+
+// Supported video sorts.
+const (
+	SortChannel  VideoSort = "channel"  // Order returned by SwitchTube
+	SortEpisode  VideoSort = "episode"  // Ascending episode numbers
+	SortTitle    VideoSort = "title"    // Alphabetical by title
+	SortDate     VideoSort = "date"     // Oldest first
+	SortDuration VideoSort = "duration" // Shortest first
+)
+
+// folderPlaceholder matches the placeholders of a folder template, e.g. {channel}.
+//
+//nolint:gochecknoglobals // Compiled once
+var folderPlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// folderPlaceholders are the placeholders supported in folder templates.
+//
+//nolint:gochecknoglobals // Read-only list of placeholders
+var folderPlaceholders = []string{"channel", "id", "year"}
+
+var (
+	errInvalidArchiveFormat      = errors.New("invalid archive format")
+	errInvalidChapterMode        = errors.New("invalid chapter mode")
+	errInvalidCollisionPolicy    = errors.New("invalid collision policy")
+	errInvalidContainer          = errors.New("invalid container")
+	errInvalidDownloadOrder      = errors.New("invalid download order")
+	errInvalidDuplicatePolicy    = errors.New("invalid duplicate policy")
+	errInvalidExternalDownloader = errors.New("invalid external downloader")
+	errInvalidFileMode           = errors.New("invalid permissions")
+	errInvalidFolderTemplate     = errors.New("invalid folder template")
+	errInvalidFsyncPolicy        = errors.New("invalid fsync policy")
+	errInvalidQualityPolicy      = errors.New("invalid quality")
+	errInvalidLinkMode           = errors.New("invalid link mode")
+	errInvalidSchedule           = errors.New("invalid schedule")
+	errInvalidVideoSort          = errors.New("invalid sort")
+)
+
 // DownloadConfig holds configuration options for the Download function.
 type DownloadConfig struct {
-	Media      string // Video or channel ID/URL
-	OutputDir  string // Output directory
-	UseEpisode bool   // Whether to use episode numbers in filenames
-	Skip       bool   // Whether to skip existing files
-	Force      bool   // Whether to force overwrite existing files
-	All        bool   // Whether to download all videos
+	StartAt            time.Time          // Time the downloads start at, zero to start immediately
+	Tags               []string           // Tags recorded with the downloaded videos in the history
+	Selection          Selection          // Channel videos to download by listed position, nil to choose them interactively
+	Media              string             // Video or channel ID/URL
+	OutputDir          string             // Output directory, StdoutOutput to write the video to stdout
+	OnCollision        CollisionPolicy    // What to do when two videos share a filename
+	OnDuplicate        DuplicatePolicy    // What to do when a video was downloaded to another path before, empty to download it
+	LinkMode           LinkMode           // How duplicates are linked with DuplicateLink, LinkHard if empty
+	Quality            QualityPolicy      // Which variant to download
+	NotifyCmd          string             // Shell command run after a batch finishes
+	NotifyWebhook      string             // URL receiving a JSON summary after a batch finishes
+	ExternalDownloader ExternalDownloader // Tool the byte transfer is delegated to, if any
+	Remux              Container          // Container videos are remuxed into with ffmpeg, ContainerSource to keep it
+	Chapters           ChapterMode        // What to do with chapters listed in video descriptions
+	TrashDir           string             // Folder removed videos are moved into instead of being deleted
+	StagingDir         string             // Folder videos are downloaded and processed in before being moved into place, empty for none
+	ArchiveOutput      string             // Archive the videos are written into instead of individual files, empty for none
+	RcloneRemote       string             // rclone remote path completed videos are uploaded to, e.g. "gdrive:Lectures", empty for none
+	Fsync              FsyncPolicy        // When downloaded data is flushed to disk
+	Order              DownloadOrder      // Order in which pending videos are downloaded
+	Sort               VideoSort          // Order in which channel videos are listed for selection
+	Include            string             // Regular expression channel video titles must match to be offered
+	Exclude            string             // Regular expression excluding matching channel video titles
+	MinDuration        time.Duration      // Channel videos shorter than this are not offered, 0 for no limit
+	MaxDuration        time.Duration      // Channel videos longer than this are not offered, 0 for no limit
+	FileMode           os.FileMode        // Permissions of created files, 0 for the default permissions
+	DirMode            os.FileMode        // Permissions of created folders, 0 for the default permissions
+	Note               string             // Note recorded with the downloaded videos in the history
+	Segments           int                // Number of concurrent byte ranges per video, 1 disables segmentation
+	EpisodePad         int                // Number of digits episode numbers are zero-padded to in filenames, 0 to keep them
+	Concurrency        int                // Maximum number of videos downloaded at once, 0 for no limit
+	WriteBuffer        int                // Number of bytes collected before they are written to a file, 0 to write directly
+	CopyBuffer         int                // Size in bytes of the buffer video data is copied through, 0 for the io.Copy default
+	UseEpisode         bool               // Whether to use episode numbers in filenames
+	Renumber           bool               // Whether channel videos are numbered sequentially instead of using their episode field
+	InferEpisodes      bool               // Whether channel videos without episode field are numbered by their position
+	Skip               bool               // Whether to skip existing files
+	Force              bool               // Whether to force overwrite existing files
+	Backup             bool               // Whether existing files are renamed to numbered backups instead of overwritten
+	All                bool               // Whether to download all videos
+	FolderTemplate     string             // Name of the channel folder with placeholders, DefaultFolderTemplate if empty
+	Flat               bool               // Whether to place channel videos directly in the output directory
+	DryRun             bool               // Whether to only report the folders a download would create, without writing anything
+	NoMtime            bool               // Whether to keep the download time instead of the publish date as mtime
+	NoManifest         bool               // Whether to skip writing manifest.json after a channel download
+	Playlist           bool               // Whether to write an .m3u8 playlist after a channel download
+	EmbedMetadata      bool               // Whether to embed title, episode, channel and date into the files with ffmpeg
+	ContactSheet       bool               // Whether to render a thumbnail grid image next to every video with ffmpeg
+	RcloneMove         bool               // Whether the local files are deleted once uploaded to RcloneRemote
+	WriteFeed          bool               // Whether to write an RSS feed after a channel download
+	Quiet              bool               // Whether to print only the final results
+	JSON               bool               // Whether to print the results as JSON to stdout
+	ForceLock          bool               // Whether to write into the output directory even if another run locked it
+	Sync               bool               // Whether videos in the history are recognized by ID instead of filename
+	RenameMoved        bool               // Whether local files of videos renamed on SwitchTube are renamed too when syncing
+	DeleteRemoved      bool               // Whether local files of videos removed from the channel are deleted when syncing
+}
+
+// ParseExternalDownloader converts a flag value into an ExternalDownloader.
+func ParseExternalDownloader(value string) (ExternalDownloader, error) {
+	switch downloader := ExternalDownloader(value); downloader {
+	case ExternalNone, ExternalAria2c, ExternalCurl:
+		return downloader, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected aria2c or curl)", errInvalidExternalDownloader, value)
+	}
+}
+
+// ParseChapterMode converts a flag value into a ChapterMode.
+func ParseChapterMode(value string) (ChapterMode, error) {
+	switch mode := ChapterMode(value); mode {
+	case ChaptersNone, ChaptersFile, ChaptersEmbed:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected file or embed)", errInvalidChapterMode, value)
+	}
+}
+
+// ParseContainer converts a flag value into a Container.
+func ParseContainer(value string) (Container, error) {
+	switch container := Container(value); container {
+	case ContainerSource, ContainerMKV, ContainerMP4:
+		return container, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected mkv or mp4)", errInvalidContainer, value)
+	}
+}
+
+// ParseFsyncPolicy converts a flag value into an FsyncPolicy.
+func ParseFsyncPolicy(value string) (FsyncPolicy, error) {
+	switch policy := FsyncPolicy(value); policy {
+	case FsyncNever, FsyncEnd, FsyncAlways:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected end or always)", errInvalidFsyncPolicy, value)
+	}
+}
+
+// ParseArchiveFormat returns the format of an archive by the extension of its filename.
+func ParseArchiveFormat(filename string) (ArchiveFormat, error) {
+	name := strings.ToLower(filename)
+
+	switch {
+	case strings.HasSuffix(name, ".tar"):
+		return ArchiveTar, nil
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ArchiveTarGz, nil
+	case strings.HasSuffix(name, ".zip"):
+		return ArchiveZip, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected a .tar, .tar.gz, .tgz or .zip file)", errInvalidArchiveFormat, filename)
+	}
+}
+
+// ParseQualityPolicy converts a flag value into a QualityPolicy.
+func ParseQualityPolicy(value string) (QualityPolicy, error) {
+	switch policy := QualityPolicy(value); policy {
+	case QualityHighest, QualityLowest, QualityPerVideo, QualityAsk:
+		return policy, nil
+	default:
+		if policy.Height() > 0 {
+			return policy, nil
+		}
+
+		return "", fmt.Errorf("%w: %q (expected highest, lowest, per-video, ask or a resolution like 720p)", errInvalidQualityPolicy, value)
+	}
+}
+
+// ParseDownloadOrder converts a flag value into a DownloadOrder.
+func ParseDownloadOrder(value string) (DownloadOrder, error) {
+	switch order := DownloadOrder(value); order {
+	case OrderSelection, OrderEpisode, OrderSmallest, OrderLargest:
+		return order, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected selection, episode, smallest or largest)", errInvalidDownloadOrder, value)
+	}
+}
+
+// ParseVideoSort converts a flag value into a VideoSort.
+func ParseVideoSort(value string) (VideoSort, error) {
+	switch sort := VideoSort(value); sort {
+	case SortChannel, SortEpisode, SortTitle, SortDate, SortDuration:
+		return sort, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected channel, episode, title, date or duration)", errInvalidVideoSort, value)
+	}
+}
+
+// ParseFileMode converts octal permissions like "0664" into a file mode.
+// An empty value keeps the default permissions and returns 0.
+func ParseFileMode(value string) (os.FileMode, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > uint64(os.ModePerm) {
+		return 0, fmt.Errorf("%w: %q (expected octal permissions like 0644)", errInvalidFileMode, value)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+// ParseFolderTemplate checks that a folder template only uses supported placeholders.
+func ParseFolderTemplate(value string) (string, error) {
+	if value == "" {
+		return "", fmt.Errorf("%w: must not be empty", errInvalidFolderTemplate)
+	}
+
+	for _, match := range folderPlaceholder.FindAllStringSubmatch(value, -1) {
+		if !slices.Contains(folderPlaceholders, match[1]) {
+			return "", fmt.Errorf("%w: unknown placeholder %s (expected {channel}, {id} or {year})", errInvalidFolderTemplate, match[0])
+		}
+	}
+
+	return value, nil
+}
+
+// ParseSchedule converts a time of day like "02:00" into its next occurrence after now.
+func ParseSchedule(value string, now time.Time) (time.Time, error) {
+	clock, err := time.ParseInLocation("15:04", value, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q (expected HH:MM)", errInvalidSchedule, value)
+	}
+
+	start := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !start.After(now) {
+		start = start.AddDate(0, 0, 1)
+	}
+
+	return start, nil
+}
+
+// ParseCollisionPolicy converts a flag value into a CollisionPolicy.
+func ParseCollisionPolicy(value string) (CollisionPolicy, error) {
+	switch policy := CollisionPolicy(value); policy {
+	case CollisionRename, CollisionRenameEpisode, CollisionRenameID, CollisionSkip, CollisionOverwrite, CollisionError:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected rename, rename-episode, rename-id, skip, overwrite or error)", errInvalidCollisionPolicy, value)
+	}
+}
+
+// ParseDuplicatePolicy converts a flag value into a DuplicatePolicy.
+func ParseDuplicatePolicy(value string) (DuplicatePolicy, error) {
+	switch policy := DuplicatePolicy(value); policy {
+	case DuplicateAsk, DuplicateLink, DuplicateCopy, DuplicateSkip, DuplicateDownload:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected ask, link, copy, skip or download)", errInvalidDuplicatePolicy, value)
+	}
+}
+
+// ParseLinkMode converts a flag value into a LinkMode.
+func ParseLinkMode(value string) (LinkMode, error) {
+	switch mode := LinkMode(value); mode {
+	case LinkHard, LinkSymbolic:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected hard or symlink)", errInvalidLinkMode, value)
+	}
 }
@@ -0,0 +1,6 @@
+package models
+
+// TokenProfile describes the account an access token belongs to.
+type TokenProfile struct {
+	Name string `json:"name"` // Display name of the account, empty if not reported
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// selectionIDPrefix marks a selection entry as video ID instead of position.
+const selectionIDPrefix = "id:"
+
+var (
+	errInvalidSelection    = errors.New("invalid selection")
+	errSelectionOutOfRange = errors.New("selection out of range")
+	errSelectionNotListed  = errors.New("selected video not listed")
+)
+
+// Selection picks channel videos by their position in the listed order, counted from 1
+// like in the list printed when the output is not a terminal, or by their video ID,
+// e.g. "1-5,8,id:AbCdEf". IDs keep selecting the same videos if the order changes.
+type Selection []selectionRange
+
+// selectionRange is a range of positions, both ends included, or a single video ID.
+type selectionRange struct {
+	id    string // Video ID, empty for positions
+	first int
+	last  int
+}
+
+// ParseSelection converts a selection like "1-5,8,id:AbCdEf" into a Selection. Entries
+// are separated by commas or whitespace, so a selection can also be read from a file
+// with one entry per line, where lines starting with # are comments.
+func ParseSelection(value string) (Selection, error) {
+	var selection Selection
+
+	for line := range strings.Lines(value) {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		for token := range strings.FieldsFuncSeq(line, isSelectionSeparator) {
+			r, err := parseSelectionRange(token)
+			if err != nil {
+				return nil, err
+			}
+
+			selection = append(selection, r)
+		}
+	}
+
+	if len(selection) == 0 {
+		return nil, fmt.Errorf("%w: %q (expected positions like 1-5,8 or IDs like id:AbCdEf)", errInvalidSelection, value)
+	}
+
+	return selection, nil
+}
+
+// Indices returns the indices of the selected videos out of the listed ones, in listed
+// order and without duplicates. Returns an error if a position or ID is not listed.
+func (s Selection) Indices(videos []Video) ([]int, error) {
+	count := len(videos)
+	selected := make([]bool, count)
+
+	for _, r := range s {
+		if r.id != "" {
+			i := slices.IndexFunc(videos, func(video Video) bool { return video.ID == r.id })
+			if i == -1 {
+				return nil, fmt.Errorf("%w: %s%s", errSelectionNotListed, selectionIDPrefix, r.id)
+			}
+
+			selected[i] = true
+
+			continue
+		}
+
+		if r.last > count {
+			return nil, fmt.Errorf("%w: %d (only %d videos are listed)", errSelectionOutOfRange, r.last, count)
+		}
+
+		for position := r.first; position <= r.last; position++ {
+			selected[position-1] = true
+		}
+	}
+
+	var indices []int
+
+	for i, ok := range selected {
+		if ok {
+			indices = append(indices, i)
+		}
+	}
+
+	return indices, nil
+}
+
+// parseSelectionRange converts a position like "8", a range like "1-5" or a video ID
+// like "id:AbCdEf".
+func parseSelectionRange(token string) (selectionRange, error) {
+	if id, ok := strings.CutPrefix(token, selectionIDPrefix); ok {
+		if id == "" {
+			return selectionRange{}, fmt.Errorf("%w: %q (expected a video ID after %s)", errInvalidSelection, token, selectionIDPrefix)
+		}
+
+		return selectionRange{id: id}, nil
+	}
+
+	firstValue, lastValue, isRange := strings.Cut(token, "-")
+	if !isRange {
+		lastValue = firstValue
+	}
+
+	first, err := strconv.Atoi(firstValue)
+	if err != nil || first < 1 {
+		return selectionRange{}, fmt.Errorf("%w: %q (expected a position from 1, a range like 1-5 or id:<video ID>)", errInvalidSelection, token)
+	}
+
+	last, err := strconv.Atoi(lastValue)
+	if err != nil || last < first {
+		return selectionRange{}, fmt.Errorf("%w: %q (expected a position from 1, a range like 1-5 or id:<video ID>)", errInvalidSelection, token)
+	}
+
+	return selectionRange{first: first, last: last}, nil
+}
+
+// isSelectionSeparator reports whether r separates the entries of a selection.
+func isSelectionSeparator(r rune) bool {
+	return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
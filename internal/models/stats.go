@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// DownloadStat records the transfer statistics of a single downloaded video.
+type DownloadStat struct {
+	Title   string        // The video title
+	File    string        // Path of the downloaded file
+	Bytes   int64         // Bytes written to disk
+	Started time.Time     // When the download started
+	Elapsed time.Duration // Time spent downloading
+}
+
+// Speed returns the average download speed in bytes per second.
+func (s DownloadStat) Speed() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(s.Bytes) / s.Elapsed.Seconds()
+}
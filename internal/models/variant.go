@@ -0,0 +1,15 @@
+package models
+
+// Variant represents a download variant of a video. The API lists the variants of a
+// video from the highest to the lowest quality.
+type Variant struct {
+	Path      string `json:"path"`       // Relative path to the video file on the server
+	MediaType string `json:"media_type"` //nolint:tagliatelle // API returns snake_case
+}
+
+// VariantFile holds details of a variant read from its file on the server, since the
+// API lists only the path and media type.
+type VariantFile struct {
+	Height int   // Frame height in pixels, 0 if unknown
+	Size   int64 // Size of the file in bytes, 0 if unknown
+}
@@ -1,8 +1,97 @@
 package models
 
-// Video represents a Video.
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Video represents a Video. Fields other than the ID and title are empty if the API
+// omits them, e.g. in channel listings of older instances.
 type Video struct {
-	ID      string `json:"id"`      // The video ID
-	Title   string `json:"title"`   // The video title
-	Episode string `json:"episode"` // The episode number
+	PublishedAt  time.Time `json:"published_at"`  //nolint:tagliatelle // API returns snake_case
+	ID           string    `json:"id"`            // The video ID
+	Title        string    `json:"title"`         // The video title
+	Description  string    `json:"description"`   // The video description
+	Episode      string    `json:"episode"`       // The episode number
+	ThumbnailURL string    `json:"thumbnail_url"` //nolint:tagliatelle // API returns snake_case
+	License      string    `json:"license"`       // License of the video, e.g. "CC BY 4.0"
+	URL          string    `json:"url"`           // Page of the video on SwitchTube
+	Duration     float64   `json:"duration"`      // Length in seconds, 0 if unknown
+	Size         int64     `json:"-"`             // Size of the variant to download in bytes, 0 if not looked up
+}
+
+// Length returns the duration of the video.
+func (v Video) Length() time.Duration {
+	return time.Duration(v.Duration * float64(time.Second))
+}
+
+// Compare orders two videos by the sort. Videos with equal keys compare as equal,
+// so a stable sort keeps their channel order.
+func (s VideoSort) Compare(a Video, b Video) int {
+	switch s {
+	case SortEpisode:
+		return CompareEpisodes(a.Episode, b.Episode)
+	case SortTitle:
+		return cmp.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	case SortDate:
+		return a.PublishedAt.Compare(b.PublishedAt)
+	case SortDuration:
+		return cmp.Compare(a.Duration, b.Duration)
+	case SortChannel:
+	}
+
+	return 0
+}
+
+// CompareEpisodes orders episode numbers naturally, so "2" sorts before "10".
+// Episodes without a leading number are compared as text, empty episodes sort last.
+func CompareEpisodes(a string, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	numA, restA := leadingNumber(a)
+	numB, restB := leadingNumber(b)
+
+	if numA >= 0 && numB >= 0 && numA != numB {
+		return cmp.Compare(numA, numB)
+	}
+
+	return cmp.Compare(restA, restB)
+}
+
+// PadEpisode zero-pads the leading number of an episode to width digits, e.g. "3a"
+// becomes "03a" for width 2. Episodes without a leading number are returned as they are.
+func PadEpisode(episode string, width int) string {
+	n, rest := leadingNumber(episode)
+	if n < 0 {
+		return episode
+	}
+
+	return fmt.Sprintf("%0*d%s", width, n, rest)
+}
+
+// leadingNumber splits s into its leading decimal number and the rest.
+// Returns -1 as number if s does not start with a digit.
+func leadingNumber(s string) (int, string) {
+	end := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) })
+	if end == -1 {
+		end = len(s)
+	}
+
+	n, err := strconv.Atoi(s[:end])
+	if err != nil {
+		return -1, s
+	}
+
+	return n, s[end:]
 }
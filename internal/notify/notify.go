@@ -0,0 +1,134 @@
+// Package notify sends a summary of finished download batches to external commands and webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/settings"
+)
+
+const (
+	// requestTimeout bounds the time spent on the webhook and command.
+	requestTimeout = 30 * time.Second
+	// summaryEnv is the environment variable holding the JSON summary for commands.
+	summaryEnv = "SWITCHTUBE_SUMMARY"
+)
+
+var (
+	errCommandFailed = errors.New("notify command failed")
+	errWebhookFailed = errors.New("notify webhook failed")
+)
+
+// Summary describes the outcome of a finished download batch.
+type Summary struct {
+	FinishedAt time.Time `json:"finishedAt"` // Time the batch finished
+	Media      string    `json:"media"`      // Video or channel ID/URL as passed by the user
+	Name       string    `json:"name"`       // Channel name or video title
+	Text       string    `json:"text"`       // Human-readable message, e.g. for Slack webhooks
+	Failed     []string  `json:"failed"`     // Titles of failed videos
+	Total      int       `json:"total"`      // Number of selected videos
+	Succeeded  int       `json:"succeeded"`  // Number of successful videos
+	Aborted    bool      `json:"aborted"`    // Whether the batch was aborted by the user
+}
+
+// NewSummary creates a summary for a batch of total videos of which failed did not succeed.
+func NewSummary(media string, name string, total int, failed []string, aborted bool) Summary {
+	succeeded := total - len(failed)
+
+	text := fmt.Sprintf("SwitchTube download of %s finished: %d/%d videos successful", name, succeeded, total)
+	if aborted {
+		text = fmt.Sprintf("SwitchTube download of %s was aborted", name)
+	}
+
+	return Summary{
+		FinishedAt: time.Now(),
+		Media:      media,
+		Name:       name,
+		Text:       text,
+		Failed:     failed,
+		Total:      total,
+		Succeeded:  succeeded,
+		Aborted:    aborted,
+	}
+}
+
+// Send runs command through the shell and posts to webhook, skipping empty targets.
+// The command receives the JSON summary on stdin and in $SWITCHTUBE_SUMMARY.
+func Send(command string, webhook string, summary Summary) error {
+	payload, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	var errs []error
+
+	if command != "" {
+		errs = append(errs, runCommand(ctx, command, payload))
+	}
+
+	if webhook != "" {
+		errs = append(errs, postWebhook(ctx, webhook, payload))
+	}
+
+	return errors.Join(errs...)
+}
+
+// postWebhook sends payload as JSON POST request to webhook.
+func postWebhook(ctx context.Context, webhook string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("%w: %w", errWebhookFailed, err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", settings.UserAgent())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errWebhookFailed, err)
+	}
+
+	if err := resp.Body.Close(); err != nil {
+		return fmt.Errorf("%w: %w", errWebhookFailed, err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: status %d: %s", errWebhookFailed, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	return nil
+}
+
+// runCommand runs command through the platform shell with payload on stdin.
+func runCommand(ctx context.Context, command string, payload []byte) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = stream.UI()
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), summaryEnv+"="+string(payload))
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %q: %w", errCommandFailed, command, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+// Package onboarding guides first-time users through setting up the downloader.
+package onboarding
+
+import (
+	"cmp"
+	"fmt"
+
+	"switchtube-downloader/internal/helper/ui/input"
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/settings"
+	"switchtube-downloader/internal/token"
+)
+
+// Needed reports whether the downloader runs for the first time, with no access token
+// stored and no configuration file written yet, and the setup can be shown.
+func Needed(tm *token.Manager) bool {
+	if input.PromptsDisabled() || !stream.IsTerminal() {
+		return false
+	}
+
+	return !settings.Exists() && !tm.Stored()
+}
+
+// Run guides through creating and storing an access token and asks for the default
+// output directory, color and concurrency preferences, which are written to the
+// configuration file on top of cfg. Returns the written configuration.
+func Run(tm *token.Manager, cfg settings.Config) (settings.Config, error) {
+	fmt.Fprintln(stream.UI(), i18n.T("Welcome! No access token is stored yet, so let's set up the downloader."))
+
+	if err := tm.Set(); err != nil {
+		return cfg, fmt.Errorf("failed to set up token: %w", err)
+	}
+
+	answers, err := input.AskSetup(input.SetupAnswers{
+		OutputDir:   cfg.OutputDir,
+		Color:       cmp.Or(cfg.Color, stream.ColorAuto),
+		Concurrency: cfg.Concurrency,
+	})
+	if err != nil {
+		return cfg, err //nolint:wrapcheck // Returned as is so ErrUserAbort stays recognizable
+	}
+
+	cfg.OutputDir, cfg.Color, cfg.Concurrency = answers.OutputDir, answers.Color, answers.Concurrency
+
+	if err := settings.Save(cfg); err != nil {
+		return cfg, err //nolint:wrapcheck // Already wrapped by the settings package
+	}
+
+	if path, err := settings.Path(); err == nil {
+		fmt.Fprintln(stream.UI(), i18n.T("Saved your preferences to %s, the flags of each command still take precedence", path))
+	}
+
+	return cfg, nil
+}
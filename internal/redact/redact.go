@@ -0,0 +1,95 @@
+// Package redact masks access tokens and other credentials in messages, so output pasted
+// into a bug report does not leak them.
+package redact
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Mask replaces redacted values.
+const Mask = "[REDACTED]"
+
+// minSecretLength keeps short values from being registered, which would mask common words.
+const minSecretLength = 8
+
+// patterns match credentials that were not registered, e.g. tokens of other tools. The
+// last group of every pattern is replaced by Mask.
+//
+//nolint:gochecknoglobals // Compiled once
+var patterns = []*regexp.Regexp{
+	// Header values, e.g. "Authorization: Token abc", "Authorization:[Token abc]" or "cookie=abc"
+	regexp.MustCompile(`(?i)(\b(?:proxy-)?authorization\b["']?\s*[:=]\s*["'\[]?(?:(?:token|bearer|basic)\s+)?|\bcookie\b["']?\s*[:=]\s*["'\[]?)([^\s"',;\]]+)`),
+	// Auth schemes followed by a long credential
+	regexp.MustCompile(`(\b(?:Token|Bearer)\s+)([A-Za-z0-9._~+/=-]{20,})`),
+	// Query parameters carrying credentials
+	regexp.MustCompile(`(?i)([?&](?:access_token|token|api_key|apikey|key|signature|sig)=)([^&\s"']+)`),
+}
+
+//nolint:gochecknoglobals // Secrets registered while running, e.g. the access token
+var (
+	secrets      []string
+	secretsMutex sync.RWMutex
+)
+
+// Add registers secret, so every later String masks it wherever it appears.
+func Add(secret string) {
+	secret = strings.TrimSpace(secret)
+	if len(secret) < minSecretLength {
+		return
+	}
+
+	secretsMutex.Lock()
+	defer secretsMutex.Unlock()
+
+	if slices.Contains(secrets, secret) {
+		return
+	}
+
+	secrets = append(secrets, secret)
+}
+
+// String returns s with the registered secrets and anything looking like a credential
+// replaced by Mask.
+func String(s string) string {
+	secretsMutex.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Mask)
+	}
+	secretsMutex.RUnlock()
+
+	for _, pattern := range patterns {
+		s = pattern.ReplaceAllString(s, "${1}"+Mask)
+	}
+
+	return s
+}
+
+// file redacts everything written to the wrapped file. The file stays recognizable as
+// terminal by its descriptor, so colors are kept.
+type file struct {
+	*os.File
+}
+
+// Stream returns a writer redacting everything written to f, e.g. for loggers.
+func Stream(f *os.File) io.Writer {
+	return file{File: f}
+}
+
+// Write writes p to the file with credentials masked. Reports the length of p as written.
+func (f file) Write(p []byte) (int, error) {
+	if _, err := f.File.WriteString(String(string(p))); err != nil {
+		return 0, err //nolint:wrapcheck // Plain passthrough to the file
+	}
+
+	return len(p), nil
+}
+
+// WriteString writes s to the file with credentials masked.
+func (f file) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
@@ -0,0 +1,270 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"switchtube-downloader/internal/download"
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/redact"
+)
+
+// maxPendingJobs is the number of jobs that can wait for the running one to finish.
+const maxPendingJobs = 100
+
+// Job states reported by the API.
+const (
+	statusQueued    = "queued"
+	statusRunning   = "running"
+	statusDone      = "done"
+	statusFailed    = "failed"
+	statusCancelled = "cancelled"
+)
+
+// videoState is the progress of a single video of a job.
+type videoState struct {
+	ID      string `json:"id"`      // Video ID
+	Title   string `json:"title"`   // Video title
+	Status  string `json:"status"`  // queued, running, done or failed
+	Written int64  `json:"written"` // Bytes written so far
+	Total   int64  `json:"total"`   // Expected total bytes, -1 if unknown
+}
+
+// job is an enqueued download of a video or channel.
+type job struct {
+	CreatedAt  time.Time            `json:"createdAt"`           // Time the job was enqueued
+	FinishedAt time.Time            `json:"finishedAt,omitzero"` // Time the job finished
+	ID         string               `json:"id"`                  // Job ID
+	Media      string               `json:"media"`               // Video or channel ID/URL as requested
+	Name       string               `json:"name,omitempty"`      // Channel name or video title, once resolved
+	Status     string               `json:"status"`              // queued, running, done, failed or cancelled
+	Error      string               `json:"error,omitempty"`     // Reason the job failed
+	Quality    models.QualityPolicy `json:"quality"`             // Variant to download
+	VideoIDs   []string             `json:"videoIds,omitempty"`  // Requested subset of a channel, all if empty
+	Videos     []videoState         `json:"videos,omitempty"`    // Progress of the selected videos
+	cancel     context.CancelFunc   // Cancels the running job
+}
+
+// jobStore holds all jobs of the server and runs them one after the other.
+type jobStore struct {
+	session *download.Session
+	pending chan *job
+	jobs    []*job
+	nextID  int
+	mutex   sync.Mutex
+}
+
+// newJobStore creates a jobStore downloading through session.
+func newJobStore(session *download.Session) *jobStore {
+	return &jobStore{session: session, pending: make(chan *job, maxPendingJobs)}
+}
+
+// cancel stops a queued or running job. Returns false if the job is unknown or finished.
+func (s *jobStore) cancel(id string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, j := range s.jobs {
+		if j.ID != id {
+			continue
+		}
+
+		switch j.Status {
+		case statusQueued:
+			j.Status = statusCancelled
+			j.FinishedAt = time.Now()
+
+			return true
+		case statusRunning:
+			j.cancel()
+
+			return true
+		default:
+			return false
+		}
+	}
+
+	return false
+}
+
+// enqueue adds a job for media. Returns false if too many jobs are pending.
+func (s *jobStore) enqueue(media string, quality models.QualityPolicy, videoIDs []string) (job, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.nextID++
+	j := &job{
+		CreatedAt: time.Now(),
+		ID:        strconv.Itoa(s.nextID),
+		Media:     media,
+		Status:    statusQueued,
+		Quality:   quality,
+		VideoIDs:  videoIDs,
+	}
+
+	select {
+	case s.pending <- j:
+	default:
+		return job{}, false
+	}
+
+	s.jobs = append(s.jobs, j)
+
+	return s.snapshot(j), true
+}
+
+// finish records the outcome of a job.
+func (s *jobStore) finish(j *job, status string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	j.Status = status
+	j.FinishedAt = time.Now()
+	j.cancel = nil
+
+	if err != nil {
+		j.Error = redact.String(err.Error())
+	}
+}
+
+// get returns a copy of the job with the given ID.
+func (s *jobStore) get(id string) (job, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, j := range s.jobs {
+		if j.ID == id {
+			return s.snapshot(j), true
+		}
+	}
+
+	return job{}, false
+}
+
+// list returns copies of all jobs, oldest first.
+func (s *jobStore) list() []job {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	jobs := make([]job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, s.snapshot(j))
+	}
+
+	return jobs
+}
+
+// markResults sets the final status of every video of a finished job.
+func (s *jobStore) markResults(j *job, failed []models.Video) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range j.Videos {
+		video := &j.Videos[i]
+
+		video.Status = statusDone
+		if slices.ContainsFunc(failed, func(f models.Video) bool { return f.ID == video.ID }) {
+			video.Status = statusFailed
+		}
+	}
+}
+
+// process resolves and downloads a single job.
+func (s *jobStore) process(ctx context.Context, j *job) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.mutex.Lock()
+	if j.Status == statusCancelled {
+		s.mutex.Unlock()
+
+		return
+	}
+
+	j.Status = statusRunning
+	j.cancel = cancel
+	s.mutex.Unlock()
+
+	listing, err := s.session.Lookup(ctx, j.Media)
+	if err != nil {
+		s.finish(j, statusFailed, err)
+
+		return
+	}
+
+	var indices []int
+
+	videos := make([]videoState, 0, len(listing.Videos))
+
+	for i, video := range listing.Videos {
+		if len(j.VideoIDs) > 0 && !slices.Contains(j.VideoIDs, video.ID) {
+			continue
+		}
+
+		indices = append(indices, i)
+		videos = append(videos, videoState{ID: video.ID, Title: video.Title, Status: statusQueued, Total: -1})
+	}
+
+	s.mutex.Lock()
+	j.Name = listing.Name
+	j.Videos = videos
+	s.mutex.Unlock()
+
+	failed, err := s.session.Download(ctx, listing, indices, j.Quality, func(videoID string, written int64, total int64) {
+		s.updateProgress(j, videoID, written, total)
+	})
+
+	switch {
+	case ctx.Err() != nil:
+		s.finish(j, statusCancelled, nil)
+	case err != nil:
+		s.finish(j, statusFailed, err)
+	default:
+		s.markResults(j, failed)
+
+		status := statusDone
+		if len(failed) > 0 {
+			status = statusFailed
+		}
+
+		s.finish(j, status, nil)
+	}
+}
+
+// run processes the pending jobs until ctx is cancelled.
+func (s *jobStore) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-s.pending:
+			s.process(ctx, j)
+		}
+	}
+}
+
+// snapshot returns a copy of j that can be encoded without holding the lock.
+// Caller must hold the mutex.
+func (s *jobStore) snapshot(j *job) job {
+	c := *j
+	c.Videos = slices.Clone(j.Videos)
+
+	return c
+}
+
+// updateProgress records the progress reported for a video of a running job.
+func (s *jobStore) updateProgress(j *job, videoID string, written int64, total int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range j.Videos {
+		if j.Videos[i].ID == videoID {
+			j.Videos[i].Status = statusRunning
+			j.Videos[i].Written = written
+			j.Videos[i].Total = total
+		}
+	}
+}
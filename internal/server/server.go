@@ -0,0 +1,468 @@
+// Package server exposes the download engine through a small local HTTP API,
+// so browser extensions or graphical frontends can enqueue and follow downloads.
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"switchtube-downloader/internal/download"
+	"switchtube-downloader/internal/helper/dir"
+	"switchtube-downloader/internal/helper/ui/input"
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/history"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/metrics"
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/redact"
+	"switchtube-downloader/internal/systemd"
+)
+
+const (
+	readHeaderTimeout = 10 * time.Second // Maximum time to read the request headers
+	shutdownTimeout   = 5 * time.Second  // Maximum time to finish open requests on shutdown
+	maxRequestBody    = 64 << 10         // Maximum size of a request body in bytes
+	socketDialTimeout = time.Second      // Maximum time to check whether a socket is in use
+)
+
+// extensionSchemes are the schemes of browser extension origins, the only origins that
+// may be allowed to call the API. Websites themselves are never allowed.
+//
+//nolint:gochecknoglobals // Read-only list of schemes
+var extensionSchemes = []string{"chrome-extension://", "moz-extension://", "safari-web-extension://"}
+
+// localHosts are the hosts requests over TCP must be addressed to. A website that rebinds
+// its own domain to 127.0.0.1 still sends its domain as Host and is rejected.
+//
+//nolint:gochecknoglobals // Read-only list of hosts
+var localHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+var (
+	errFailedToListen   = errors.New("failed to listen")
+	errInvalidExtension = errors.New("extension origin must be scheme://id, e.g. chrome-extension://abcdefghijklmnopabcdefghijklmnop")
+	errNotLoopback      = errors.New("refusing to listen on an address other clients can reach, use a loopback address like 127.0.0.1")
+	errNotASocket       = errors.New("refusing to replace a file that is not a socket")
+	errSocketInUse      = errors.New("socket already in use by a running server")
+)
+
+// socketConnKey marks the context of connections accepted on the unix socket, which
+// only local users can reach and whose requests carry no meaningful Host.
+type socketConnKey struct{}
+
+// enqueueRequest is the body of POST /api/downloads.
+type enqueueRequest struct {
+	Media    string               `json:"media"`    // Video or channel ID/URL
+	Quality  models.QualityPolicy `json:"quality"`  // highest or lowest, highest if empty
+	VideoIDs []string             `json:"videoIds"` // Subset of a channel, all if empty
+}
+
+// errorResponse is the body of every failed request.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// api holds the handlers of the HTTP API.
+type api struct {
+	jobs *jobStore
+}
+
+// Run serves the HTTP API on addr, and on the unix socket at socketPath if set, until
+// interrupted. Either may be empty to not listen there. Browser extensions may only call
+// the API if their origin, e.g. chrome-extension://<id>, is listed in extensions. Jobs
+// are downloaded one after the other with config. Prompts are disabled and existing
+// files are skipped unless forced. addr must be a loopback address, as the API
+// downloads with the user's token for anyone who can reach it.
+func Run(config models.DownloadConfig, addr string, socketPath string, extensions []string) error {
+	if addr != "" {
+		if err := checkLoopback(addr); err != nil {
+			return err
+		}
+	}
+
+	for _, origin := range extensions {
+		if err := checkExtension(origin); err != nil {
+			return err
+		}
+	}
+
+	input.DisablePrompts()
+
+	config.Skip = !config.Force
+
+	unlock, err := dir.LockFolder(cmp.Or(config.OutputDir, "."), config.ForceLock)
+	if err != nil {
+		return err //nolint:wrapcheck // Already wrapped by the dir package
+	}
+	defer unlock()
+
+	session, err := download.NewSession(config, os.Stderr)
+	if err != nil {
+		return err //nolint:wrapcheck // Already wrapped by the download package
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Listening first, as the umask of the socket applies to the whole process
+	listeners, err := listen(addr, socketPath)
+	if err != nil {
+		return err
+	}
+
+	jobs := newJobStore(session)
+	go jobs.run(ctx)
+
+	server := &http.Server{
+		Handler:           requireLocalHost(allowExtensions(newHandler(&api{jobs: jobs}), extensions)),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+			if conn.LocalAddr().Network() == "unix" {
+				return context.WithValue(ctx, socketConnKey{}, true)
+			}
+
+			return ctx
+		},
+	}
+
+	serveErr := make(chan error, len(listeners))
+
+	for _, listener := range listeners {
+		go func() {
+			serveErr <- server.Serve(listener)
+		}()
+
+		fmt.Fprintln(os.Stderr, i18n.T("Listening on %s", describe(listener)))
+	}
+
+	if err := systemd.Ready("Listening on " + describe(listeners[0])); err != nil {
+		stream.Warnf(os.Stderr, "%v", err)
+	}
+
+	go watchdog(ctx, listeners[0])
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("%w: %w", errFailedToListen, err)
+	case <-ctx.Done():
+	}
+
+	systemd.Stopping()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	//nolint:contextcheck // The interrupted context cannot be used to shut down gracefully
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToListen, err)
+	}
+
+	return input.ErrUserAbort
+}
+
+// allowExtensions answers CORS requests of the browser extensions with the given
+// origins, so an extension can enqueue the video or channel the user is looking at with
+// a single click. Other origins get no CORS headers and are blocked by the browser.
+func allowExtensions(next http.Handler, origins []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !slices.Contains(origins, origin) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireLocalHost rejects requests over TCP whose Host is not a loopback name or
+// address, so websites cannot reach the API through DNS rebinding.
+func requireLocalHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Context().Value(socketConnKey{}) != nil || isLocalHost(r.Host) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		writeError(w, http.StatusForbidden, "host must be localhost, 127.0.0.1 or [::1]")
+	})
+}
+
+// isLocalHost reports whether the Host header host names the local machine, with or
+// without port.
+func isLocalHost(host string) bool {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	return slices.Contains(localHosts, strings.ToLower(host))
+}
+
+// checkExtension returns errInvalidExtension unless origin is the origin of a single
+// browser extension, a known scheme followed by the extension ID.
+func checkExtension(origin string) error {
+	for _, scheme := range extensionSchemes {
+		if id, ok := strings.CutPrefix(origin, scheme); ok && id != "" && !strings.ContainsAny(id, "/*") {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", errInvalidExtension, origin)
+}
+
+// checkLoopback returns errNotLoopback unless every address the host of addr resolves
+// to is a loopback address. An empty host would listen on all interfaces.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToListen, err)
+	}
+
+	if host == "" {
+		return fmt.Errorf("%w: %s", errNotLoopback, addr)
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(context.Background(), host)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToListen, err)
+	}
+
+	for _, ip := range ips {
+		if !ip.IP.IsLoopback() {
+			return fmt.Errorf("%w: %s", errNotLoopback, addr)
+		}
+	}
+
+	return nil
+}
+
+// closeAll closes listeners that are not served yet.
+func closeAll(listeners []net.Listener) {
+	for _, listener := range listeners {
+		_ = listener.Close()
+	}
+}
+
+// describe returns the address of a listener for the startup message.
+func describe(listener net.Listener) string {
+	if listener.Addr().Network() == "unix" {
+		return listener.Addr().String()
+	}
+
+	return "http://" + listener.Addr().String()
+}
+
+// listen opens a TCP listener on addr and a unix socket listener at socketPath,
+// skipping empty ones.
+func listen(addr string, socketPath string) ([]net.Listener, error) {
+	var listeners []net.Listener
+
+	if addr != "" {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errFailedToListen, err)
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	if socketPath != "" {
+		listener, err := listenSocket(socketPath)
+		if err != nil {
+			closeAll(listeners)
+
+			return nil, err
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
+// removeStaleSocket deletes the socket a previous run left at path. A socket a running
+// server still accepts connections on is kept and reported as errSocketInUse, anything
+// else at path as errNotASocket. Only a socket refusing connections counts as stale.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToListen, err)
+	}
+
+	if info.Mode().Type() != os.ModeSocket {
+		return fmt.Errorf("%w: %s", errNotASocket, path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, socketDialTimeout)
+	if err == nil {
+		_ = conn.Close()
+
+		return fmt.Errorf("%w: %s", errSocketInUse, path)
+	}
+
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("%w: %w", errFailedToListen, err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToListen, err)
+	}
+
+	return nil
+}
+
+// newHandler registers the routes of the API.
+func newHandler(a *api) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/downloads", a.enqueue)
+	mux.HandleFunc("GET /api/downloads", a.list)
+	mux.HandleFunc("GET /api/downloads/{id}", a.get)
+	mux.HandleFunc("DELETE /api/downloads/{id}", a.cancel)
+	mux.HandleFunc("GET /api/history", a.history)
+	mux.HandleFunc("GET "+healthPath, a.health)
+	mux.Handle("GET "+metrics.Path, metrics.Handler())
+
+	return mux
+}
+
+// cancel handles DELETE /api/downloads/{id}.
+func (a *api) cancel(w http.ResponseWriter, r *http.Request) {
+	if !a.jobs.cancel(r.PathValue("id")) {
+		writeError(w, http.StatusNotFound, "no queued or running download with this ID")
+
+		return
+	}
+
+	j, _ := a.jobs.get(r.PathValue("id"))
+	writeJSON(w, http.StatusOK, j)
+}
+
+// enqueue handles POST /api/downloads.
+// Only JSON bodies are accepted, so websites cannot enqueue downloads with simple
+// cross-origin requests that skip the CORS preflight.
+func (a *api) enqueue(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "content type must be application/json")
+
+		return
+	}
+
+	var req enqueueRequest
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+
+		return
+	}
+
+	req.Media = strings.TrimSpace(req.Media)
+	if req.Media == "" {
+		writeError(w, http.StatusBadRequest, "media is required")
+
+		return
+	}
+
+	switch req.Quality {
+	case "":
+		req.Quality = models.QualityHighest
+	case models.QualityHighest, models.QualityLowest:
+	default:
+		writeError(w, http.StatusBadRequest, "quality must be highest or lowest")
+
+		return
+	}
+
+	j, ok := a.jobs.enqueue(req.Media, req.Quality, req.VideoIDs)
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, "too many pending downloads")
+
+		return
+	}
+
+	w.Header().Set("Location", "/api/downloads/"+j.ID)
+	writeJSON(w, http.StatusAccepted, j)
+}
+
+// get handles GET /api/downloads/{id}.
+func (a *api) get(w http.ResponseWriter, r *http.Request) {
+	j, ok := a.jobs.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "no download with this ID")
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, j)
+}
+
+// history handles GET /api/history, filtered by the tag query parameter if given.
+func (a *api) history(w http.ResponseWriter, r *http.Request) {
+	entries, err := history.Load()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+
+	entries = history.FilterByTag(entries, strings.TrimSpace(r.URL.Query().Get("tag")))
+
+	if entries == nil {
+		entries = []history.Entry{}
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// list handles GET /api/downloads.
+func (a *api) list(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, a.jobs.list())
+}
+
+// writeError writes an error response with the given status code, with credentials masked.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: redact.String(message)})
+}
+
+// writeJSON writes value as JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		stream.Warnf(os.Stderr, "failed to write response: %v", err)
+	}
+}
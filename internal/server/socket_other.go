@@ -0,0 +1,34 @@
+//go:build !linux && !darwin
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// socketPermissions restricts the unix socket to the current user.
+const socketPermissions = 0o600
+
+// listenSocket listens on a unix socket at path, replacing the stale socket of a
+// previous run, and restricts it to the current user where the platform supports file
+// permissions.
+func listenSocket(path string) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errFailedToListen, err)
+	}
+
+	if err := os.Chmod(path, socketPermissions); err != nil {
+		_ = listener.Close()
+
+		return nil, fmt.Errorf("%w: %w", errFailedToListen, err)
+	}
+
+	return listener, nil
+}
@@ -0,0 +1,32 @@
+//go:build linux || darwin
+
+package server
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// socketUmask leaves read and write permission on the socket to the current user only.
+const socketUmask = 0o177
+
+// listenSocket listens on a unix socket at path, replacing the stale socket of a
+// previous run. The socket is created with the permissions restricted by the umask, so
+// it is never reachable by other users, not even until its permissions could be changed.
+func listenSocket(path string) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	previous := unix.Umask(socketUmask)
+	listener, err := net.Listen("unix", path)
+	unix.Umask(previous)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errFailedToListen, err)
+	}
+
+	return listener, nil
+}
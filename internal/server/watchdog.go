@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"switchtube-downloader/internal/systemd"
+)
+
+// healthPath is the path of the health check, answered while the job store responds.
+const healthPath = "/api/health"
+
+// healthResponse is the body of GET /api/health.
+type healthResponse struct {
+	Status string `json:"status"`
+	Jobs   int    `json:"jobs"` // Number of jobs, queued, running or finished
+}
+
+// health handles GET /api/health.
+func (a *api) health(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok", Jobs: len(a.jobs.list())})
+}
+
+// watchdog pings the watchdog of the service manager, if it has one, whenever the health
+// check answers through listener, until ctx is done. A server that stops answering
+// requests is restarted that way, not only one whose process hangs.
+func watchdog(ctx context.Context, listener net.Listener) {
+	interval, ok := systemd.WatchdogInterval()
+	if !ok {
+		return
+	}
+
+	addr := listener.Addr()
+	client := &http.Client{
+		Timeout: interval,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+
+				return dialer.DialContext(ctx, addr.Network(), addr.String())
+			},
+		},
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if healthy(ctx, client) {
+				systemd.Watchdog()
+			}
+		}
+	}
+}
+
+// healthy reports whether the health check answers through client.
+func healthy(ctx context.Context, client *http.Client) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost"+healthPath, nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+
+	_ = resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK
+}
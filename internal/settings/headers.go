@@ -0,0 +1,88 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// userAgentProduct names the downloader in the User-Agent header.
+const userAgentProduct = "switchtube-downloader"
+
+// reservedHeaders are set by the downloader itself and cannot be given with --header.
+//
+//nolint:gochecknoglobals // Read-only lookup table
+var reservedHeaders = []string{"Authorization", "Host", "Range"}
+
+var errInvalidHeader = errors.New("invalid header")
+
+//nolint:gochecknoglobals // Set once at startup from the build version
+var userAgent = formatUserAgent("unknown")
+
+//nolint:gochecknoglobals // Set once at startup from the global --header flags
+var extraHeaders = http.Header{}
+
+// UserAgent returns the User-Agent sent with every request, naming the downloader and its version.
+func UserAgent() string {
+	if agent := extraHeaders.Get("User-Agent"); agent != "" {
+		return agent
+	}
+
+	return userAgent
+}
+
+// SetVersion sets the version reported in the default User-Agent.
+func SetVersion(version string) {
+	userAgent = formatUserAgent(version)
+}
+
+// SetHeaders validates headers given as "Name: value" and sends them with every request
+// to SwitchTube. A User-Agent among them replaces the default one.
+func SetHeaders(headers []string) error {
+	parsed := http.Header{}
+
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("%w: %q (expected \"Name: value\")", errInvalidHeader, header)
+		}
+
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		if slices.Contains(reservedHeaders, name) {
+			return fmt.Errorf("%w: %s is set by the downloader", errInvalidHeader, name)
+		}
+
+		parsed.Add(name, strings.TrimSpace(value))
+	}
+
+	extraHeaders = parsed
+
+	return nil
+}
+
+// Headers returns the headers sent with every request to SwitchTube, the User-Agent and
+// the headers set by SetHeaders.
+func Headers() http.Header {
+	headers := extraHeaders.Clone()
+	headers.Set("User-Agent", UserAgent())
+
+	return headers
+}
+
+// ApplyHeaders sets the headers returned by Headers on req.
+func ApplyHeaders(req *http.Request) {
+	for name, values := range Headers() {
+		req.Header[name] = values
+	}
+}
+
+// formatUserAgent returns the default User-Agent for version.
+func formatUserAgent(version string) string {
+	return fmt.Sprintf("%s/%s (%s; %s; +https://github.com/niekdomi/SwitchTube-Downloader)", userAgentProduct, version, runtime.GOOS, runtime.GOARCH)
+}
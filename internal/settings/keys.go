@@ -0,0 +1,154 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/models"
+)
+
+var (
+	errUnknownKey   = errors.New("unknown config key")
+	errInvalidValue = errors.New("invalid config value")
+)
+
+// Key is a setting of the configuration file that can be read and changed by name.
+type Key struct {
+	Name        string // Name of the setting in the configuration file
+	Description string // What the setting controls and which values it accepts
+
+	field    func(cfg *Config) any              // Pointer to the string or int field in cfg
+	validate func(value string) (string, error) // Returns value as it is stored
+}
+
+// Keys returns the settings of the configuration file in the order they are listed.
+func Keys() []Key {
+	return []Key{
+		{
+			Name:        "baseUrl",
+			Description: "SwitchTube instance to use, e.g. " + DefaultBaseURL,
+			field:       func(cfg *Config) any { return &cfg.BaseURL },
+			validate:    normalizeBaseURL,
+		},
+		{
+			Name:        "color",
+			Description: "Whether output is colored: auto, always or never",
+			field:       func(cfg *Config) any { return &cfg.Color },
+			validate: func(value string) (string, error) {
+				switch value {
+				case stream.ColorAuto, stream.ColorAlways, stream.ColorNever:
+					return value, nil
+				default:
+					return "", fmt.Errorf("%q (expected auto, always or never)", value)
+				}
+			},
+		},
+		{
+			Name:        "concurrency",
+			Description: "Videos downloaded at once by download, watch, serve and tui unless --concurrency is given, 0 for all",
+			field:       func(cfg *Config) any { return &cfg.Concurrency },
+			validate: func(value string) (string, error) {
+				concurrency, err := strconv.Atoi(value)
+				if err != nil || concurrency < 0 {
+					return "", fmt.Errorf("%q (expected a number from 0)", value)
+				}
+
+				return strconv.Itoa(concurrency), nil
+			},
+		},
+		{
+			Name:        "outputDir",
+			Description: "Folder download, watch, serve and tui write to unless --output is given",
+			field:       func(cfg *Config) any { return &cfg.OutputDir },
+			validate: func(value string) (string, error) {
+				if value == "-" {
+					return "", fmt.Errorf("%q (writing to stdout is only available with --output)", value)
+				}
+
+				return value, nil
+			},
+		},
+		{
+			Name:        "remux",
+			Description: "Container the download command remuxes into unless --remux is given: mkv or mp4",
+			field:       func(cfg *Config) any { return &cfg.Remux },
+			validate: func(value string) (string, error) {
+				container, err := models.ParseContainer(value)
+
+				return string(container), err //nolint:wrapcheck // Wrapped by Set
+			},
+		},
+	}
+}
+
+// LookupKey returns the setting called name. Returns an error listing the known
+// settings if there is none.
+func LookupKey(name string) (Key, error) {
+	keys := Keys()
+	names := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		if key.Name == name {
+			return key, nil
+		}
+
+		names = append(names, key.Name)
+	}
+
+	return Key{}, fmt.Errorf("%w: %q (expected one of %s)", errUnknownKey, name, strings.Join(names, ", "))
+}
+
+// Get returns the value of the setting in cfg, empty if it is not set.
+func (k Key) Get(cfg Config) string {
+	switch field := k.field(&cfg).(type) {
+	case *string:
+		return *field
+	case *int:
+		if *field == 0 {
+			return ""
+		}
+
+		return strconv.Itoa(*field)
+	default:
+		return ""
+	}
+}
+
+// Set validates value and stores it as the setting in cfg. An empty value removes the
+// setting, so its default applies again.
+func (k Key) Set(cfg *Config, value string) error {
+	value = strings.TrimSpace(value)
+
+	if value != "" {
+		validated, err := k.validate(value)
+		if err != nil {
+			return fmt.Errorf("%w for %s: %w", errInvalidValue, k.Name, err)
+		}
+
+		value = validated
+	}
+
+	switch field := k.field(cfg).(type) {
+	case *string:
+		*field = value
+	case *int:
+		*field, _ = strconv.Atoi(value) // Validated above, 0 if removed
+	}
+
+	return nil
+}
+
+// Validate returns an error for the first setting of cfg with an invalid value, e.g.
+// after the configuration file was edited by hand.
+func (cfg Config) Validate() error {
+	for _, key := range Keys() {
+		if err := key.Set(&cfg, key.Get(cfg)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
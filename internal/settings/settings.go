@@ -0,0 +1,226 @@
+// Package settings provides the persisted user configuration and the SwitchTube instance in use.
+package settings
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"switchtube-downloader/internal/helper/dir"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultBaseURL is the SwitchTube instance used unless configured otherwise.
+	DefaultBaseURL = "https://tube.switch.ch/"
+	// SelectionEnv is the environment variable selecting channel videos without prompting.
+	SelectionEnv = "SWITCHTUBE_SELECTION"
+	// TokenEnv is the environment variable giving the access token instead of the keyring.
+	TokenEnv = "SWITCHTUBE_TOKEN"
+
+	// configFilename is the configuration file in the config directory.
+	configFilename = "config.yaml"
+	// configPermissions are the permissions of the written configuration file.
+	configPermissions = 0o644
+)
+
+// ErrNoConfigDir is returned by Load if the config directory cannot be located or
+// created, e.g. in a container without home directory.
+var ErrNoConfigDir = errors.New("failed to locate config directory")
+
+var (
+	errFailedToLoadConfig = errors.New("failed to load config")
+	errFailedToSaveConfig = errors.New("failed to save config")
+	errInvalidBaseURL     = errors.New("invalid base URL")
+)
+
+//nolint:gochecknoglobals // Resolved once at startup and read by the API client and token manager
+var baseURL = DefaultBaseURL
+
+// Config holds the settings persisted in the configuration file.
+type Config struct {
+	BaseURL     string `yaml:"baseUrl,omitempty"`     // SwitchTube instance to use
+	Remux       string `yaml:"remux,omitempty"`       // Container downloads are remuxed into unless --remux is given
+	OutputDir   string `yaml:"outputDir,omitempty"`   // Folder downloads are written to unless --output is given
+	Color       string `yaml:"color,omitempty"`       // Whether output is colored: auto, always or never
+	Concurrency int    `yaml:"concurrency,omitempty"` // Videos downloaded at once unless --concurrency is given, 0 for all
+}
+
+// BaseURL returns the base URL of the SwitchTube instance in use, always ending with a slash.
+func BaseURL() string {
+	return baseURL
+}
+
+// Host returns the host of the SwitchTube instance in use.
+func Host() string {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.Host
+}
+
+// IsDefaultInstance reports whether the default SwitchTube instance is in use.
+func IsDefaultInstance() bool {
+	return baseURL == DefaultBaseURL
+}
+
+// SetBaseURL validates and selects the SwitchTube instance to use.
+func SetBaseURL(raw string) error {
+	normalized, err := normalizeBaseURL(raw)
+	if err != nil {
+		return err
+	}
+
+	baseURL = normalized
+
+	return nil
+}
+
+// normalizeBaseURL validates raw as base URL of a SwitchTube instance and returns it
+// without query and fragment, always ending with a slash.
+func normalizeBaseURL(raw string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errInvalidBaseURL, err)
+	}
+
+	if (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return "", fmt.Errorf("%w: %q (expected e.g. %s)", errInvalidBaseURL, raw, DefaultBaseURL)
+	}
+
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/") + "/"
+	parsed.RawQuery, parsed.Fragment = "", ""
+
+	return parsed.String(), nil
+}
+
+// Load reads the configuration file. Returns an empty configuration if none exists, and
+// ErrNoConfigDir if the config directory cannot be located or created. Unknown keys,
+// e.g. misspelled ones, fail the loading instead of being ignored.
+func Load() (Config, error) {
+	path, err := Path()
+	if err != nil {
+		return Config{}, fmt.Errorf("%w: %w", ErrNoConfigDir, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Config{}, nil
+	}
+
+	if err != nil {
+		return Config{}, fmt.Errorf("%w: %w", errFailedToLoadConfig, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var cfg Config
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("%w: %s: %w", errFailedToLoadConfig, path, err)
+	}
+
+	return cfg, nil
+}
+
+// Save writes cfg to the configuration file. The comments and the order of the settings
+// in an existing file are kept, settings cfg leaves unset are removed.
+func Save(cfg Config) error {
+	path, err := Path()
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToSaveConfig, err)
+	}
+
+	var fresh yaml.Node
+	if err := fresh.Encode(cfg); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToSaveConfig, err)
+	}
+
+	doc := &fresh
+
+	// An unreadable file is replaced as a whole
+	if existing, err := os.ReadFile(path); err == nil {
+		var parsed yaml.Node
+		if yaml.Unmarshal(existing, &parsed) == nil && len(parsed.Content) == 1 && parsed.Content[0].Kind == yaml.MappingNode {
+			mergeSettings(parsed.Content[0], &fresh)
+			doc = &parsed
+		}
+	}
+
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToSaveConfig, err)
+	}
+
+	if err := os.WriteFile(path, data, configPermissions); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToSaveConfig, err)
+	}
+
+	return nil
+}
+
+// mergeSettings replaces the settings of the mapping target by those of the mapping
+// source in place, keeping the comments and order of the settings in target. Settings
+// missing in source are removed, those only in source are appended.
+func mergeSettings(target *yaml.Node, source *yaml.Node) {
+	values := make(map[string]*yaml.Node, len(source.Content)/2) //nolint:mnd // Key and value nodes
+
+	for i := 0; i+1 < len(source.Content); i += 2 {
+		values[source.Content[i].Value] = source.Content[i+1]
+	}
+
+	content := make([]*yaml.Node, 0, len(source.Content))
+
+	for i := 0; i+1 < len(target.Content); i += 2 {
+		key, value := target.Content[i], target.Content[i+1]
+
+		updated, ok := values[key.Value]
+		if !ok {
+			continue
+		}
+
+		delete(values, key.Value)
+
+		value.Kind, value.Style, value.Tag, value.Value, value.Content =
+			updated.Kind, updated.Style, updated.Tag, updated.Value, updated.Content
+		content = append(content, key, value)
+	}
+
+	for i := 0; i+1 < len(source.Content); i += 2 {
+		if _, added := values[source.Content[i].Value]; added {
+			content = append(content, source.Content[i], source.Content[i+1])
+		}
+	}
+
+	target.Content = content
+}
+
+// Exists reports whether the configuration file was written, e.g. by the first-run setup.
+func Exists() bool {
+	path, err := Path()
+	if err != nil {
+		return false
+	}
+
+	_, err = os.Stat(path)
+
+	return err == nil
+}
+
+// Path returns the path of the configuration file.
+func Path() (string, error) {
+	configDir, err := dir.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, configFilename), nil
+}
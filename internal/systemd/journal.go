@@ -0,0 +1,72 @@
+package systemd
+
+import (
+	"bytes"
+	"io"
+)
+
+// Syslog priorities the journal reads from the start of a line, see sd-daemon(3).
+const (
+	priorityCritical = "<2>"
+	priorityError    = "<3>"
+	priorityWarning  = "<4>"
+	priorityInfo     = "<6>"
+	priorityDebug    = "<7>"
+)
+
+// priorities maps the levels of the logger to syslog priorities.
+//
+//nolint:gochecknoglobals // Read-only lookup table
+var priorities = map[string]string{
+	"debug": priorityDebug,
+	"info":  priorityInfo,
+	"warn":  priorityWarning,
+	"error": priorityError,
+	"fatal": priorityCritical,
+}
+
+// journalWriter prefixes every line of logfmt output with the syslog priority of its
+// level, so the journal records it with that priority.
+type journalWriter struct {
+	out io.Writer
+}
+
+// JournalWriter returns a writer for log lines in logfmt, as written by the logger with
+// the logfmt formatter, that prefixes each line written to out with the priority of its
+// level field. journalctl -p can then filter the lines, e.g. -p warning for problems.
+func JournalWriter(out io.Writer) io.Writer {
+	return journalWriter{out: out}
+}
+
+// Write implements io.Writer. The logger writes one or more complete lines at a time.
+func (jw journalWriter) Write(p []byte) (int, error) {
+	var prefixed []byte
+
+	for line := range bytes.Lines(p) {
+		prefixed = append(prefixed, priority(line)...)
+		prefixed = append(prefixed, line...)
+	}
+
+	if _, err := jw.out.Write(prefixed); err != nil {
+		return 0, err //nolint:wrapcheck // Plain passthrough to the log stream
+	}
+
+	return len(p), nil
+}
+
+// priority returns the syslog priority prefix of a logfmt line by its level field, info
+// if it has none. The level precedes the message, so the first level field is the one
+// of the logger even if the message quotes another.
+func priority(line []byte) string {
+	for field := range bytes.FieldsSeq(line) {
+		if level, ok := bytes.CutPrefix(field, []byte("level=")); ok {
+			if prefix, ok := priorities[string(level)]; ok {
+				return prefix
+			}
+
+			break
+		}
+	}
+
+	return priorityInfo
+}
@@ -0,0 +1,85 @@
+// Package systemd integrates the long-running watch and serve commands with systemd:
+// readiness and status notifications for Type=notify services, watchdog pings and log
+// lines whose priority the journal understands.
+package systemd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errFailedToNotify = errors.New("failed to notify systemd")
+
+// Ready tells the service manager that the service finished starting up, with status
+// as the text shown by systemctl status. Does nothing unless started by systemd as a
+// Type=notify service.
+func Ready(status string) error {
+	return notify("READY=1\nSTATUS=" + status)
+}
+
+// Status updates the text shown by systemctl status. Failures are ignored, as Ready
+// already reported whether the service manager can be reached.
+func Status(status string) {
+	_ = notify("STATUS=" + status)
+}
+
+// Stopping tells the service manager that the service is shutting down. Failures are
+// ignored, the service stops either way.
+func Stopping() {
+	_ = notify("STOPPING=1")
+}
+
+// WatchdogInterval returns the interval the watchdog of the service manager must be
+// pinged at, half its timeout as recommended by sd_watchdog_enabled(3). Returns false
+// unless the service sets WatchdogSec for this process.
+func WatchdogInterval() (time.Duration, bool) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+
+	return time.Duration(usec) * time.Microsecond / 2, true //nolint:mnd // Half the timeout
+}
+
+// Watchdog tells the watchdog of the service manager that the service still works. Call
+// it from the work itself, e.g. its main loop, so a hung service is restarted. Failures
+// are ignored, the next ping retries.
+func Watchdog() {
+	_ = notify("WATCHDOG=1")
+}
+
+// notify sends state to the socket of the service manager given by NOTIFY_SOCKET, like
+// sd_notify(3). Does nothing if it is not set.
+func notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	// Sockets starting with @ live in the abstract namespace
+	if name, ok := strings.CutPrefix(socket, "@"); ok {
+		socket = "\x00" + name
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFailedToNotify, err)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("%w: %w", errFailedToNotify, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build linux
+
+package systemd
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// LogsToJournal reports whether file is connected to the journal, i.e. its device and
+// inode match JOURNAL_STREAM, which systemd sets for the standard streams of a service.
+// A stream redirected by the service itself does not match.
+func LogsToJournal(file *os.File) bool {
+	journal := os.Getenv("JOURNAL_STREAM")
+	if journal == "" {
+		return false
+	}
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(int(file.Fd()), &stat); err != nil {
+		return false
+	}
+
+	return journal == fmt.Sprintf("%d:%d", stat.Dev, stat.Ino)
+}
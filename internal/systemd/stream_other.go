@@ -0,0 +1,10 @@
+//go:build !linux
+
+package systemd
+
+import "os"
+
+// LogsToJournal reports false, as the journal only exists on Linux.
+func LogsToJournal(_ *os.File) bool {
+	return false
+}
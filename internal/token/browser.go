@@ -0,0 +1,237 @@
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"switchtube-downloader/internal/helper/browser"
+	"switchtube-downloader/internal/helper/ui/stream"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/settings"
+)
+
+const (
+	// accessTokensPath is the page of SwitchTube where tokens are created.
+	accessTokensPath = "access_tokens"
+	// browserTimeout bounds the wait for the token submitted in the browser.
+	browserTimeout = 10 * time.Minute
+	// stateBytes is the length of the random path that keeps other pages from submitting tokens.
+	stateBytes = 16
+	// maxFormSize bounds the size of a submitted form.
+	maxFormSize = 64 << 10
+	// readHeaderTimeout bounds reading the request headers of the local page.
+	readHeaderTimeout = 10 * time.Second
+	// shutdownTimeout bounds the wait for open requests when the page is no longer needed.
+	shutdownTimeout = 5 * time.Second
+)
+
+// tokenPage asks for the token created on SwitchTube.
+//
+//nolint:gochecknoglobals // Parsed once
+var tokenPage = template.Must(template.New("token").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>SwitchTube Downloader</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 3em auto; padding: 0 1em; line-height: 1.5; }
+input[type=password] { width: 100%; padding: .5em; box-sizing: border-box; }
+button { margin-top: 1em; padding: .5em 1.5em; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+<h1>SwitchTube Downloader</h1>
+{{if .Done}}
+<p>{{.Done}}</p>
+{{else}}
+<ol>
+<li><a href="{{.TokensURL}}" target="_blank" rel="noopener">{{.Create}}</a></li>
+<li>{{.Paste}}</li>
+</ol>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post">
+<input type="password" name="token" autocomplete="off" autofocus required>
+<button type="submit">{{.Submit}}</button>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+var (
+	errBrowserTimeout   = errors.New("no token was submitted in the browser in time")
+	errFailedToServe    = errors.New("failed to serve the token page")
+	errFailedToGetState = errors.New("failed to create the token page")
+)
+
+// tokenPageData fills tokenPage.
+type tokenPageData struct {
+	TokensURL string // Access tokens page of SwitchTube
+	Create    string // Link text of the access tokens page
+	Paste     string // Instruction to paste the token
+	Submit    string // Label of the submit button
+	Error     string // Reason the last submitted token was rejected
+	Done      string // Message shown instead of the form once the token was accepted
+}
+
+// browserResult is a token submitted in the browser and accepted by SwitchTube.
+type browserResult struct {
+	token   string
+	profile models.TokenProfile
+}
+
+// SetFromBrowser creates and stores a new access token with the help of the browser,
+// replacing the copy and paste into the terminal of Set. A page served on localhost
+// links to the access tokens page of SwitchTube and takes the created token, which is
+// validated before the page accepts it. The page is opened in the default browser and
+// its URL printed for when no browser can be opened.
+func (tm *Manager) SetFromBrowser(ctx context.Context) error {
+	if err := tm.checkExistingToken(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, browserTimeout)
+	defer cancel()
+
+	result, err := tm.receiveToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	return tm.store(result.token, result.profile)
+}
+
+// receiveToken serves the token page on a random localhost port until a token accepted
+// by SwitchTube is submitted or ctx is done.
+func (tm *Manager) receiveToken(ctx context.Context) (browserResult, error) {
+	state, err := randomState()
+	if err != nil {
+		return browserResult{}, err
+	}
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", "127.0.0.1:0")
+	if err != nil {
+		return browserResult{}, fmt.Errorf("%w: %w", errFailedToServe, err)
+	}
+
+	results := make(chan browserResult, 1)
+	server := &http.Server{
+		Handler:           tm.tokenHandler(state, results),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	defer func() {
+		// Let the handler finish the page of the accepted token before stopping
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+		defer cancel()
+
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	pageURL := fmt.Sprintf("http://%s/%s", listener.Addr(), state)
+
+	fmt.Fprintln(stream.UI(), i18n.T("Create a token in the browser and paste it into the page at %s", pageURL))
+
+	if err := browser.Open(pageURL); err != nil {
+		stream.Warnf(stream.UI(), "%v, open the page manually", err)
+	}
+
+	select {
+	case result := <-results:
+		return result, nil
+	case err := <-serveErr:
+		return browserResult{}, fmt.Errorf("%w: %w", errFailedToServe, err)
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return browserResult{}, errBrowserTimeout
+		}
+
+		return browserResult{}, ctx.Err() //nolint:wrapcheck // Interrupted by the user
+	}
+}
+
+// tokenHandler serves the token page at /state and validates the submitted tokens,
+// sending the first accepted one to results.
+func (tm *Manager) tokenHandler(state string, results chan<- browserResult) http.Handler {
+	data := tokenPageData{
+		TokensURL: settings.BaseURL() + accessTokensPath,
+		Create:    i18n.T("Create a new token on SwitchTube"),
+		Paste:     i18n.T("Copy the generated token and paste it here"),
+		Submit:    i18n.T("Save token"),
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/")
+		if subtle.ConstantTimeCompare([]byte(path), []byte(state)) != 1 {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		page := data
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+			token := strings.TrimSpace(r.PostFormValue("token"))
+			if token == "" {
+				page.Error = errTokenEmpty.Error()
+
+				break
+			}
+
+			profile, err := tm.fetchProfile(r.Context(), token)
+			if err != nil {
+				page.Error = i18n.T("SwitchTube rejected the token: %v", err)
+
+				break
+			}
+
+			page.Done = i18n.T("The token was accepted, you can close this page and return to the terminal.")
+
+			select {
+			case results <- browserResult{token: token, profile: profile}:
+			default: // A token was accepted already
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+
+		_ = tokenPage.Execute(w, page)
+	})
+}
+
+// randomState returns a random hex string that makes the URL of the token page unguessable.
+func randomState() (string, error) {
+	buf := make([]byte, stateBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("%w: %w", errFailedToGetState, err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
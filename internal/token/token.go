@@ -3,35 +3,46 @@ package token
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/user"
 	"strings"
 	"time"
 
+	"switchtube-downloader/internal/auth"
 	"switchtube-downloader/internal/helper/ui/input"
+	"switchtube-downloader/internal/helper/ui/stream"
 	"switchtube-downloader/internal/helper/ui/table"
+	"switchtube-downloader/internal/i18n"
+	"switchtube-downloader/internal/models"
+	"switchtube-downloader/internal/redact"
+	"switchtube-downloader/internal/settings"
+	"switchtube-downloader/internal/tracing"
 
 	"github.com/charmbracelet/huh/spinner"
 	charm "github.com/charmbracelet/log"
-	"github.com/charmbracelet/x/term"
 	"github.com/zalando/go-keyring"
 )
 
 const (
 	serviceName           = "SwitchTube"
-	profileAPIURL         = "https://tube.switch.ch/api/v1/profiles/me"
+	profileAPI            = "api/v1/profiles/me"
 	requestTimeoutSeconds = 10
 )
 
+// maxProfileSize is the maximum size of a profile response that is decoded.
+const maxProfileSize = 1 << 20
+
 const (
 	maskThreshold    = 10
 	maskVisibleChars = 5
 )
 
-var log = charm.NewWithOptions(os.Stderr, charm.Options{
+var log = charm.NewWithOptions(redact.Stream(os.Stderr), charm.Options{
 	ReportTimestamp: false,
 	ReportCaller:    false,
 })
@@ -47,14 +58,40 @@ var (
 	errTokenInvalid          = errors.New("token authentication failed")
 )
 
+// provided is the token given through the environment or a file, used instead of the
+// keyring if set.
+//
+//nolint:gochecknoglobals // Set once at startup from SWITCHTUBE_TOKEN or the global --token-file flag
+var provided string
+
+// Use makes every Manager return token instead of reading the keyring, e.g. for
+// containers and CI jobs without keyring, where it is given through the environment or
+// a secret file. The keyring is never accessed to read the token afterwards.
+func Use(token string) {
+	provided = token
+	redact.Add(token)
+}
+
+// Provided reports whether the token was given with Use instead of being read from the
+// keyring.
+func Provided() bool {
+	return provided != ""
+}
+
 // Manager encapsulates token management logic.
 type Manager struct {
 	keyringService string
 }
 
-// NewTokenManager creates a new instance of Manager.
+// NewTokenManager creates a new instance of Manager for the SwitchTube instance in use.
+// Tokens of other instances than the default one are stored separately per host.
 func NewTokenManager() *Manager {
-	return &Manager{keyringService: serviceName}
+	service := serviceName
+	if !settings.IsDefaultInstance() {
+		service = fmt.Sprintf("%s (%s)", serviceName, settings.Host())
+	}
+
+	return &Manager{keyringService: service}
 }
 
 // Delete removes the access token from the system keyring.
@@ -64,8 +101,13 @@ func (tm *Manager) Delete() error {
 		return err
 	}
 
-	if !input.Confirm("Are you sure you want to delete the stored token?") {
-		log.Warn("Token deletion cancelled")
+	confirmed, err := input.Confirm("%s", i18n.T("Are you sure you want to delete the stored token?"))
+	if err != nil {
+		return fmt.Errorf("failed to confirm deletion: %w", err)
+	}
+
+	if !confirmed {
+		log.Warn(i18n.T("Token deletion cancelled"))
 
 		return nil
 	}
@@ -78,37 +120,68 @@ func (tm *Manager) Delete() error {
 		return fmt.Errorf("failed to delete token: %w", err)
 	}
 
-	log.Info("Token successfully deleted from keyring")
+	log.Info(i18n.T("Token successfully deleted from keyring"))
 
 	return nil
 }
 
-// Get retrieves the access token from the system keyring and validates it.
+// Get retrieves the access token from the system keyring and validates it. A token
+// validated successfully within the last hours is trusted without asking SwitchTube
+// again, and so is a token validated before if SwitchTube cannot be reached, so cached
+// data stays usable offline. Validation is skipped entirely after SkipValidation.
 func (tm *Manager) Get(ctx context.Context) (string, error) {
 	token, err := tm.GetRaw()
 	if err != nil {
 		return "", err
 	}
 
+	if validationSkipped {
+		return token, nil
+	}
+
+	fresh, known := tm.checkValidation(token)
+	if fresh {
+		return token, nil
+	}
+
 	if err := tm.validateToken(ctx, token); err != nil {
+		if known && isNetworkError(err) {
+			return token, nil
+		}
+
 		return token, fmt.Errorf("stored token is invalid: %w", err)
 	}
 
+	tm.recordValidation(token)
+
 	return token, nil
 }
 
 // GetAndDisplay retrieves the token and shows it in the info table.
 func (tm *Manager) GetAndDisplay() error {
-	token, validateErr := tm.getValidated("Validating token...")
+	token, profile, validateErr := tm.getValidated(i18n.T("Validating token..."))
 
-	tm.displayTokenInfo(token, validateErr == nil)
+	tm.displayTokenInfo(token, validateErr == nil, profile)
 
 	return validateErr
 }
 
-// GetRaw retrieves the token from the keyring without any validation.
-// Use this when you just need the raw token value.
+// Stored reports whether a token is stored in the keyring, without validating it.
+// Reports true if the keyring cannot be read, so callers do not mistake it for a
+// first run.
+func (tm *Manager) Stored() bool {
+	_, err := tm.GetRaw()
+
+	return !errors.Is(err, errNoToken)
+}
+
+// GetRaw retrieves the token from the keyring without any validation, or returns the
+// token given with Use. Use this when you just need the raw token value.
 func (tm *Manager) GetRaw() (string, error) {
+	if provided != "" {
+		return provided, nil
+	}
+
 	username, err := tm.getUsername()
 	if err != nil {
 		return "", err
@@ -123,6 +196,8 @@ func (tm *Manager) GetRaw() (string, error) {
 		return "", fmt.Errorf("failed to retrieve token: %w", err)
 	}
 
+	redact.Add(token)
+
 	return token, nil
 }
 
@@ -134,39 +209,31 @@ func (tm *Manager) Set() error {
 
 	table.DisplayInstructions()
 
-	token := input.Input("Enter your access token")
+	token, err := input.Input(i18n.T("Enter your access token"))
+	if err != nil {
+		return fmt.Errorf("failed to read token: %w", err)
+	}
+
 	if token == "" {
 		return errTokenEmpty
 	}
 
-	validateErr := tm.validateWithSpinner("Validating token with SwitchTube API...", token)
+	profile, validateErr := tm.validateWithSpinner(i18n.T("Validating token with SwitchTube API..."), token)
 	if validateErr != nil {
-		log.Error("Token validation failed", "err", validateErr)
-		tm.displayTokenInfo(token, false)
+		log.Error(i18n.T("Token validation failed"), "err", validateErr)
+		tm.displayTokenInfo(token, false, profile)
 
 		return validateErr
 	}
 
-	username, err := tm.getUsername()
-	if err != nil {
-		return err
-	}
-
-	if err := keyring.Set(tm.keyringService, username, token); err != nil {
-		return fmt.Errorf("failed to store token: %w", err)
-	}
-
-	tm.displayTokenInfo(token, true)
-	log.Info("Token is valid and successfully stored in keyring")
-
-	return nil
+	return tm.store(token, profile)
 }
 
-// Validate validates the stored token and displays its status.
+// Validate validates the stored token and displays its status and account.
 func (tm *Manager) Validate() error {
-	token, validateErr := tm.getValidated("Validating token...")
+	token, profile, validateErr := tm.getValidated(i18n.T("Validating token..."))
 
-	tm.displayTokenInfo(token, validateErr == nil)
+	tm.displayTokenInfo(token, validateErr == nil, profile)
 
 	return validateErr
 }
@@ -178,12 +245,17 @@ func (tm *Manager) checkExistingToken() error {
 		return nil
 	}
 
-	tm.displayTokenInfo(existingToken, err == nil)
+	tm.displayTokenInfo(existingToken, err == nil, models.TokenProfile{})
 
-	fmt.Println()
+	fmt.Fprintln(stream.UI())
 
-	if !input.Confirm("Do you want to replace it?") {
-		log.Warn("Operation cancelled")
+	replace, err := input.Confirm("%s", i18n.T("Do you want to replace it?"))
+	if err != nil {
+		return fmt.Errorf("failed to confirm replacement: %w", err)
+	}
+
+	if !replace {
+		log.Warn(i18n.T("Operation cancelled"))
 
 		return ErrTokenAlreadyExists
 	}
@@ -191,14 +263,58 @@ func (tm *Manager) checkExistingToken() error {
 	return nil
 }
 
-// displayTokenInfo shows information about the token in a table.
-func (tm *Manager) displayTokenInfo(token string, valid bool) {
+// displayTokenInfo shows information about the token and its profile in a table.
+func (tm *Manager) displayTokenInfo(token string, valid bool, profile models.TokenProfile) {
 	username, err := tm.getUsername()
 	if err != nil {
 		return
 	}
 
-	table.DisplayTokenInfo(tm.keyringService, username, valid, tm.maskToken(token), len(token))
+	table.DisplayTokenInfo(tm.keyringService, username, valid, tm.maskToken(token), len(token), profile)
+}
+
+// fetchProfile requests the profile of the token from the SwitchTube API, which fails
+// if the token is invalid.
+func (tm *Manager) fetchProfile(ctx context.Context, token string) (models.TokenProfile, error) {
+	var profile models.TokenProfile
+
+	redact.Add(token)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, settings.BaseURL()+profileAPI, http.NoBody)
+	if err != nil {
+		return profile, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	settings.ApplyHeaders(req)
+	auth.Apply(req, auth.TokenHeader(token))
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{
+		Timeout:   requestTimeoutSeconds * time.Second,
+		Transport: tracing.Transport(http.DefaultTransport),
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return profile, fmt.Errorf("%w: %w", errFailedToValidateToken, err)
+	}
+
+	// The account name is only shown, so a profile that cannot be decoded is no error
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, maxProfileSize)).Decode(&profile)
+
+	if err := resp.Body.Close(); err != nil {
+		return profile, fmt.Errorf("%w: %w", errFailedToCloseResponse, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return models.TokenProfile{}, errTokenInvalid
+	}
+
+	if decodeErr != nil {
+		return models.TokenProfile{}, nil
+	}
+
+	return profile, nil
 }
 
 // getUsername returns the current system username.
@@ -211,28 +327,22 @@ func (tm *Manager) getUsername() (string, error) {
 	return u.Username, nil
 }
 
-// getValidated retrieves and validates the token, using a spinner in terminal mode.
-func (tm *Manager) getValidated(title string) (string, error) {
-	var (
-		token       string
-		validateErr error
-	)
-
-	if !term.IsTerminal(os.Stdout.Fd()) {
-		token, validateErr = tm.Get(context.Background())
-	} else {
-		_ = spinner.New().
-			Title(title).
-			Context(context.Background()).
-			ActionWithErr(func(ctx context.Context) error {
-				token, validateErr = tm.Get(ctx)
+// getValidated retrieves and validates the token and returns its profile, using a
+// spinner in terminal mode.
+func (tm *Manager) getValidated(title string) (string, models.TokenProfile, error) {
+	token, err := tm.GetRaw()
+	if err != nil {
+		return "", models.TokenProfile{}, err
+	}
 
-				return nil
-			}).
-			Run()
+	profile, err := tm.validateWithSpinner(title, token)
+	if err != nil {
+		return token, profile, fmt.Errorf("stored token is invalid: %w", err)
 	}
 
-	return token, validateErr
+	tm.recordValidation(token)
+
+	return token, profile, nil
 }
 
 // maskToken masks the middle portion of the token.
@@ -246,53 +356,53 @@ func (tm *Manager) maskToken(token string) string {
 		token[len(token)-maskVisibleChars:]
 }
 
-// validateToken checks if the token is valid by making a request to the SwitchTube API.
-func (tm *Manager) validateToken(ctx context.Context, token string) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, profileAPIURL, http.NoBody)
+// store saves the validated token in the keyring and displays its status and account.
+func (tm *Manager) store(token string, profile models.TokenProfile) error {
+	username, err := tm.getUsername()
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
 
-	req.Header.Set("Authorization", "Token "+token)
-	req.Header.Set("Accept", "application/json")
-
-	client := &http.Client{
-		Timeout: requestTimeoutSeconds * time.Second,
+	if err := keyring.Set(tm.keyringService, username, token); err != nil {
+		return fmt.Errorf("failed to store token: %w", err)
 	}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("%w: %w", errFailedToValidateToken, err)
-	}
+	tm.recordValidation(token)
 
-	if err := resp.Body.Close(); err != nil {
-		return fmt.Errorf("%w: %w", errFailedToCloseResponse, err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return errTokenInvalid
-	}
+	tm.displayTokenInfo(token, true, profile)
+	log.Info(i18n.T("Token is valid and successfully stored in keyring"))
 
 	return nil
 }
 
-// validateWithSpinner validates a token value, using a spinner in terminal mode.
-func (tm *Manager) validateWithSpinner(title string, token string) error {
-	if !term.IsTerminal(os.Stdout.Fd()) {
-		return tm.validateToken(context.Background(), token)
+// validateToken checks if the token is valid by making a request to the SwitchTube API.
+func (tm *Manager) validateToken(ctx context.Context, token string) error {
+	_, err := tm.fetchProfile(ctx, token)
+
+	return err
+}
+
+// validateWithSpinner validates a token value and returns its profile, using a spinner in terminal mode.
+func (tm *Manager) validateWithSpinner(title string, token string) (models.TokenProfile, error) {
+	if !stream.IsTerminal() {
+		return tm.fetchProfile(context.Background(), token)
 	}
 
-	var validateErr error
+	var (
+		profile     models.TokenProfile
+		validateErr error
+	)
 
 	_ = spinner.New().
+		Output(stream.UI()).
 		Title(title).
 		Context(context.Background()).
 		ActionWithErr(func(ctx context.Context) error {
-			validateErr = tm.validateToken(ctx, token)
+			profile, validateErr = tm.fetchProfile(ctx, token)
 
 			return nil
 		}).
 		Run()
 
-	return validateErr
+	return profile, validateErr
 }
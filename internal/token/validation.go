@@ -0,0 +1,120 @@
+package token
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"switchtube-downloader/internal/helper/dir"
+)
+
+const (
+	// validationTTL is how long a successful validation is trusted without asking SwitchTube again.
+	validationTTL = 12 * time.Hour
+	// validationDirName is the cache subdirectory holding the validation records.
+	validationDirName = "token"
+	// validationFilename stores when each token was last validated successfully.
+	validationFilename = "validated.json"
+	// validationPermissions keep the records private, even though they only hold hashes.
+	validationPermissions = 0o600
+)
+
+//nolint:gochecknoglobals // Set once at startup from the global --skip-validation flag
+var validationSkipped bool
+
+// SkipValidation makes Get return the stored token without validating it against SwitchTube.
+func SkipValidation() {
+	validationSkipped = true
+}
+
+// Forget drops the record of a successful validation of token, e.g. after SwitchTube
+// rejected it, so the next Get validates it again.
+func (tm *Manager) Forget(token string) {
+	records := loadValidations()
+	delete(records, tm.validationKey(token))
+	saveValidations(records)
+}
+
+// checkValidation decides whether token must be validated over the network. Returns
+// true if it was validated within validationTTL, and whether it was ever validated.
+func (tm *Manager) checkValidation(token string) (bool, bool) {
+	records := loadValidations()
+
+	validatedAt, ok := records[tm.validationKey(token)]
+	if !ok {
+		return false, false
+	}
+
+	return time.Since(validatedAt) < validationTTL, true
+}
+
+// recordValidation remembers that token was just validated successfully.
+func (tm *Manager) recordValidation(token string) {
+	records := loadValidations()
+	records[tm.validationKey(token)] = time.Now()
+	saveValidations(records)
+}
+
+// validationKey identifies token of this manager's service in the records without storing the token.
+func (tm *Manager) validationKey(token string) string {
+	sum := sha256.Sum256([]byte(tm.keyringService + "\x00" + token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// loadValidations returns the validation times by key. Missing or broken records are empty.
+func loadValidations() map[string]time.Time {
+	records := make(map[string]time.Time)
+
+	path, err := validationPath()
+	if err != nil {
+		return records
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return records
+	}
+
+	if err := json.Unmarshal(data, &records); err != nil {
+		return make(map[string]time.Time)
+	}
+
+	return records
+}
+
+// saveValidations writes the validation records. Failing to write them only means
+// the token is validated again next time.
+func saveValidations(records map[string]time.Time) {
+	path, err := validationPath()
+	if err != nil {
+		return
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return
+	}
+
+	_ = os.WriteFile(path, data, validationPermissions)
+}
+
+// validationPath returns the path of the validation records.
+func validationPath() (string, error) {
+	cacheDir, err := dir.CacheDir(validationDirName)
+	if err != nil {
+		return "", err //nolint:wrapcheck // Only checked for success
+	}
+
+	return filepath.Join(cacheDir, validationFilename), nil
+}
+
+// isNetworkError reports whether a validation failed because SwitchTube could not be
+// reached, as opposed to rejecting the token.
+func isNetworkError(err error) bool {
+	return errors.Is(err, errFailedToValidateToken)
+}
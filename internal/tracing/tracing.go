@@ -0,0 +1,127 @@
+// Package tracing logs the HTTP traffic with SwitchTube to diagnose proxy and authentication problems.
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"switchtube-downloader/internal/redact"
+
+	charm "github.com/charmbracelet/log"
+)
+
+// redacted replaces the value of headers carrying credentials.
+const redacted = "[REDACTED]"
+
+//nolint:gochecknoglobals // Enabled once at startup by --trace-http
+var enabled bool
+
+//nolint:gochecknoglobals // Trace output is kept apart from the progress output on stdout
+var log = charm.NewWithOptions(redact.Stream(os.Stderr), charm.Options{
+	ReportTimestamp: true,
+	TimeFormat:      "15:04:05.000",
+	Prefix:          "http",
+})
+
+// sensitiveHeaders are redacted before headers are logged.
+//
+//nolint:gochecknoglobals // Read-only lookup table
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"Set-Cookie",
+}
+
+// transport logs every request and response passing through next.
+type transport struct {
+	next http.RoundTripper
+}
+
+// Enable turns on tracing for all transports wrapped afterwards.
+func Enable() {
+	enabled = true
+}
+
+// Debugf logs a diagnostic message about the traffic with SwitchTube, e.g. ignored
+// response fields, if tracing is enabled.
+func Debugf(format string, args ...any) {
+	if enabled {
+		log.Infof(format, args...)
+	}
+}
+
+// Transport wraps next so that its traffic is logged if tracing is enabled.
+// Returns next unchanged otherwise.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if !enabled {
+		return next
+	}
+
+	return &transport{next: next}
+}
+
+// RoundTrip logs req, forwards it and logs the response or error with the elapsed time.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	log.Info("request", "method", req.Method, "url", req.URL.Redacted(), "headers", formatHeaders(req.Header))
+
+	start := time.Now()
+
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		log.Error("request failed", "method", req.Method, "url", req.URL.Redacted(), "elapsed", elapsed, "err", err)
+
+		return nil, err //nolint:wrapcheck // Transport errors are passed on unchanged
+	}
+
+	log.Info("response",
+		"status", resp.Status,
+		"url", req.URL.Redacted(),
+		"proto", resp.Proto,
+		"elapsed", elapsed,
+		"headers", formatHeaders(resp.Header),
+	)
+
+	return resp, nil
+}
+
+// formatHeaders renders headers sorted by name on a single line, redacting credentials.
+func formatHeaders(headers http.Header) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	parts := make([]string, 0, len(names))
+
+	for _, name := range names {
+		value := strings.Join(headers.Values(name), ", ")
+		if slices.Contains(sensitiveHeaders, name) {
+			value = redactValue(name, value)
+		}
+
+		parts = append(parts, fmt.Sprintf("%s: %s", name, value))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// redactValue hides the credential in the header name, keeping the authentication
+// scheme such as "Token" visible.
+func redactValue(name string, value string) string {
+	if strings.HasSuffix(name, "Authorization") {
+		if scheme, _, found := strings.Cut(value, " "); found {
+			return scheme + " " + redacted
+		}
+	}
+
+	return redacted
+}